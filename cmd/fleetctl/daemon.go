@@ -0,0 +1,194 @@
+// cmd/fleetctl/daemon.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/config"
+	"fleetctl/internal/fleet"
+	"fleetctl/internal/metrics"
+	"fleetctl/internal/state"
+)
+
+// defaultStatePath is the --state default; when unchanged, each fleet gets its own
+// state file next to its config.
+const defaultStatePath = ".fleetctl/state.json"
+
+// fleetRuntime bundles one managed fleet with its config source and control loop status.
+type fleetRuntime struct {
+	name    string
+	cfgPath string
+	fleet   *fleet.Fleet
+	store   *state.Store
+	status  *controlStatus
+}
+
+// daemon holds every fleet served by one HTTP process, keyed by fleet name.
+type daemon struct {
+	fleets  map[string]*fleetRuntime
+	order   []string // sorted fleet names; order[0] is the default fleet
+	stores  map[string]*state.Store
+	metrics *metrics.Registry // operation metrics of every fleet
+}
+
+func newDaemon() *daemon {
+	return &daemon{
+		fleets:  map[string]*fleetRuntime{},
+		stores:  map[string]*state.Store{},
+		metrics: metrics.NewRegistry(),
+	}
+}
+
+// resolveStatePath places the default state file alongside the config file and
+// names it after the fleet, unless --state was overridden.
+func resolveStatePath(flagPath, cfgPath, fleetName string) string {
+	if flagPath != defaultStatePath {
+		return flagPath
+	}
+	return filepath.Join(filepath.Dir(cfgPath), fmt.Sprintf(".%s.state.json", fleetName))
+}
+
+// lockPathFor returns the per-fleet lock file next to the state file. Fleets sharing
+// one state file still lock independently, and CLI runs and daemons agree on the path.
+func lockPathFor(statePath, fleetName string) string {
+	return filepath.Join(filepath.Dir(statePath), fmt.Sprintf(".%s.lock", fleetName))
+}
+
+// openStore opens the state backend selected by spec.state and applies the fleet's
+// retention; statePath is the default location for the file and bolt backends. Fleets
+// resolving to a backend already in shared (keyed by backend) get that Store.
+func openStore(cfg *config.FleetConfig, statePath string, shared map[string]*state.Store) (*state.Store, error) {
+	b, err := state.OpenBackend(cfg.Spec.State, statePath, cfg.Metadata.Name)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := shared[b.String()]
+	if !ok {
+		st = state.NewWithBackend(b)
+		if shared != nil {
+			shared[b.String()] = st
+		}
+	}
+	st.SetRetention(cfg.Metadata.Name, cfg.Spec.State.Retention)
+	return st, nil
+}
+
+// loadDaemon parses every fleet config (*.yaml, *.yml) in dir.
+// Fleets share one Store when they resolve to the same backend (e.g. --state override);
+// each keeps its own spec.state.retention.
+func loadDaemon(dir, flagStatePath string) (*daemon, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read config dir: %w", err)
+	}
+	d := newDaemon()
+	for _, e := range entries {
+		name := e.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if e.IsDir() || strings.HasPrefix(name, ".") || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		cfgPath := filepath.Join(dir, name)
+		cfg, err := config.ParseFile(cfgPath)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Kind != "FleetConfig" {
+			log.Printf("skipping %s: kind %q is not FleetConfig", cfgPath, cfg.Kind)
+			continue
+		}
+		statePath := resolveStatePath(flagStatePath, cfgPath, cfg.Metadata.Name)
+		st, err := openStore(cfg, statePath, d.stores)
+		if err != nil {
+			return nil, fmt.Errorf("%s: state: %w", cfgPath, err)
+		}
+		if err := d.add(cfgPath, cfg, st, statePath); err != nil {
+			return nil, err
+		}
+	}
+	if len(d.order) == 0 {
+		return nil, fmt.Errorf("no fleet configs found in %s", dir)
+	}
+	return d, nil
+}
+
+// add registers a fleet and initializes its OCI client and cross-process lock.
+func (d *daemon) add(cfgPath string, cfg *config.FleetConfig, st *state.Store, statePath string) error {
+	name := cfg.Metadata.Name
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%s: metadata.name is required", cfgPath)
+	}
+	if prev, ok := d.fleets[name]; ok {
+		return fmt.Errorf("duplicate fleet name %q in %s and %s", name, prev.cfgPath, cfgPath)
+	}
+	cli, err := client.New(cfg.Spec.Auth)
+	if err != nil {
+		return fmt.Errorf("init OCI client for fleet %s: %w", name, err)
+	}
+	m := metrics.New()
+	f := fleet.New(*cfg, cli, st, m)
+	f.Lock = mustFleetLock(*cfg, statePath, cli)
+	d.fleets[name] = &fleetRuntime{
+		name:    name,
+		cfgPath: cfgPath,
+		fleet:   f,
+		store:   st,
+		status:  &controlStatus{},
+	}
+	d.metrics.Register(name, m)
+	d.order = append(d.order, name)
+	sort.Strings(d.order)
+	return nil
+}
+
+func (d *daemon) defaultName() string {
+	if len(d.order) == 0 {
+		return ""
+	}
+	return d.order[0]
+}
+
+// fleetPrefix returns the URL prefix for a fleet's namespaced endpoints. The name is
+// path-escaped; the mux unescapes it again for {name}.
+func fleetPrefix(name string) string {
+	return "/fleets/" + url.PathEscape(name)
+}
+
+// handle registers h at path for the default fleet and at /fleets/{name}/path for every fleet.
+func (d *daemon) handle(mux *http.ServeMux, path string, h func(http.ResponseWriter, *http.Request, *fleetRuntime)) {
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		h(w, r, d.fleets[d.defaultName()])
+	})
+	mux.HandleFunc("/fleets/{name}"+path, func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := d.fleets[r.PathValue("name")]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown fleet %q", r.PathValue("name")), http.StatusNotFound)
+			return
+		}
+		h(w, r, rt)
+	})
+}
+
+// runDaemon starts one control loop per fleet and then serves the HTTP API.
+func runDaemon(d *daemon, addr string, every time.Duration) error {
+	// Normalize address: allow bare port like "8080" by prefixing with ":"
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	for _, name := range d.order {
+		rt := d.fleets[name]
+		log.Printf("Starting control loop for fleet %s every %s (config: %s)", name, every, rt.cfgPath)
+		startControlLoop(rt, every)
+	}
+	log.Printf("Starting HTTP server on %s (%d fleet(s))", addr, len(d.order))
+	return startHTTPServer(d, addr)
+}
@@ -0,0 +1,133 @@
+// cmd/fleetctl/daemon_test.go
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDaemonDir writes an OCI config and one fleet config per name into a temp dir.
+// The OCI config is only parsed; no request leaves the process.
+func writeDaemonDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	ociConfig := filepath.Join(dir, "oci-config")
+	write := func(path, body string) {
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	write(ociConfig, "[DEFAULT]\nuser=ocid1.user.oc1..test\nfingerprint=00:00\ntenancy=ocid1.tenancy.oc1..test\nregion=us-ashburn-1\nkey_file="+filepath.Join(dir, "key.pem")+"\n")
+	for _, name := range names {
+		write(filepath.Join(dir, name+".yaml"), "kind: FleetConfig\nmetadata:\n  name: "+name+"\nspec:\n  auth:\n    method: user\n    configFile: "+ociConfig+"\n")
+	}
+	write(filepath.Join(dir, "notes.txt"), "not a fleet")
+	return dir
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, _ := io.ReadAll(rec.Result().Body)
+	return rec.Code, string(body)
+}
+
+func TestDaemonRoutesFleets(t *testing.T) {
+	d, err := loadDaemon(writeDaemonDir(t, "web", "api"), defaultStatePath)
+	if err != nil {
+		t.Fatalf("loadDaemon: %v", err)
+	}
+	if strings.Join(d.order, ",") != "api,web" {
+		t.Fatalf("order = %v, want [api web]", d.order)
+	}
+	h := d.routes()
+
+	cases := []struct {
+		path  string
+		code  int
+		fleet string // "fleet" field of the /metrics response
+	}{
+		{"/metrics", http.StatusOK, "api"},
+		{"/fleets/api/metrics", http.StatusOK, "api"},
+		{"/fleets/web/metrics", http.StatusOK, "web"},
+		{"/fleets/db/metrics", http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		code, body := get(t, h, c.path)
+		if code != c.code {
+			t.Fatalf("GET %s = %d, want %d: %s", c.path, code, c.code, body)
+		}
+		if c.fleet == "" {
+			continue
+		}
+		var out struct{ Fleet string }
+		if err := json.Unmarshal([]byte(body), &out); err != nil {
+			t.Fatalf("GET %s: %v", c.path, err)
+		}
+		if out.Fleet != c.fleet {
+			t.Fatalf("GET %s served fleet %q, want %q", c.path, out.Fleet, c.fleet)
+		}
+	}
+}
+
+func TestDaemonUIBase(t *testing.T) {
+	d, err := loadDaemon(writeDaemonDir(t, "web", "api"), defaultStatePath)
+	if err != nil {
+		t.Fatalf("loadDaemon: %v", err)
+	}
+	h := d.routes()
+
+	cases := []struct {
+		path, want, selected string
+	}{
+		{"/", `sse-connect="/events"`, `<option value="api" selected>`},
+		{"/fleets/web/", `sse-connect="/fleets/web/events"`, `<option value="web" selected>`},
+	}
+	for _, c := range cases {
+		code, body := get(t, h, c.path)
+		if code != http.StatusOK {
+			t.Fatalf("GET %s = %d", c.path, code)
+		}
+		if !strings.Contains(body, c.want) || !strings.Contains(body, c.selected) {
+			t.Fatalf("GET %s: page lacks %s or %s", c.path, c.want, c.selected)
+		}
+		if strings.Contains(body, "{{BASE}}") {
+			t.Fatalf("GET %s: {{BASE}} left in page", c.path)
+		}
+	}
+	if code, _ := get(t, h, "/fleets/db/"); code != http.StatusNotFound {
+		t.Fatalf("GET /fleets/db/ = %d, want 404", code)
+	}
+}
+
+func TestFleetPrefixEscapesName(t *testing.T) {
+	cases := map[string]string{
+		"web":      "/fleets/web",
+		"a b/c":    "/fleets/a%20b%2Fc",
+		`x"><img>`: "/fleets/x%22%3E%3Cimg%3E",
+	}
+	for name, want := range cases {
+		if got := fleetPrefix(name); got != want {
+			t.Fatalf("fleetPrefix(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	// The escaped prefix routes back to the fleet
+	d := newDaemon()
+	rt := &fleetRuntime{name: "a b/c", status: &controlStatus{}}
+	d.fleets[rt.name], d.order = rt, []string{rt.name}
+	mux := http.NewServeMux()
+	d.handle(mux, "/ping", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		_, _ = w.Write([]byte(rt.name))
+	})
+	if code, body := get(t, mux, fleetPrefix(rt.name)+"/ping"); code != http.StatusOK || body != rt.name {
+		t.Fatalf("GET %s/ping = %d %q, want 200 %q", fleetPrefix(rt.name), code, body, rt.name)
+	}
+}
@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,8 +21,9 @@ import (
 	"fleetctl/internal/config"
 	"fleetctl/internal/diagram"
 	"fleetctl/internal/fleet"
+	"fleetctl/internal/lock"
+	"fleetctl/internal/maintenance"
 	"fleetctl/internal/metrics"
-	"fleetctl/internal/state"
 )
 
 const version = "0.1.0"
@@ -37,6 +40,11 @@ var (
 	flagHTTP           string
 	flagReconcileEvery time.Duration
 	flagDiagram        string
+	flagConfigDir      string
+	flagForceUnlock    bool
+	flagOverrideWindow string
+	flagScaleTTL       time.Duration
+	flagClearDesired   bool
 )
 
 // controlStatus tracks the background control loop state for diagnostics.
@@ -51,6 +59,11 @@ type controlStatus struct {
 	LastAction       string
 	LastError        string
 	LoopCount        int
+	Errors           int                   // errors recorded by fail since the loop started
+	ActualGroups     map[string]int        // remote instances per group at the last tick
+	Lock             *lock.Status          // refreshed every tick when locking is available
+	WarmPool         *fleet.WarmPoolStatus // refreshed every tick when spec.warmPool is set
+	DesiredState     *fleet.DesiredState   // baseline, override and resulting target of the last tick
 }
 
 func (c *controlStatus) set(update func(*controlStatus)) {
@@ -59,6 +72,14 @@ func (c *controlStatus) set(update func(*controlStatus)) {
 	update(c)
 }
 
+// fail records msg as the last error and counts it.
+func (c *controlStatus) fail(msg string) {
+	c.set(func(c *controlStatus) {
+		c.LastError = msg
+		c.Errors++
+	})
+}
+
 func (c *controlStatus) snapshot() map[string]any {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -76,32 +97,43 @@ func (c *controlStatus) snapshot() map[string]any {
 		"lastAction":       c.LastAction,
 		"lastError":        c.LastError,
 		"loopCount":        c.LoopCount,
+		"errors":           c.Errors,
+		"actualGroups":     c.ActualGroups,
+		"lock":             c.Lock,
+		"warmPool":         c.WarmPool,
+		"desiredState":     c.DesiredState,
 	}
 }
 
-var ctrlStatus controlStatus
-
 func init() {
 	flag.StringVar(&flagConfig, "config", "fleet.yaml", "Path to fleet configuration file")
 	flag.IntVar(&flagScale, "scale", -1, "Scale fleet to desired total number of instances")
 	flag.BoolVar(&flagRollingRestart, "rolling-restart", false, "Perform a rolling restart of the fleet")
 	flag.BoolVar(&flagVersion, "version", false, "Print version and exit")
 	flag.BoolVar(&flagStatus, "status", false, "Print tracked fleet state from local store")
-	flag.StringVar(&flagState, "state", ".fleetctl/state.json", "Path to local state JSON for tracking launched instances")
+	flag.StringVar(&flagState, "state", defaultStatePath, "Path to local state JSON for tracking launched instances")
 	flag.BoolVar(&flagAuthValidate, "auth-validate", false, "Validate OCI authentication by performing a lightweight API call")
 	flag.BoolVar(&flagSyncState, "sync-state", false, "Rebuild local state by querying OCI for instances tagged to this fleet")
-	flag.StringVar(&flagHTTP, "http", "", "Listen address for HTTP API (e.g., :8080). Serves /healthz, /status, /metrics and command endpoints.")
+	flag.StringVar(&flagHTTP, "http", "", "Listen address for HTTP API (e.g., :8080). Serves /healthz, /status, /metrics, /metrics/prometheus and command endpoints.")
 	flag.DurationVar(&flagReconcileEvery, "reconcile-every", 30*time.Second, "Background reconcile interval for --http mode (e.g., 30s, 1m)")
 	flag.StringVar(&flagDiagram, "diagram", "", "Generate Mermaid diagram (packages, architecture)")
+	flag.BoolVar(&flagForceUnlock, "force-unlock", false, "Clear this fleet's cross-process lock (use only when the holder is gone)")
+	flag.StringVar(&flagConfigDir, "config-dir", "", "Directory of fleet configuration files; with --http, runs one control loop per fleet")
+	flag.DurationVar(&flagScaleTTL, "scale-ttl", 0, "How long the --scale target overrides the config baseline (default spec.scaling.overrideTTL, 24h)")
+	flag.BoolVar(&flagClearDesired, "clear-desired", false, "Drop the desired override set by --scale or POST /scale; the config baseline applies again")
+	flag.StringVar(&flagOverrideWindow, "override-window", "", "Run --scale (down) or --rolling-restart outside maintenance windows; the reason is recorded in history")
 
 	// Custom usage printer
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "fleetctl %s\n\nUsage:\n  %s [flags]\n\nRequires: --config plus at least one additional flag, or --diagram, or --version\n\nFlags:\n", version, os.Args[0])
+		fmt.Fprintf(os.Stderr, "fleetctl %s\n\nUsage:\n  %s [flags]\n  %s state migrate|backup|list-snapshots|restore|export|import [flags]\n\nRequires: --config (or --config-dir) plus at least one additional flag, or --diagram, or --version\n\nFlags:\n", version, os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
+	if isStateCommand() {
+		os.Exit(runStateCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
 	flag.Parse()
 
 	if flagVersion {
@@ -142,13 +174,13 @@ func main() {
 		return
 	}
 
-	// Require at least two flags to be provided, and one must be --config.
+	// Require at least two flags to be provided, and one must be --config (or --config-dir).
 	// This enforces usage like: --config <file> plus one action flag (e.g., --auth-validate, --status, --scale, --rolling-restart).
 	var visitedCount int
 	hasConfig := false
 	flag.Visit(func(f *flag.Flag) {
 		visitedCount++
-		if f.Name == "config" {
+		if f.Name == "config" || f.Name == "config-dir" {
 			hasConfig = true
 		}
 	})
@@ -156,41 +188,47 @@ func main() {
 		flag.Usage()
 		os.Exit(1)
 	}
+	overrideSet := false
+	flag.Visit(func(f *flag.Flag) { overrideSet = overrideSet || f.Name == "override-window" })
+	if overrideSet && strings.TrimSpace(flagOverrideWindow) == "" {
+		log.Fatalf("--override-window requires a reason, e.g. --override-window \"hotfix for incident 42\"")
+	}
+	cliOpts := fleet.OpOptions{Source: fleet.SourceCLI, OverrideWindow: strings.TrimSpace(flagOverrideWindow)}
+
+	// Multi-fleet daemon: load every config in --config-dir and run one control loop per fleet.
+	if flagConfigDir != "" {
+		if flagHTTP == "" {
+			log.Fatalf("--config-dir requires --http")
+		}
+		d, err := loadDaemon(flagConfigDir, flagState)
+		if err != nil {
+			log.Fatalf("load fleets from %s: %v", flagConfigDir, err)
+		}
+		if err := runDaemon(d, flagHTTP, flagReconcileEvery); err != nil {
+			log.Fatalf("http server error: %v", err)
+		}
+		return
+	}
 
 	cfg, err := config.ParseFile(flagConfig)
 	if err != nil {
 		log.Fatalf("failed to load configuration from %s: %v", flagConfig, err)
 	}
 
-	// Resolve default state path to be alongside the config file and reflect the fleet name, unless overridden.
-	statePath := flagState
-	cfgDir := filepath.Dir(flagConfig)
-	if statePath == ".fleetctl/state.json" {
-		base := fmt.Sprintf(".%s.state.json", cfg.Metadata.Name)
-		statePath = filepath.Join(cfgDir, base)
+	statePath := resolveStatePath(flagState, flagConfig, cfg.Metadata.Name)
+	st, err := openStore(cfg, statePath, nil)
+	if err != nil {
+		log.Fatalf("state: %v", err)
 	}
-	st := state.New(statePath)
-	f := fleet.New(*cfg, nil, st)
+	f := fleet.New(*cfg, nil, st, nil)
 
 	switch {
 	case flagHTTP != "":
-		// Initialize OCI client for remote operations
-		if f.Client == nil {
-			cli, err := client.New(cfg.Spec.Auth)
-			if err != nil {
-				log.Fatalf("init OCI client: %v", err)
-			}
-			f.Client = cli
-		}
-		// Normalize address: allow bare port like "8080" by prefixing with ":"
-		addr := flagHTTP
-		if !strings.Contains(addr, ":") {
-			addr = ":" + addr
+		d := newDaemon()
+		if err := d.add(flagConfig, cfg, st, statePath); err != nil {
+			log.Fatalf("init fleet %s: %v", cfg.Metadata.Name, err)
 		}
-		log.Printf("Starting control loop every %s (config: %s)", flagReconcileEvery, flagConfig)
-		startControlLoop(f, flagConfig, flagReconcileEvery)
-		log.Printf("Starting HTTP server on %s", addr)
-		if err := startHTTPServer(f, st, cfg, addr); err != nil {
+		if err := runDaemon(d, flagHTTP, flagReconcileEvery); err != nil {
 			log.Fatalf("http server error: %v", err)
 		}
 	case flagSyncState:
@@ -200,6 +238,7 @@ func main() {
 		}
 
 		f.Client = stubClient
+		f.Lock = mustFleetLock(*cfg, statePath, stubClient)
 		if err := f.SyncState(); err != nil {
 			log.Fatalf("sync-state failed: %v", err)
 		}
@@ -239,9 +278,26 @@ func main() {
 			}
 			f.Client = cli
 		}
-		if err := f.Scale(flagScale); err != nil {
+		f.Lock = mustFleetLock(*cfg, statePath, f.Client)
+		err := f.ScaleWithOptions(flagScale, cliOpts)
+		var be *maintenance.BlockedError
+		if err != nil && errors.As(err, &be) {
 			log.Fatalf("scale failed: %v", err)
 		}
+		// Persist the target so a running daemon converges on it instead of the config baseline.
+		if o, serr := f.SetDesired(flagScale, flagScaleTTL, cliOpts); serr != nil {
+			log.Printf("warning: %v", serr)
+		} else {
+			fmt.Printf("desired %d persisted until %s\n", o.Desired, o.ExpiresAt.Format(time.RFC3339))
+		}
+		if err != nil {
+			log.Fatalf("scale failed: %v", err)
+		}
+	case flagClearDesired:
+		if err := f.ClearDesired(cliOpts); err != nil {
+			log.Fatalf("clear desired: %v", err)
+		}
+		fmt.Printf("desired override cleared; config baseline %d applies\n", f.Baseline())
 	case flagRollingRestart:
 		if f.Client == nil {
 			cli, err := client.New(cfg.Spec.Auth)
@@ -250,7 +306,8 @@ func main() {
 			}
 			f.Client = cli
 		}
-		if err := f.RollingRestart(); err != nil {
+		f.Lock = mustFleetLock(*cfg, statePath, f.Client)
+		if err := f.RollingRestartWithOptions(cliOpts); err != nil {
 			log.Fatalf("rolling restart failed: %v", err)
 		}
 	case flagStatus:
@@ -262,20 +319,63 @@ func main() {
 			}
 			f.Client = cli
 		}
+		f.Lock = mustFleetLock(*cfg, statePath, f.Client)
 		// Use Fleet.StatusCompare to print clearly labeled local vs remote sections
 		out, err := f.StatusCompare()
 		if err != nil {
 			log.Fatalf("status failed: %v", err)
 		}
 		fmt.Println(out)
+	case flagForceUnlock:
+		var cli *client.Client
+		if strings.TrimSpace(cfg.Spec.Lock.Lease) != "" {
+			if cli, err = client.New(cfg.Spec.Auth); err != nil {
+				log.Fatalf("init OCI client: %v", err)
+			}
+		}
+		lk := mustFleetLock(*cfg, statePath, cli)
+		if st, err := lk.Status(context.Background()); err == nil && st.Held && st.Info != nil {
+			log.Printf("force-unlock: clearing lock held by %s (%s) since %s", st.Info.Holder, st.Info.Operation, st.Info.AcquiredAt.Format(time.RFC3339))
+		}
+		if err := lk.ForceUnlock(context.Background()); err != nil {
+			log.Fatalf("force-unlock failed: %v", err)
+		}
+		fmt.Printf("Lock cleared for fleet %q\n", cfg.Metadata.Name)
 	default:
 		// If only --config (or other non-action flags) are provided, print a summary by default.
 		fmt.Println(f.Summary())
 	}
 }
 
-// startHTTPServer serves health, metrics, status and control endpoints.
-func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, addr string) error {
+// mustFleetLock builds the fleet's cross-process lock (flock next to the state file, plus any configured lease).
+func mustFleetLock(cfg config.FleetConfig, statePath string, cli *client.Client) lock.Locker {
+	var lk lock.Locker
+	var err error
+	lockPath := lockPathFor(statePath, cfg.Metadata.Name)
+	if cli != nil {
+		lk, err = lock.ForFleet(cfg, lockPath, cli.Provider, cli.Region)
+	} else {
+		lk, err = lock.ForFleet(cfg, lockPath, nil, "")
+	}
+	if err != nil {
+		log.Fatalf("init fleet lock: %v", err)
+	}
+	return lk
+}
+
+// startHTTPServer serves the daemon's HTTP API on addr.
+func startHTTPServer(d *daemon, addr string) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: d.routes(),
+	}
+	return server.ListenAndServe()
+}
+
+// routes returns the health, metrics, status and control endpoints.
+// Per-fleet endpoints are served under /fleets/{name}/...; the unprefixed
+// paths remain as aliases for the default (first) fleet.
+func (d *daemon) routes() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -283,7 +383,23 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+	// List managed fleets with their control loop snapshots
+	mux.HandleFunc("/fleets", func(w http.ResponseWriter, r *http.Request) {
+		out := make([]map[string]any, 0, len(d.order))
+		for _, name := range d.order {
+			rt := d.fleets[name]
+			out = append(out, map[string]any{
+				"name":    rt.name,
+				"config":  rt.cfgPath,
+				"control": rt.status.snapshot(),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	d.handle(mux, "/status", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		f := rt.fleet
 		out, err := f.StatusCompare()
 		if err != nil {
 			http.Error(w, fmt.Sprintf("status error: %v", err), http.StatusInternalServerError)
@@ -293,11 +409,12 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 		_, _ = w.Write([]byte(out))
 	})
 
-	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+	d.handle(mux, "/metrics", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		st := rt.store
 		// Simple JSON metrics for now
-		localActive, _ := st.CountActive(cfg.Metadata.Name)
+		localActive, _ := st.CountActive(rt.name)
 		// use control loop snapshot to avoid concurrent SDK calls (prevents race)
-		cs := ctrlStatus.snapshot()
+		cs := rt.status.snapshot()
 		remoteActive := 0
 		if v, ok := cs["actual"].(int); ok {
 			remoteActive = v
@@ -305,37 +422,63 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 			remoteActive = int(df)
 		}
 		var lbSnapshot any
-		if lb, ok, _ := st.GetLBInfo(cfg.Metadata.Name); ok {
+		if lb, ok, _ := st.GetLBInfo(rt.name); ok {
 			lbSnapshot = map[string]any{
-				"enabled":       lb.Enabled,
-				"id":            lb.ID,
-				"backendSet":    lb.BackendSet,
-				"listener":      lb.Listener,
-				"backends":      lb.Backends,
-				"backendsCount": lb.BackendsCount,
-				"updatedAt":     lb.UpdatedAt.Format(time.RFC3339),
+				"enabled":          lb.Enabled,
+				"id":               lb.ID,
+				"backendSet":       lb.BackendSet,
+				"listener":         lb.Listener,
+				"backends":         lb.Backends,
+				"backendsCount":    lb.BackendsCount,
+				"updatedAt":        lb.UpdatedAt.Format(time.RFC3339),
+				"needsReplacement": lb.NeedsReplacement,
 			}
 		}
 		resp := map[string]any{
-			"fleet":        cfg.Metadata.Name,
+			"fleet":        rt.name,
 			"localActive":  localActive,
 			"remoteActive": remoteActive,
 			"timestamp":    time.Now().Format(time.RFC3339),
-			"control":      ctrlStatus.snapshot(),
-			"actions":      metrics.Snapshot(),
+			"control":      cs,
+			"actions":      rt.fleet.Metrics.Snapshot(),
 			"lb":           lbSnapshot,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	})
 
-	mux.HandleFunc("/scale", func(w http.ResponseWriter, r *http.Request) {
+	// Operation metrics of every fleet with totals across fleets
+	mux.HandleFunc("/metrics/fleets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(d.metrics.Snapshot())
+	})
+
+	// Prometheus text exposition: every fleet unprefixed, one fleet under /fleets/{name}
+	mux.HandleFunc("/metrics/prometheus", func(w http.ResponseWriter, r *http.Request) {
+		fleets := make([]*fleetRuntime, 0, len(d.order))
+		for _, name := range d.order {
+			fleets = append(fleets, d.fleets[name])
+		}
+		writePrometheus(w, fleets, d.metrics.Collect)
+	})
+	mux.HandleFunc("/fleets/{name}/metrics/prometheus", func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := d.fleets[r.PathValue("name")]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown fleet %q", r.PathValue("name")), http.StatusNotFound)
+			return
+		}
+		writePrometheus(w, []*fleetRuntime{rt}, func(e *metrics.Exposition) { rt.fleet.Metrics.Collect(e, "fleet", rt.name) })
+	})
+
+	d.handle(mux, "/scale", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		var body struct {
-			Desired int `json:"desired"`
+			Desired        int    `json:"desired"`
+			OverrideWindow string `json:"overrideWindow"` // reason for running outside maintenance windows
+			TTL            string `json:"ttl"`            // lifetime of the desired override (default spec.scaling.overrideTTL)
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, "invalid json", http.StatusBadRequest)
@@ -345,28 +488,74 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 			http.Error(w, "desired must be >= 0", http.StatusBadRequest)
 			return
 		}
+		var ttl time.Duration
+		if body.TTL != "" {
+			d, err := time.ParseDuration(body.TTL)
+			if err != nil || d <= 0 {
+				http.Error(w, "ttl must be a positive duration (e.g. 2h)", http.StatusBadRequest)
+				return
+			}
+			ttl = d
+		}
 		desired := body.Desired
+		opts := fleet.OpOptions{Source: fleet.SourceAPI, OverrideWindow: strings.TrimSpace(body.OverrideWindow)}
+		admitErr := rt.fleet.AdmitScale(desired, opts)
+		var be *maintenance.BlockedError
+		queued := errors.As(admitErr, &be) && be.Queued
+		if admitErr != nil && !queued {
+			writeAdmitError(w, admitErr)
+			return
+		}
+		// Persist the target so the control loop converges on it instead of the config baseline.
+		if _, err := rt.fleet.SetDesired(desired, ttl, opts); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if queued {
+			writeAdmitError(w, admitErr)
+			return
+		}
 		// Enqueue requested desired to show in Scale queue badge immediately.
 		// Do not override current scaling badge; it should reflect the active operation.
-		localActive, _ := st.CountActive(cfg.Metadata.Name)
+		localActive, _ := rt.store.CountActive(rt.name)
 		if desired != localActive {
-			metrics.AppendScaleQueue(desired)
+			rt.fleet.Metrics.AppendScaleQueue(desired)
 		}
 		go func(d int) {
-			if err := f.Scale(d); err != nil {
-				log.Printf("scale failed (async): %v", err)
+			if err := rt.fleet.ScaleWithOptions(d, opts); err != nil {
+				log.Printf("scale failed (async, fleet %s): %v", rt.name, err)
 			}
 		}(desired)
 		w.WriteHeader(http.StatusAccepted)
 		_, _ = w.Write([]byte("scale accepted"))
 	})
 
-	mux.HandleFunc("/rolling-restart", func(w http.ResponseWriter, r *http.Request) {
+	d.handle(mux, "/rolling-restart", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		if err := f.RollingRestart(); err != nil {
+		// Optional body: {"overrideWindow": "<reason>"}
+		var body struct {
+			OverrideWindow string `json:"overrideWindow"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid json", http.StatusBadRequest)
+				return
+			}
+		}
+		opts := fleet.OpOptions{Source: fleet.SourceAPI, OverrideWindow: strings.TrimSpace(body.OverrideWindow)}
+		if err := rt.fleet.AdmitRollingRestart(opts); err != nil {
+			writeAdmitError(w, err)
+			return
+		}
+		if err := rt.fleet.RollingRestartWithOptions(opts); err != nil {
+			var be *maintenance.BlockedError
+			if errors.As(err, &be) {
+				http.Error(w, fmt.Sprintf("rolling restart stopped: %v", err), http.StatusConflict)
+				return
+			}
 			http.Error(w, fmt.Sprintf("rolling restart failed: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -374,12 +563,12 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 		_, _ = w.Write([]byte("rolling-restart OK"))
 	})
 
-	mux.HandleFunc("/sync-state", func(w http.ResponseWriter, r *http.Request) {
+	d.handle(mux, "/sync-state", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		if err := f.SyncState(); err != nil {
+		if err := rt.fleet.SyncState(); err != nil {
 			http.Error(w, fmt.Sprintf("sync-state failed: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -388,13 +577,65 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 	})
 
 	// Emit control loop status
-	mux.HandleFunc("/control", func(w http.ResponseWriter, r *http.Request) {
+	d.handle(mux, "/control", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rt.status.snapshot())
+	})
+
+	// Operation history (newest last); ?limit=N returns the last N entries
+	d.handle(mux, "/history", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		limit := 50
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				limit = n
+			}
+		}
+		hist, err := rt.store.History(rt.name, limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("history: %v", err), http.StatusInternalServerError)
+			return
+		}
+		queued, _ := rt.store.Queued(rt.name)
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(ctrlStatus.snapshot())
+		_ = json.NewEncoder(w).Encode(map[string]any{"history": hist, "queued": queued})
+	})
+
+	// Tracked instances with the details recorded at launch and sync
+	d.handle(mux, "/instances", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		recs, err := rt.store.ActiveRecords(rt.name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("instances: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"configRevision": rt.fleet.Config.Spec.Revision(), "instances": recs})
+	})
+
+	// Desired state: GET shows baseline, override and target; DELETE drops the override
+	d.handle(mux, "/desired", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			if err := rt.fleet.ClearDesired(fleet.OpOptions{Source: fleet.SourceAPI}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ds, err := rt.fleet.Desired()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ds)
 	})
 
 	// Server-Sent Events for live updates
-	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+	d.handle(mux, "/events", func(w http.ResponseWriter, r *http.Request, rt *fleetRuntime) {
+		f := rt.fleet
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
@@ -418,7 +659,7 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 				return
 			case <-ticker.C:
 				// control snapshot first (authoritative desired from control loop that reloads config)
-				ctrl := ctrlStatus.snapshot()
+				ctrl := rt.status.snapshot()
 				desired := 0
 				if dv, ok := ctrl["desired"].(int); ok {
 					desired = dv
@@ -427,7 +668,7 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 				}
 
 				// compute local/remote
-				localActive, _ := st.CountActive(cfg.Metadata.Name)
+				localActive, _ := rt.store.CountActive(rt.name)
 				remoteActive := 0
 				if v, ok := ctrl["actual"].(int); ok {
 					remoteActive = v
@@ -441,7 +682,7 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 					desired, remoteActive, localActive,
 				)
 				// fleet name header
-				fleetNameHTML := fmt.Sprintf("<div class='fleet-name'>Fleet: <code>%s</code></div>", html.EscapeString(cfg.Metadata.Name))
+				fleetNameHTML := fmt.Sprintf("<div class='fleet-name'>Fleet: <code>%s</code></div>", html.EscapeString(rt.name))
 				// minimums from config (sum and per-group)
 				minTotal := 0
 				var groupParts []string
@@ -458,7 +699,7 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 				minimumsHTML := fmt.Sprintf("<div class='minimums'><div class='label'>Config file Minimums</div><div class='value'>%d</div><div class='groups'>%s</div></div>", minTotal, html.EscapeString(strings.Join(groupParts, " ")))
 
 				// metrics HTML
-				act := metrics.Snapshot()
+				act := rt.fleet.Metrics.Snapshot()
 				actJSON, _ := json.MarshalIndent(act, "", "  ")
 				metricsHTML := "<pre>" + string(actJSON) + "</pre>"
 
@@ -624,106 +865,230 @@ func startHTTPServer(f *fleet.Fleet, st *state.Store, cfg *config.FleetConfig, a
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(openAPISpecJSON()))
 	})
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_, _ = w.Write([]byte(uiPageHTML()))
+		_, _ = w.Write([]byte(uiPageHTML("", d.defaultName(), d.order)))
 	})
+	mux.HandleFunc("/fleets/{name}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := d.fleets[r.PathValue("name")]
+		if !ok {
+			http.Error(w, "unknown fleet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(uiPageHTML(fleetPrefix(rt.name), rt.name, d.order)))
+	})
+	return mux
+}
 
-	server := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+// writeAdmitError maps an admission failure to an HTTP response: 202 when the request was
+// queued for the next maintenance window, 409 when it is blocked, 500 otherwise.
+func writeAdmitError(w http.ResponseWriter, err error) {
+	var be *maintenance.BlockedError
+	switch {
+	case errors.As(err, &be) && be.Queued:
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(be.Error()))
+	case errors.As(err, &be):
+		http.Error(w, be.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
-	return server.ListenAndServe()
 }
 
-func startControlLoop(f *fleet.Fleet, cfgPath string, every time.Duration) {
+// controlScaleDown scales the fleet down toward target from the control loop. The disruption
+// budget is spent in steps: only as many instances as it allows are removed per tick.
+func controlScaleDown(rt *fleetRuntime, actual, target int) {
+	f, status := rt.fleet, rt.status
+	remaining, limited, err := f.DisruptionAllowance()
+	if err != nil {
+		status.fail(err.Error())
+		log.Printf("control[%s]: disruption budget: %v", rt.name, err)
+		return
+	}
+	if limited && actual-target > remaining {
+		if remaining == 0 {
+			msg := fmt.Sprintf("scale down to %d waiting for disruption budget", target)
+			status.set(func(c *controlStatus) { c.LastAction = msg })
+			log.Printf("control[%s]: %s", rt.name, msg)
+			return
+		}
+		target = actual - remaining
+	}
+	status.set(func(c *controlStatus) { c.LastAction = fmt.Sprintf("scale down to %d", target) })
+	log.Printf("control[%s]: scaling down to meet target; target=%d actual=%d", rt.name, target, actual)
+	if err := f.ScaleWithOptions(target, fleet.OpOptions{Source: fleet.SourceControlLoop}); err != nil {
+		var be *maintenance.BlockedError
+		if errors.As(err, &be) {
+			status.set(func(c *controlStatus) { c.LastAction = err.Error() })
+			log.Printf("control[%s]: %v", rt.name, err)
+			return
+		}
+		status.fail(err.Error())
+		log.Printf("control[%s]: scale down to %d failed: %v", rt.name, target, err)
+	}
+}
+
+// startControlLoop runs the reconcile loop for one fleet in the background.
+func startControlLoop(rt *fleetRuntime, every time.Duration) {
+	f := rt.fleet
+	cfgPath := rt.cfgPath
+	status := rt.status
 	go func() {
 		ticker := time.NewTicker(every)
 		defer ticker.Stop()
 
 		var lastMod time.Time
+		var stab fleet.Stabilizer // hysteresis for scale decisions; lives as long as the loop
 
-		ctrlStatus.set(func(c *controlStatus) {
+		status.set(func(c *controlStatus) {
 			c.Enabled = true
 			c.Interval = every.String()
 			c.LastError = ""
 		})
 
+		// 0) Startup: resolve launch intents left by a previous run that stopped mid-launch
+		if f.Client != nil {
+			if ids, err := f.RecoverLaunches(context.Background()); err != nil {
+				status.fail(err.Error())
+				log.Printf("control[%s]: recover pending launches: %v", rt.name, err)
+			} else if len(ids) > 0 {
+				status.set(func(c *controlStatus) {
+					c.LastAction = fmt.Sprintf("recovered %d instance(s) from pending launches", len(ids))
+				})
+			}
+		}
+
 		for {
-			ctrlStatus.set(func(c *controlStatus) {
+			status.set(func(c *controlStatus) {
 				c.LastTick = time.Now()
 				c.LoopCount++
 			})
 			// 1) Reload config if modified
 			if fi, err := os.Stat(cfgPath); err == nil {
 				if fi.ModTime().After(lastMod) {
-					if newCfg, err := config.ParseFile(cfgPath); err == nil {
+					if newCfg, err := config.ParseFile(cfgPath); err != nil {
+						log.Printf("control[%s]: parse config error: %v", rt.name, err)
+					} else if newCfg.Metadata.Name != rt.name {
+						lastMod = fi.ModTime()
+						msg := fmt.Sprintf("config %s renamed fleet %q to %q; restart the daemon to apply", cfgPath, rt.name, newCfg.Metadata.Name)
+						status.fail(msg)
+						log.Printf("control[%s]: %s", rt.name, msg)
+					} else {
 						f.Config = *newCfg
 						lastMod = fi.ModTime()
 						t := fi.ModTime()
 						tCopy := t
-						ctrlStatus.set(func(c *controlStatus) {
+						status.set(func(c *controlStatus) {
 							c.LastConfigReload = &tCopy
 							c.LastError = ""
 						})
-						log.Printf("control: reloaded config (modified %s)", fi.ModTime().Format(time.RFC3339))
-					} else {
-						log.Printf("control: parse config error: %v", err)
+						log.Printf("control[%s]: reloaded config (modified %s)", rt.name, fi.ModTime().Format(time.RFC3339))
 					}
 				}
 			} else {
-				ctrlStatus.set(func(c *controlStatus) { c.LastError = err.Error() })
-				log.Printf("control: stat config error: %v", err)
+				status.fail(err.Error())
+				log.Printf("control[%s]: stat config error: %v", rt.name, err)
 			}
 
-			// 2) Determine desired total from config, then apply lower-bound from local state (only scale up)
-			desired := 0
-			for _, g := range f.Config.Spec.Instances {
-				desired += g.Count
+			// 2) Run operations queued for the maintenance window once it opens
+			if f.Client != nil {
+				if ran, err := f.RunQueued(); err != nil {
+					status.fail(err.Error())
+					log.Printf("control[%s]: queued operations: %v", rt.name, err)
+				} else if len(ran) > 0 {
+					status.set(func(c *controlStatus) { c.LastAction = fmt.Sprintf("ran %d queued operation(s)", len(ran)) })
+				}
 			}
-			// baseline from local state: do not go below what's tracked locally
-			target := desired
-			if f.Store != nil {
-				if la, err := f.Store.CountActive(f.Config.Metadata.Name); err == nil && la > target {
-					target = la
+
+			// 3) Detect preemptible instances reclaimed by OCI; they no longer count as active
+			// and are backfilled below because actual drops under the target.
+			if f.Client != nil {
+				if ids, err := f.DetectReclaimed(context.Background()); err != nil {
+					log.Printf("control[%s]: detect reclaimed error: %v", rt.name, err)
+				} else if len(ids) > 0 {
+					log.Printf("control[%s]: %d preemptible instance(s) reclaimed; backfilling", rt.name, len(ids))
 				}
 			}
-			ctrlStatus.set(func(c *controlStatus) { c.Desired = target })
 
-			// 3) Compare actual vs desired and reconcile if needed
+			// 4) Determine the desired total: the config baseline, or an unexpired override
+			// persisted in state by --scale or POST /scale
+			ds, err := f.Desired()
+			if err != nil {
+				status.fail(err.Error())
+				log.Printf("control[%s]: desired state error: %v (using config baseline)", rt.name, err)
+			}
+			target := ds.Target
+			status.set(func(c *controlStatus) {
+				c.Desired = target
+				c.DesiredState = &ds
+			})
+
+			// 5) Compare actual vs desired and reconcile if needed
 			if f.Client != nil {
 				inst, err := f.Client.ListInstancesByFleet(context.Background(), f.Config.Spec.CompartmentID, f.Config.Metadata.Name)
 				if err != nil {
-					ctrlStatus.set(func(c *controlStatus) { c.LastError = err.Error() })
-					log.Printf("control: list instances error: %v", err)
+					status.fail(err.Error())
+					log.Printf("control[%s]: list instances error: %v", rt.name, err)
 				} else {
 					actual := len(inst)
-					ctrlStatus.set(func(c *controlStatus) {
+					groups := f.GroupCounts(inst)
+					status.set(func(c *controlStatus) {
 						c.Actual = actual
+						c.ActualGroups = groups
 						c.LastError = ""
 					})
-					if actual < target {
-						ctrlStatus.set(func(c *controlStatus) { c.LastAction = fmt.Sprintf("scale up to %d", target) })
-						log.Printf("control: scaling up to meet target; target=%d actual=%d", target, actual)
-						if err := f.Scale(target); err != nil {
-							ctrlStatus.set(func(c *controlStatus) { c.LastError = err.Error() })
-							log.Printf("control: scale up to %d failed: %v", target, err)
+					scaling := f.Config.Spec.Scaling
+					next, wait := stab.Recommend(time.Now(), actual, target, scaling.UpStabilization(), scaling.DownStabilization())
+					switch {
+					case next == actual && wait > 0:
+						msg := fmt.Sprintf("scale %s to %d pending (stabilizing, %s left)", stab.Pending(), target, wait.Round(time.Second))
+						status.set(func(c *controlStatus) { c.LastAction = msg })
+						log.Printf("control[%s]: %s; actual=%d", rt.name, msg, actual)
+					case next > actual:
+						status.set(func(c *controlStatus) { c.LastAction = fmt.Sprintf("scale up to %d", next) })
+						log.Printf("control[%s]: scaling up to meet target; target=%d actual=%d", rt.name, next, actual)
+						if err := f.ScaleWithOptions(next, fleet.OpOptions{Source: fleet.SourceControlLoop}); err != nil {
+							status.fail(err.Error())
+							log.Printf("control[%s]: scale up to %d failed: %v", rt.name, next, err)
 						}
-					} else {
-						ctrlStatus.set(func(c *controlStatus) { c.LastAction = "noop" })
-						log.Printf("control: actual (%d) meets or exceeds target (%d); no downscale", actual, target)
+					case next < actual:
+						controlScaleDown(rt, actual, next)
+					default:
+						status.set(func(c *controlStatus) { c.LastAction = "noop" })
 					}
 				}
 			}
 
-			// 4) Load balancer reconcile every tick
+			// 6) Refill the warm pool after scaling has taken what it needs
+			if f.Client != nil && f.Config.Spec.WarmPool != nil {
+				wp, err := f.RefillWarmPool(context.Background())
+				if err != nil {
+					status.fail(err.Error())
+					log.Printf("control[%s]: warm pool refill error: %v", rt.name, err)
+				}
+				status.set(func(c *controlStatus) { c.WarmPool = &wp })
+			} else {
+				status.set(func(c *controlStatus) { c.WarmPool = nil })
+			}
+
+			// 7) Reconcile the load balancer and other registrars every tick
 			if f.Client != nil {
-				ctrlStatus.set(func(c *controlStatus) { c.LastAction = "lb-reconcile" })
-				if err := f.ReconcileLoadBalancer(context.Background()); err != nil {
-					ctrlStatus.set(func(c *controlStatus) { c.LastError = err.Error() })
-					log.Printf("control: lb reconcile error: %v", err)
+				status.set(func(c *controlStatus) { c.LastAction = "backend-reconcile" })
+				if err := f.ReconcileBackends(context.Background()); err != nil {
+					status.fail(err.Error())
+					log.Printf("control[%s]: backend reconcile error: %v", rt.name, err)
+				} else {
+					status.set(func(c *controlStatus) { c.LastError = "" })
+				}
+			}
+
+			// 8) Refresh lock status for /control (handlers never query lock backends directly)
+			if ls, ok, err := f.LockStatus(context.Background()); ok {
+				if err != nil {
+					log.Printf("control[%s]: lock status error: %v", rt.name, err)
 				} else {
-					ctrlStatus.set(func(c *controlStatus) { c.LastError = "" })
+					status.set(func(c *controlStatus) { c.Lock = &ls })
 				}
 			}
 
@@ -739,7 +1104,7 @@ func openAPISpecJSON() string {
   "info": {
     "title": "fleetctl API",
     "version": "0.1.0",
-    "description": "HTTP API for fleetctl daemon: health, status, metrics, and control operations"
+    "description": "HTTP API for fleetctl daemon: health, status, metrics, and control operations. Per-fleet endpoints are also served under /fleets/{name}/ (e.g. /fleets/{name}/scale); unprefixed paths address the default fleet."
   },
   "paths": {
     "/healthz": {
@@ -750,6 +1115,61 @@ func openAPISpecJSON() string {
         }
       }
     },
+    "/fleets": {
+      "get": {
+        "summary": "List managed fleets",
+        "responses": {
+          "200": {
+            "description": "Fleets with their control loop status",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "properties": {
+                      "name": { "type": "string" },
+                      "config": { "type": "string" },
+                      "control": { "type": "object" }
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/fleets/{name}/scale": {
+      "post": {
+        "summary": "Scale the named fleet to desired total",
+        "parameters": [
+          { "name": "name", "in": "path", "required": true, "schema": { "type": "string" } }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "desired": { "type": "integer", "minimum": 0 },
+                  "overrideWindow": { "type": "string", "description": "Reason for scaling in outside maintenance windows; recorded in history" },
+                  "ttl": { "type": "string", "description": "How long desired overrides the config baseline (Go duration; default spec.scaling.overrideTTL)" }
+                },
+                "required": ["desired"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": { "description": "Accepted - scaling in background, or queued for the next maintenance window", "content": { "text/plain": { } } },
+          "400": { "description": "Bad request", "content": { "text/plain": { } } },
+          "409": { "description": "Blocked by maintenance windows or the disruption budget", "content": { "text/plain": { } } },
+          "404": { "description": "Unknown fleet", "content": { "text/plain": { } } }
+        }
+      }
+    },
     "/status": {
       "get": {
         "summary": "Local vs Remote (OCI) status",
@@ -786,6 +1206,36 @@ func openAPISpecJSON() string {
         }
       }
     },
+    "/metrics/fleets": {
+      "get": {
+        "summary": "Operation metrics of every fleet with the operations running and totals across fleets",
+        "responses": {
+          "200": {
+            "description": "Aggregated metrics",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "fleets": { "type": "object", "additionalProperties": { "type": "object" } },
+                    "running": { "type": "object", "additionalProperties": { "type": "string" } },
+                    "totals": { "type": "object", "additionalProperties": { "type": "integer" } }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metrics/prometheus": {
+      "get": {
+        "summary": "Prometheus text exposition of every fleet (per-group instances, LB backends, control loop and operation series)",
+        "responses": {
+          "200": { "description": "Metrics in the Prometheus text format 0.0.4", "content": { "text/plain": { } } }
+        }
+      }
+    },
     "/scale": {
       "post": {
         "summary": "Scale fleet to desired total",
@@ -795,15 +1245,20 @@ func openAPISpecJSON() string {
             "application/json": {
               "schema": {
                 "type": "object",
-                "properties": { "desired": { "type": "integer", "minimum": 0 } },
+                "properties": {
+                  "desired": { "type": "integer", "minimum": 0 },
+                  "overrideWindow": { "type": "string", "description": "Reason for scaling in outside maintenance windows; recorded in history" },
+                  "ttl": { "type": "string", "description": "How long desired overrides the config baseline (Go duration; default spec.scaling.overrideTTL)" }
+                },
                 "required": ["desired"]
               }
             }
           }
         },
         "responses": {
-          "202": { "description": "Accepted - scaling in background", "content": { "text/plain": { } } },
+          "202": { "description": "Accepted - scaling in background, or queued for the next maintenance window", "content": { "text/plain": { } } },
           "400": { "description": "Bad request", "content": { "text/plain": { } } },
+          "409": { "description": "Blocked by maintenance windows or the disruption budget", "content": { "text/plain": { } } },
           "500": { "description": "Error", "content": { "text/plain": { } } }
         }
       }
@@ -811,8 +1266,131 @@ func openAPISpecJSON() string {
     "/rolling-restart": {
       "post": {
         "summary": "Serial rolling restart",
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "overrideWindow": { "type": "string", "description": "Reason for restarting outside maintenance windows; recorded in history" }
+                }
+              }
+            }
+          }
+        },
         "responses": {
           "200": { "description": "OK", "content": { "text/plain": { } } },
+          "202": { "description": "Queued for the next maintenance window", "content": { "text/plain": { } } },
+          "409": { "description": "Blocked by maintenance windows or the disruption budget", "content": { "text/plain": { } } },
+          "500": { "description": "Error", "content": { "text/plain": { } } }
+        }
+      }
+    },
+    "/desired": {
+      "get": {
+        "summary": "Desired state: config baseline, override and resulting target",
+        "responses": {
+          "200": {
+            "description": "Desired state",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "baseline": { "type": "integer" },
+                    "target": { "type": "integer" },
+                    "source": { "type": "string", "enum": ["config", "override"] },
+                    "override": {
+                      "type": "object",
+                      "properties": {
+                        "desired": { "type": "integer" },
+                        "source": { "type": "string" },
+                        "setAt": { "type": "string", "format": "date-time" },
+                        "expiresAt": { "type": "string", "format": "date-time" }
+                      }
+                    },
+                    "groups": { "type": "object", "additionalProperties": { "type": "integer" }, "description": "target split per instance group" }
+                  }
+                }
+              }
+            }
+          },
+          "500": { "description": "Error", "content": { "text/plain": { } } }
+        }
+      },
+      "delete": {
+        "summary": "Drop the desired override; the config baseline applies again",
+        "responses": {
+          "200": { "description": "Desired state after clearing", "content": { "application/json": { } } },
+          "500": { "description": "Error", "content": { "text/plain": { } } }
+        }
+      }
+    },
+    "/history": {
+      "get": {
+        "summary": "Operation history and queued operations",
+        "parameters": [
+          { "name": "limit", "in": "query", "required": false, "schema": { "type": "integer", "minimum": 1, "default": 50 } }
+        ],
+        "responses": {
+          "200": {
+            "description": "History, newest last",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "history": { "type": "array", "items": { "type": "object" } },
+                    "queued": { "type": "array", "items": { "type": "object" } }
+                  }
+                }
+              }
+            }
+          },
+          "500": { "description": "Error", "content": { "text/plain": { } } }
+        }
+      }
+    },
+    "/instances": {
+      "get": {
+        "summary": "Active instances from state with addresses, placement, image and config revision",
+        "responses": {
+          "200": {
+            "description": "Instances, oldest first",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "configRevision": { "type": "string" },
+                    "instances": {
+                      "type": "array",
+                      "items": {
+                        "type": "object",
+                        "properties": {
+                          "id": { "type": "string" },
+                          "group": { "type": "string" },
+                          "name": { "type": "string" },
+                          "status": { "type": "string" },
+                          "capacity": { "type": "string" },
+                          "createdAt": { "type": "string", "format": "date-time" },
+                          "updatedAt": { "type": "string", "format": "date-time" },
+                          "availabilityDomain": { "type": "string" },
+                          "faultDomain": { "type": "string" },
+                          "shape": { "type": "string" },
+                          "privateIp": { "type": "string" },
+                          "publicIp": { "type": "string" },
+                          "imageId": { "type": "string" },
+                          "configRevision": { "type": "string" }
+                        }
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          },
           "500": { "description": "Error", "content": { "text/plain": { } } }
         }
       }
@@ -847,8 +1425,23 @@ func openAPISpecJSON() string {
 }
 
 // uiPageHTML returns a minimal interactive UI for status and control.
-func uiPageHTML() string {
-	return `<!doctype html>
+// base is the URL prefix for the selected fleet's endpoints ("" for the default fleet).
+func uiPageHTML(base, current string, fleets []string) string {
+	var opts strings.Builder
+	for _, name := range fleets {
+		sel := ""
+		if name == current {
+			sel = " selected"
+		}
+		fmt.Fprintf(&opts, "<option value=\"%s\"%s>%s</option>", html.EscapeString(name), sel, html.EscapeString(name))
+	}
+	return strings.NewReplacer(
+		"{{BASE}}", html.EscapeString(base),
+		"{{FLEET_OPTIONS}}", opts.String(),
+	).Replace(uiPageTemplate)
+}
+
+const uiPageTemplate = `<!doctype html>
 <html lang="en">
 <head>
 <meta charset="utf-8">
@@ -885,11 +1478,18 @@ pre { background: #f7f7f7; padding: 12px; overflow: auto; border-radius: 6px; }
 .minimums { margin-top:8px; padding:8px; background: var(--chip); border:1px solid var(--border); border-radius:6px; }
 .minimums .groups { font-size:0.8rem; color: var(--muted); margin-top:4px; }
 .badge.scalequeue-badge { background:#f3f4f6; color:#374151; border-color:#e5e7eb; margin-left:8px; }
+.fleet-select { margin-bottom: 16px; }
+select { padding: 6px; }
 </style>
 </head>
-<body hx-ext="sse" sse-connect="/events">
+<body hx-ext="sse" sse-connect="{{BASE}}/events">
 <h1>fleetctl UI</h1>
 
+<div class="controls fleet-select">
+  <label for="fleet">Fleet:</label>
+  <select id="fleet" onchange="location.href='/fleets/' + encodeURIComponent(this.value) + '/'">{{FLEET_OPTIONS}}</select>
+</div>
+
 <section>
   <h2>Fleet Status</h2>
   <div id="status-panel" sse-swap="status"></div>
@@ -921,7 +1521,7 @@ pre { background: #f7f7f7; padding: 12px; overflow: auto; border-radius: 6px; }
 <script>
 async function scale() {
   const d = parseInt(document.getElementById('desired').value, 10) || 0;
-  const res = await fetch('/scale', {
+  const res = await fetch('{{BASE}}/scale', {
     method: 'POST',
     headers: {'Content-Type': 'application/json'},
     body: JSON.stringify({ desired: d })
@@ -929,17 +1529,16 @@ async function scale() {
   alert(await res.text());
 }
 async function rollingRestart() {
-  const res = await fetch('/rolling-restart', { method: 'POST' });
+  const res = await fetch('{{BASE}}/rolling-restart', { method: 'POST' });
   alert(await res.text());
 }
 async function syncState() {
-  const res = await fetch('/sync-state', { method: 'POST' });
+  const res = await fetch('{{BASE}}/sync-state', { method: 'POST' });
   alert(await res.text());
 }
 </script>
 </body>
 </html>`
-}
 
 // findProjectRoot walks up from the current working directory to find go.mod.
 func findProjectRoot() (string, error) {
@@ -0,0 +1,67 @@
+// cmd/fleetctl/prometheus.go
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"fleetctl/internal/metrics"
+)
+
+// writePrometheus serves the Prometheus text exposition of fleets followed by the
+// operation series added by ops.
+func writePrometheus(w http.ResponseWriter, fleets []*fleetRuntime, ops func(*metrics.Exposition)) {
+	var e metrics.Exposition
+	for _, rt := range fleets {
+		collectFleet(&e, rt)
+	}
+	ops(&e)
+	w.Header().Set("Content-Type", metrics.ContentType)
+	_, _ = e.WriteTo(w)
+}
+
+// collectFleet adds the series of one fleet, labelled with its name: desired, actual and
+// local instances per group, warm pool and LB backend gauges, and control loop counters.
+// Like /metrics it reads the control loop snapshot and state, never OCI.
+func collectFleet(e *metrics.Exposition, rt *fleetRuntime) {
+	st := rt.status
+	st.mu.RLock()
+	var desired map[string]int
+	if st.DesiredState != nil {
+		desired = st.DesiredState.Groups
+	}
+	actual, ticks, errs, wp := st.ActualGroups, st.LoopCount, st.Errors, st.WarmPool
+	st.mu.RUnlock()
+
+	local := map[string]int{}
+	if recs, err := rt.store.ActiveRecords(rt.name); err == nil {
+		for _, r := range recs {
+			local[r.Group]++
+		}
+	}
+	groups := map[string]bool{}
+	for _, m := range []map[string]int{desired, actual, local} {
+		for g := range m {
+			groups[g] = true
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	for _, g := range names {
+		e.Gauge("fleetctl_instances_desired", "Instances the control loop converges on, per group.", float64(desired[g]), "fleet", rt.name, "group", g)
+		e.Gauge("fleetctl_instances_actual", "Instances running in OCI at the last control loop tick, per group.", float64(actual[g]), "fleet", rt.name, "group", g)
+		e.Gauge("fleetctl_instances_local", "Active instances tracked in state, per group.", float64(local[g]), "fleet", rt.name, "group", g)
+	}
+	if wp != nil {
+		e.Gauge("fleetctl_warm_pool_size", "Configured warm pool size.", float64(wp.Size), "fleet", rt.name)
+		e.Gauge("fleetctl_warm_pool_ready", "Stopped warm pool instances ready to start.", float64(wp.Ready), "fleet", rt.name)
+	}
+	if lb, ok, err := rt.store.GetLBInfo(rt.name); err == nil && ok && lb.Enabled {
+		e.Gauge("fleetctl_lb_backends", "Load balancer backends last counted.", float64(lb.BackendsCount), "fleet", rt.name)
+	}
+	e.Counter("fleetctl_control_loop_ticks_total", "Control loop ticks.", float64(ticks), "fleet", rt.name)
+	e.Counter("fleetctl_control_loop_errors_total", "Errors recorded by the control loop.", float64(errs), "fleet", rt.name)
+}
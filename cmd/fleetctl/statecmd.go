@@ -0,0 +1,183 @@
+// cmd/fleetctl/statecmd.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"fleetctl/internal/config"
+	"fleetctl/internal/state"
+)
+
+const stateUsage = `Usage:
+  fleetctl state <subcommand> [flags] [args]
+
+Subcommands:
+  migrate [--check]            Upgrade the state document to the current schema version (a backup is written first)
+  backup [--label name]        Save the current state as a snapshot that is never pruned automatically
+  list-snapshots               List automatic snapshots and backups, oldest first
+  restore <snapshot>           Replace the state with a snapshot (the current state is snapshotted first)
+  export [--out file]          Write the current state document as JSON (default stdout)
+  import <file|->              Replace the state with an exported document (the current state is snapshotted first)
+
+Every subcommand accepts --config (default fleet.yaml) and --state to locate the store.
+`
+
+// runStateCommand implements "fleetctl state <subcommand>" and returns the exit code.
+func runStateCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, stateUsage)
+		return 1
+	}
+	sub := args[0]
+	fs := flag.NewFlagSet("state "+sub, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cfgPath := fs.String("config", "fleet.yaml", "Path to fleet configuration file")
+	statePath := fs.String("state", defaultStatePath, "Path to local state JSON (file and bolt backends)")
+	check := fs.Bool("check", false, "migrate: show what would change without writing")
+	label := fs.String("label", "", "backup: label added to the snapshot name")
+	out := fs.String("out", "", "export: write to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, stateUsage+"\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	cfg, err := config.ParseFile(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load configuration from %s: %v\n", *cfgPath, err)
+		return 1
+	}
+	st, err := openStore(cfg, resolveStatePath(*statePath, *cfgPath, cfg.Metadata.Name), nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "state: %v\n", err)
+		return 1
+	}
+
+	switch sub {
+	case "migrate":
+		return stateMigrate(st, *check, stdout, stderr)
+	case "backup":
+		loc, err := st.Backup(*label)
+		if err != nil {
+			fmt.Fprintf(stderr, "backup: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "Backup written: %s\n", loc)
+	case "list-snapshots":
+		snaps, err := st.ListSnapshots()
+		if err != nil {
+			fmt.Fprintf(stderr, "list-snapshots: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "Snapshots of %s:\n", st.Backend())
+		if len(snaps) == 0 {
+			fmt.Fprintln(stdout, "  (none)")
+		}
+		for _, sn := range snaps {
+			fmt.Fprintf(stdout, "  %-48s %s %8d bytes  %s\n", sn.Name, sn.Time.Format("2006-01-02T15:04:05Z07:00"), sn.Size, sn.Location)
+		}
+	case "restore":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(stderr, "restore: exactly one snapshot name required (see list-snapshots)")
+			return 1
+		}
+		pre, err := st.Restore(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "restore: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "Restored %s from snapshot %s\n", st.Backend(), fs.Arg(0))
+		if pre != "" {
+			fmt.Fprintf(stdout, "Previous state saved as: %s\n", pre)
+		}
+	case "export":
+		data, err := st.Export()
+		if err != nil {
+			fmt.Fprintf(stderr, "export: %v\n", err)
+			return 2
+		}
+		if *out == "" {
+			_, _ = stdout.Write(append(data, '\n'))
+			return 0
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+			fmt.Fprintf(stderr, "export: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "Exported %s to %s\n", st.Backend(), *out)
+	case "import":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(stderr, "import: exactly one file required (use - for stdin)")
+			return 1
+		}
+		var data []byte
+		if fs.Arg(0) == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(fs.Arg(0))
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "import: %v\n", err)
+			return 1
+		}
+		pre, err := st.Import(data)
+		if err != nil {
+			fmt.Fprintf(stderr, "import: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "Imported %s into %s\n", fs.Arg(0), st.Backend())
+		if pre != "" {
+			fmt.Fprintf(stdout, "Previous state saved as: %s\n", pre)
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown state subcommand %q\n\n%s", sub, stateUsage)
+		return 1
+	}
+	return 0
+}
+
+// stateMigrate prints the migration plan (--check) or applies it.
+func stateMigrate(st *state.Store, check bool, stdout, stderr io.Writer) int {
+	var m state.Migration
+	var err error
+	if check {
+		m, err = st.MigrationPlan()
+	} else {
+		m, err = st.Migrate()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "migrate: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(stdout, "State: %s\n", st.Backend())
+	if len(m.Steps) == 0 {
+		fmt.Fprintf(stdout, "Schema: v%d (current); nothing to migrate\n", m.From)
+		return 0
+	}
+	fmt.Fprintf(stdout, "Schema: v%d -> v%d\n", m.From, m.To)
+	for _, step := range m.Steps {
+		fmt.Fprintf(stdout, "  v%d -> v%d: %s\n", step.From, step.To, step.Description)
+		for _, c := range step.Changes {
+			fmt.Fprintf(stdout, "    - %s\n", c)
+		}
+		if len(step.Changes) == 0 {
+			fmt.Fprintf(stdout, "    (no data changes)\n")
+		}
+	}
+	if check {
+		fmt.Fprintln(stdout, "Check only: nothing was written. Run without --check to migrate.")
+	} else {
+		fmt.Fprintf(stdout, "Migrated. Backup of the original: %s\n", m.Backup)
+	}
+	return 0
+}
+
+// isStateCommand reports whether the command line invokes "fleetctl state ...".
+func isStateCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == "state"
+}
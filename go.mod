@@ -3,12 +3,13 @@ module fleetctl
 go 1.23.0
 
 require (
+	github.com/gofrs/flock v0.10.0
 	github.com/oracle/oci-go-sdk/v65 v65.105.0
+	go.etcd.io/bbolt v1.4.3
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/gofrs/flock v0.10.0 // indirect
 	github.com/sony/gobreaker v0.5.0 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.35.0 // indirect
@@ -3,8 +3,12 @@ package client
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,6 +33,17 @@ type Client struct {
 // FleetTagKey is the freeform tag key used to mark instances for a given fleet.
 const FleetTagKey = "fleetctl-fleet"
 
+// WarmPoolTagKey marks stopped, pre-provisioned instances held in a fleet's warm pool.
+// Tagged instances are excluded from ListInstancesByFleet until started and untagged.
+const WarmPoolTagKey = "fleetctl-warm-pool"
+
+// ConfigRevisionTagKey records the config.Spec.Revision an instance was launched from.
+const ConfigRevisionTagKey = "fleetctl-config-revision"
+
+// LaunchTokenTagKey carries the opc-retry-token an instance was launched with, so a
+// launch intent left behind by a crash can be matched to the instance it created.
+const LaunchTokenTagKey = "fleetctl-launch-token"
+
 // AuthInfo captures details discovered during auth validation.
 type AuthInfo struct {
 	Region            string
@@ -38,11 +53,120 @@ type AuthInfo struct {
 	SubscribedRegions []string
 }
 
+// Capacity types reported in InstanceInfo.Capacity.
+const (
+	CapacityOnDemand    = "on-demand"
+	CapacityPreemptible = "preemptible"
+)
+
 // InstanceInfo represents minimal details for an OCI instance we manage.
 type InstanceInfo struct {
 	ID          string
 	DisplayName string
 	Lifecycle   string
+	Capacity    string // CapacityOnDemand or CapacityPreemptible
+
+	// Placement the instance landed on
+	AvailabilityDomain string
+	FaultDomain        string
+	Shape              string
+
+	ImageID        string
+	TimeCreated    time.Time // OCI timeCreated; zero when unknown
+	ConfigRevision string    // from the ConfigRevisionTagKey tag
+	LaunchToken    string    // from the LaunchTokenTagKey tag
+
+	// Addresses of the primary VNIC; filled after launch and start, not by listings
+	PrivateIP string
+	PublicIP  string
+}
+
+// Addresses are the IPs of an instance's primary VNIC.
+type Addresses struct {
+	PrivateIP string
+	PublicIP  string // empty without a public IP
+}
+
+// LaunchOptions tunes how LaunchInstances provisions instances.
+type LaunchOptions struct {
+	Preemptible        bool // launch as preemptible capacity (reclaimable by OCI)
+	PreserveBootVolume bool // keep the boot volume when a preemptible instance is reclaimed
+	Warm               bool // tag for the warm pool and STOP once provisioned
+
+	// Placements are the ordered launch candidates; empty uses PlacementCandidates(cfg).
+	Placements []Placement
+
+	// Journal, when set, records each launch attempt before it is requested.
+	Journal LaunchJournal
+}
+
+// LaunchIntent describes one LaunchInstance request about to be made.
+type LaunchIntent struct {
+	Token     string // opc-retry-token of the request, also set as the LaunchTokenTagKey tag
+	Group     string
+	Name      string
+	Capacity  string
+	Placement Placement
+}
+
+// LaunchJournal is a write-ahead log of launches. Begin runs before each LaunchInstance
+// request and must persist the intent (an error aborts the launch); Abandon runs when the
+// request failed without creating an instance. Intents whose instance exists are settled
+// by the caller once LaunchInstances returns it (InstanceInfo.LaunchToken).
+type LaunchJournal interface {
+	Begin(in LaunchIntent) error
+	Abandon(token string) error
+}
+
+// newRetryToken returns a random opc-retry-token (OCI accepts up to 64 characters).
+func newRetryToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("fleetctl-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// isRetryableLaunchError reports whether a LaunchInstance request can be sent again with
+// the same retry token: throttling, OCI 5xx responses and network errors. Capacity errors
+// are 500s too but are not retried; the next placement candidate is tried instead.
+func isRetryableLaunchError(err error) bool {
+	if IsCapacityError(err) {
+		return false
+	}
+	if isThrottleError(err) {
+		return true
+	}
+	if se, ok := common.IsServiceError(err); ok {
+		return se.GetHTTPStatusCode() >= 500
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// isLaunchRejected reports whether OCI definitely created no instance for a failed
+// LaunchInstance: a capacity error or another 4xx answer. After network errors, timeouts
+// and other 5xx answers the request may still have been accepted.
+func isLaunchRejected(err error) bool {
+	if IsCapacityError(err) {
+		return true
+	}
+	se, ok := common.IsServiceError(err)
+	return ok && se.GetHTTPStatusCode() >= 400 && se.GetHTTPStatusCode() < 500
+}
+
+// IsCapacityError reports whether err means OCI had no capacity for the requested
+// shape/AD (including preemptible capacity), as opposed to a configuration error.
+func IsCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	le := strings.ToLower(err.Error())
+	return strings.Contains(le, "out of host capacity") ||
+		strings.Contains(le, "outofhostcapacity") ||
+		strings.Contains(le, "out of capacity") ||
+		strings.Contains(le, "insufficient capacity") ||
+		strings.Contains(le, "capacity not available")
 }
 
 // Backoff/retry helpers for transient throttling (HTTP 429) on compute APIs.
@@ -265,31 +389,30 @@ func (c *Client) waitInstanceState(ctx context.Context, id string, target core.I
 	}
 }
 
-// LaunchInstances creates n instances in OCI using details from cfg and returns their basic info.
-func (c *Client) LaunchInstances(ctx context.Context, cfg config.FleetConfig, group string, n int) ([]InstanceInfo, error) {
-	if n <= 0 {
-		return nil, nil
-	}
-	if c == nil || c.Provider == nil {
-		return nil, fmt.Errorf("client not initialized")
-	}
-	cc, err := core.NewComputeClientWithConfigurationProvider(c.Provider)
-	if err != nil {
-		return nil, fmt.Errorf("compute client init: %w", err)
-	}
-	if c.Region != "" {
-		cc.SetRegion(c.Region)
-	}
+// Placement is one candidate location and shape for a launch.
+type Placement struct {
+	AvailabilityDomain string              // full AD name
+	FaultDomain        string              // e.g. FAULT-DOMAIN-1; empty lets OCI choose
+	Shape              string              // compute shape
+	ShapeConfig        *config.ShapeConfig // required for Flex shapes
+}
 
-	var out []InstanceInfo
-	prefix := cfg.Spec.DisplayNamePrefix
-	if strings.TrimSpace(prefix) == "" {
-		prefix = fmt.Sprintf("%s-%s", cfg.Metadata.Name, group)
+func (p Placement) String() string {
+	fd := p.FaultDomain
+	if fd == "" {
+		fd = "any"
 	}
+	return fmt.Sprintf("ad=%s fd=%s shape=%s", p.AvailabilityDomain, fd, p.Shape)
+}
+
+// DefaultFaultDomains are the fault domains of every OCI availability domain; spread
+// placement uses them when spec.faultDomains is empty.
+var DefaultFaultDomains = []string{"FAULT-DOMAIN-1", "FAULT-DOMAIN-2", "FAULT-DOMAIN-3"}
 
-	// Resolve availability domain to a full AD name in the current region.
-	// Accepts either a full name (e.g., "kIdk:US-ASHBURN-AD-1") or a suffix (e.g., "US-ASHBURN-AD-1" or "PHX-AD-1").
-	reqAD := strings.TrimSpace(cfg.Spec.AvailabilityDomain)
+// resolveADs maps the configured AD candidates (full names or suffixes such as
+// "PHX-AD-1") to full AD names in the current region, in order. When none are
+// configured, the first AD of the region is used (every AD with spread placement).
+func (c *Client) resolveADs(ctx context.Context, cfg config.FleetConfig) ([]string, error) {
 	idc, err := identity.NewIdentityClientWithConfigurationProvider(c.Provider)
 	if err != nil {
 		return nil, fmt.Errorf("identity client init: %w", err)
@@ -310,19 +433,128 @@ func (c *Client) LaunchInstances(ctx context.Context, cfg config.FleetConfig, gr
 	if len(ads.Items) == 0 || ads.Items[0].Name == nil {
 		return nil, fmt.Errorf("no availability domains found in region %s", c.Region)
 	}
-	// Default to the first AD, but override if we can match the requested value.
-	ad := *ads.Items[0].Name
-	if reqAD != "" {
+
+	wanted := cfg.Spec.AvailabilityDomainCandidates()
+	if len(wanted) == 0 {
+		if !cfg.Spec.Spread() {
+			return []string{*ads.Items[0].Name}, nil
+		}
+		var all []string
+		for _, item := range ads.Items {
+			if item.Name != nil {
+				all = append(all, *item.Name)
+			}
+		}
+		return all, nil
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, req := range wanted {
+		match := ""
 		for _, item := range ads.Items {
 			if item.Name == nil {
 				continue
 			}
 			full := *item.Name
-			if strings.EqualFold(full, reqAD) || strings.HasSuffix(full, reqAD) {
-				ad = full
+			if strings.EqualFold(full, req) || strings.HasSuffix(full, req) {
+				match = full
 				break
 			}
 		}
+		if match == "" {
+			log.Printf("Launch: availability domain %q not found in region %s; skipping", req, c.Region)
+			continue
+		}
+		if !seen[match] {
+			seen[match] = true
+			out = append(out, match)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("none of the configured availability domains %v exist in region %s", wanted, c.Region)
+	}
+	return out, nil
+}
+
+// PlacementCandidates returns the ordered launch candidates for cfg: the primary shape
+// in every AD and fault domain first, then each fallback shape in the same order.
+func (c *Client) PlacementCandidates(ctx context.Context, cfg config.FleetConfig) ([]Placement, error) {
+	if c == nil || c.Provider == nil {
+		return nil, fmt.Errorf("client not initialized")
+	}
+	ads, err := c.resolveADs(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	fds := cfg.Spec.FaultDomains
+	if len(fds) == 0 {
+		fds = []string{""}
+		if cfg.Spec.Spread() {
+			fds = DefaultFaultDomains
+		}
+	}
+	shapes := append([]config.ShapeOption{{Shape: cfg.Spec.Shape, ShapeConfig: cfg.Spec.ShapeConfig}}, cfg.Spec.FallbackShapes...)
+	var out []Placement
+	for _, sh := range shapes {
+		for _, ad := range ads {
+			for _, fd := range fds {
+				out = append(out, Placement{
+					AvailabilityDomain: ad,
+					FaultDomain:        strings.TrimSpace(fd),
+					Shape:              sh.Shape,
+					ShapeConfig:        sh.ShapeConfig,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+// launchShapeConfig returns the shape config for Flex shapes (nil for fixed shapes).
+func launchShapeConfig(shape string, sc *config.ShapeConfig) (*core.LaunchInstanceShapeConfigDetails, error) {
+	if !strings.Contains(strings.ToLower(shape), "flex") {
+		return nil, nil
+	}
+	if sc == nil {
+		return nil, fmt.Errorf("shape %q requires shapeConfig (ocpus, memoryInGBs)", shape)
+	}
+	oc := sc.OCPUs
+	mem := sc.MemoryInGBs
+	return &core.LaunchInstanceShapeConfigDetails{
+		Ocpus:       &oc,
+		MemoryInGBs: &mem,
+	}, nil
+}
+
+// LaunchInstances creates n instances in OCI using details from cfg and returns their basic info.
+// Each instance tries the placement candidates in order (opts.Placements, or PlacementCandidates
+// when empty) and moves on to the next candidate only when OCI reports a capacity error.
+func (c *Client) LaunchInstances(ctx context.Context, cfg config.FleetConfig, group string, n int, opts LaunchOptions) ([]InstanceInfo, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if c == nil || c.Provider == nil {
+		return nil, fmt.Errorf("client not initialized")
+	}
+	cc, err := core.NewComputeClientWithConfigurationProvider(c.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("compute client init: %w", err)
+	}
+	if c.Region != "" {
+		cc.SetRegion(c.Region)
+	}
+
+	var out []InstanceInfo
+	prefix := cfg.Spec.DisplayNamePrefix
+	if strings.TrimSpace(prefix) == "" {
+		prefix = fmt.Sprintf("%s-%s", cfg.Metadata.Name, group)
+	}
+
+	candidates := opts.Placements
+	if len(candidates) == 0 {
+		if candidates, err = c.PlacementCandidates(ctx, cfg); err != nil {
+			return nil, err
+		}
 	}
 
 	// Resolve subnet ID with optional per-group override
@@ -352,23 +584,29 @@ func (c *Client) LaunchInstances(ctx context.Context, cfg config.FleetConfig, gr
 	if subnetResp.Subnet.CompartmentId != nil && *subnetResp.Subnet.CompartmentId != cfg.Spec.CompartmentID {
 		return nil, fmt.Errorf("subnet %s is in compartment %s but spec.compartmentId is %s", subnetID, *subnetResp.Subnet.CompartmentId, cfg.Spec.CompartmentID)
 	}
+	// An AD-specific subnet can only host instances in its own AD.
+	if subAD := subnetResp.Subnet.AvailabilityDomain; subAD != nil && *subAD != "" {
+		var kept []Placement
+		for _, p := range candidates {
+			if p.AvailabilityDomain == *subAD {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			return nil, fmt.Errorf("subnet %s is AD-specific (%s) and matches no placement candidate", subnetID, *subAD)
+		}
+		candidates = kept
+	}
 
 	// Preflight: verify image exists in this region
 	if _, err := cc.GetImage(ctx, core.GetImageRequest{ImageId: &cfg.Spec.ImageID}); err != nil {
 		return nil, fmt.Errorf("verify image %s: %w", cfg.Spec.ImageID, err)
 	}
 
-	// ShapeConfig for Flexible shapes
-	var shapeCfg *core.LaunchInstanceShapeConfigDetails
-	if strings.Contains(strings.ToLower(cfg.Spec.Shape), "flex") {
-		if cfg.Spec.ShapeConfig == nil {
-			return nil, fmt.Errorf("shape %q requires shapeConfig (ocpus, memoryInGBs)", cfg.Spec.Shape)
-		}
-		oc := cfg.Spec.ShapeConfig.OCPUs
-		mem := cfg.Spec.ShapeConfig.MemoryInGBs
-		shapeCfg = &core.LaunchInstanceShapeConfigDetails{
-			Ocpus:       &oc,
-			MemoryInGBs: &mem,
+	// Preflight: every candidate shape must be launchable (shapeConfig for Flexible shapes)
+	for _, p := range candidates {
+		if _, err := launchShapeConfig(p.Shape, p.ShapeConfig); err != nil {
+			return nil, err
 		}
 	}
 
@@ -380,53 +618,182 @@ func (c *Client) LaunchInstances(ctx context.Context, cfg config.FleetConfig, gr
 			ftags[k] = v
 		}
 		ftags[FleetTagKey] = cfg.Metadata.Name
-
-		details := core.LaunchInstanceDetails{
-			CompartmentId:      &cfg.Spec.CompartmentID,
-			AvailabilityDomain: &ad,
-			Shape:              &cfg.Spec.Shape,
-			ShapeConfig:        shapeCfg,
-			SourceDetails: &core.InstanceSourceViaImageDetails{
-				ImageId: &cfg.Spec.ImageID,
-			},
-			CreateVnicDetails: &core.CreateVnicDetails{
-				SubnetId: &subnetID,
-			},
-			DisplayName:  &name,
-			FreeformTags: ftags,
-			// NOTE: DefinedTags in OCI SDK require map[string]map[string]interface{}; skipped initially.
-		}
-		log.Printf("Launch: requesting %s (group=%s, shape=%s, ad=%s, subnet=%s)", name, group, cfg.Spec.Shape, ad, subnetID)
-		req := core.LaunchInstanceRequest{LaunchInstanceDetails: details}
-		resp, err := cc.LaunchInstance(ctx, req)
-		if err != nil {
-			return nil, fmt.Errorf("launch instance %d/%d: %w", i+1, n, err)
+		ftags[ConfigRevisionTagKey] = cfg.Spec.Revision()
+		if opts.Warm {
+			ftags[WarmPoolTagKey] = cfg.Metadata.Name
 		}
-		ii := InstanceInfo{DisplayName: name}
-		if resp.Instance.Id != nil {
-			ii.ID = *resp.Instance.Id
-		}
-		log.Printf("Launch: requested %s id=%s", name, ii.ID)
-		// Wait for completion: prefer Work Request if present; otherwise poll until RUNNING
-		if resp.OpcWorkRequestId != nil {
-			if err := c.waitWorkRequest(ctx, *resp.OpcWorkRequestId, fmt.Sprintf("launch %s", ii.ID)); err != nil {
-				return nil, fmt.Errorf("wait for launch %s: %w", ii.ID, err)
+
+		var (
+			ii      InstanceInfo
+			lastErr error
+		)
+		for ci, p := range candidates {
+			token := newRetryToken()
+			ftags[LaunchTokenTagKey] = token
+			if opts.Journal != nil {
+				capacity := CapacityOnDemand
+				if opts.Preemptible {
+					capacity = CapacityPreemptible
+				}
+				if err := opts.Journal.Begin(LaunchIntent{Token: token, Group: group, Name: name, Capacity: capacity, Placement: p}); err != nil {
+					return nil, fmt.Errorf("record launch intent for %s: %w", name, err)
+				}
+			}
+			ii, lastErr = c.launchAt(ctx, cc, cfg, group, name, subnetID, token, ftags, p, opts)
+			if lastErr == nil {
+				break
+			}
+			if opts.Journal != nil && ii.ID == "" && isLaunchRejected(lastErr) {
+				// OCI rejected the request. Otherwise an instance may exist (or be
+				// created yet), and the intent stays for recovery by the token tag.
+				if err := opts.Journal.Abandon(token); err != nil {
+					log.Printf("Launch: drop launch intent %s: %v", token, err)
+				}
+			}
+			if !IsCapacityError(lastErr) {
+				return nil, fmt.Errorf("launch instance %d/%d: %w", i+1, n, lastErr)
 			}
-		} else if ii.ID != "" {
-			if err := c.waitInstanceState(ctx, ii.ID, core.InstanceLifecycleStateRunning); err != nil {
-				return nil, fmt.Errorf("wait running %s: %w", ii.ID, err)
+			if ci < len(candidates)-1 {
+				log.Printf("Launch: no capacity for %s at %s; trying next candidate (%s)", name, p, candidates[ci+1])
 			}
 		}
-		if resp.Instance.LifecycleState != "" {
-			ii.Lifecycle = string(resp.Instance.LifecycleState)
+		if lastErr != nil {
+			return nil, fmt.Errorf("launch instance %d/%d: no capacity in any of %d placement candidate(s): %w", i+1, n, len(candidates), lastErr)
+		}
+		if opts.Warm && ii.ID != "" {
+			if err := c.instanceAction(ctx, cc, ii.ID, core.InstanceActionActionStop, core.InstanceLifecycleStateStopped); err != nil {
+				return nil, fmt.Errorf("stop warm instance %s: %w", ii.ID, err)
+			}
+			ii.Lifecycle = string(core.InstanceLifecycleStateStopped)
 		}
 		out = append(out, ii)
 	}
 	return out, nil
 }
 
-// TerminateInstances terminates the specified OCI instances.
+// launchAt launches one instance at placement p and waits for it to provision. The request
+// carries retryToken, so resending it after a transient failure cannot create a second
+// instance. When waiting fails after OCI accepted the request, the returned info still
+// carries the instance ID.
+func (c *Client) launchAt(ctx context.Context, cc core.ComputeClient, cfg config.FleetConfig, group, name, subnetID, retryToken string, ftags map[string]string, p Placement, opts LaunchOptions) (InstanceInfo, error) {
+	shapeCfg, err := launchShapeConfig(p.Shape, p.ShapeConfig)
+	if err != nil {
+		return InstanceInfo{}, err
+	}
+	ad, shape := p.AvailabilityDomain, p.Shape
+	details := core.LaunchInstanceDetails{
+		CompartmentId:      &cfg.Spec.CompartmentID,
+		AvailabilityDomain: &ad,
+		Shape:              &shape,
+		ShapeConfig:        shapeCfg,
+		SourceDetails: &core.InstanceSourceViaImageDetails{
+			ImageId: &cfg.Spec.ImageID,
+		},
+		CreateVnicDetails: &core.CreateVnicDetails{
+			SubnetId: &subnetID,
+		},
+		DisplayName:  &name,
+		FreeformTags: ftags,
+		// NOTE: DefinedTags in OCI SDK require map[string]map[string]interface{}; skipped initially.
+	}
+	if p.FaultDomain != "" {
+		fd := p.FaultDomain
+		details.FaultDomain = &fd
+	}
+	capacity := CapacityOnDemand
+	if opts.Preemptible {
+		capacity = CapacityPreemptible
+		preserve := opts.PreserveBootVolume
+		details.PreemptibleInstanceConfig = &core.PreemptibleInstanceConfigDetails{
+			PreemptionAction: core.TerminatePreemptionAction{PreserveBootVolume: &preserve},
+		}
+	}
+	log.Printf("Launch: requesting %s (group=%s, %s, subnet=%s, capacity=%s)", name, group, p, subnetID, capacity)
+	req := core.LaunchInstanceRequest{LaunchInstanceDetails: details, OpcRetryToken: &retryToken}
+	var resp core.LaunchInstanceResponse
+	for attempt := 1; ; attempt++ {
+		resp, err = cc.LaunchInstance(ctx, req)
+		if err == nil || attempt == 5 || !isRetryableLaunchError(err) {
+			break
+		}
+		log.Printf("Launch: %s attempt %d: %v; retrying with the same retry token", name, attempt, err)
+		select {
+		case <-ctx.Done():
+			return InstanceInfo{}, ctx.Err()
+		case <-time.After(backoffDelay(attempt)):
+		}
+	}
+	if err != nil {
+		return InstanceInfo{}, err
+	}
+	ii := InstanceInfo{
+		DisplayName:        name,
+		Capacity:           capacity,
+		AvailabilityDomain: p.AvailabilityDomain,
+		FaultDomain:        p.FaultDomain,
+		Shape:              p.Shape,
+		ImageID:            cfg.Spec.ImageID,
+		ConfigRevision:     ftags[ConfigRevisionTagKey],
+		LaunchToken:        retryToken,
+	}
+	if resp.Instance.Id != nil {
+		ii.ID = *resp.Instance.Id
+	}
+	if resp.Instance.FaultDomain != nil {
+		ii.FaultDomain = *resp.Instance.FaultDomain
+	}
+	if resp.Instance.TimeCreated != nil {
+		ii.TimeCreated = resp.Instance.TimeCreated.Time
+	}
+	log.Printf("Launch: requested %s id=%s", name, ii.ID)
+	// Wait for completion: prefer Work Request if present; otherwise poll until RUNNING
+	if resp.OpcWorkRequestId != nil {
+		if err := c.waitWorkRequest(ctx, *resp.OpcWorkRequestId, fmt.Sprintf("launch %s", ii.ID)); err != nil {
+			return InstanceInfo{ID: ii.ID, LaunchToken: retryToken}, fmt.Errorf("wait for launch %s: %w", ii.ID, err)
+		}
+	} else if ii.ID != "" {
+		if err := c.waitInstanceState(ctx, ii.ID, core.InstanceLifecycleStateRunning); err != nil {
+			return InstanceInfo{ID: ii.ID, LaunchToken: retryToken}, fmt.Errorf("wait running %s: %w", ii.ID, err)
+		}
+	}
+	if resp.Instance.LifecycleState != "" {
+		ii.Lifecycle = string(resp.Instance.LifecycleState)
+	}
+	if ii.ID != "" {
+		if addr, err := c.InstanceAddresses(ctx, cfg.Spec.CompartmentID, ii.ID); err == nil {
+			ii.PrivateIP, ii.PublicIP = addr.PrivateIP, addr.PublicIP
+		} else {
+			log.Printf("Launch: resolve addresses of %s: %v (filled on next sync)", ii.ID, err)
+		}
+	}
+	return ii, nil
+}
+
+// fillDetails copies the instance fields fleetctl records from an OCI instance.
+func (ii *InstanceInfo) fillDetails(in core.Instance) {
+	if in.ImageId != nil {
+		ii.ImageID = *in.ImageId
+	}
+	if in.TimeCreated != nil {
+		ii.TimeCreated = in.TimeCreated.Time
+	}
+	ii.ConfigRevision = in.FreeformTags[ConfigRevisionTagKey]
+	ii.LaunchToken = in.FreeformTags[LaunchTokenTagKey]
+}
+
+// ListInstancesByFleet returns the non-terminated instances tagged to fleetName,
+// excluding instances held in the warm pool.
 func (c *Client) ListInstancesByFleet(ctx context.Context, compartmentId, fleetName string) ([]InstanceInfo, error) {
+	return c.listFleetInstances(ctx, compartmentId, fleetName, false)
+}
+
+// ListWarmPool returns the non-terminated warm pool instances of fleetName.
+func (c *Client) ListWarmPool(ctx context.Context, compartmentId, fleetName string) ([]InstanceInfo, error) {
+	return c.listFleetInstances(ctx, compartmentId, fleetName, true)
+}
+
+// listFleetInstances lists instances tagged to fleetName that are (warm) or are not (!warm) in the warm pool.
+func (c *Client) listFleetInstances(ctx context.Context, compartmentId, fleetName string, warm bool) ([]InstanceInfo, error) {
 	if c == nil || c.Provider == nil {
 		return nil, fmt.Errorf("client not initialized")
 	}
@@ -470,6 +837,9 @@ func (c *Client) ListInstancesByFleet(ctx context.Context, compartmentId, fleetN
 			// Match our fleet tag
 			if it.FreeformTags != nil {
 				if val, ok := it.FreeformTags[FleetTagKey]; ok && val == fleetName {
+					if _, inPool := it.FreeformTags[WarmPoolTagKey]; inPool != warm {
+						continue
+					}
 					info := InstanceInfo{}
 					if it.Id != nil {
 						info.ID = *it.Id
@@ -480,6 +850,20 @@ func (c *Client) ListInstancesByFleet(ctx context.Context, compartmentId, fleetN
 					if it.LifecycleState != "" {
 						info.Lifecycle = string(it.LifecycleState)
 					}
+					info.Capacity = CapacityOnDemand
+					if it.PreemptibleInstanceConfig != nil {
+						info.Capacity = CapacityPreemptible
+					}
+					if it.AvailabilityDomain != nil {
+						info.AvailabilityDomain = *it.AvailabilityDomain
+					}
+					if it.FaultDomain != nil {
+						info.FaultDomain = *it.FaultDomain
+					}
+					if it.Shape != nil {
+						info.Shape = *it.Shape
+					}
+					info.fillDetails(it)
 					out = append(out, info)
 				}
 			}
@@ -492,6 +876,89 @@ func (c *Client) ListInstancesByFleet(ctx context.Context, compartmentId, fleetN
 	return out, nil
 }
 
+// instanceAction issues a power action (START, STOP, ...) and waits for the target lifecycle state.
+func (c *Client) instanceAction(ctx context.Context, cc core.ComputeClient, id string, action core.InstanceActionActionEnum, target core.InstanceLifecycleStateEnum) error {
+	var err error
+	for attempt := 1; attempt <= 5; attempt++ {
+		_, err = cc.InstanceAction(ctx, core.InstanceActionRequest{InstanceId: &id, Action: action})
+		if err == nil || !isThrottleError(err) {
+			break
+		}
+		time.Sleep(backoffDelay(attempt))
+	}
+	if err != nil {
+		return fmt.Errorf("instance action %s on %s: %w", action, id, err)
+	}
+	log.Printf("InstanceAction: %s requested for %s", action, id)
+	return c.waitInstanceState(ctx, id, target)
+}
+
+// StartWarmInstance starts a stopped warm pool instance, waits until it is RUNNING and
+// removes the warm pool tag so it is counted as a regular fleet member. If the tag cannot
+// be removed the instance is stopped again and stays in the pool.
+func (c *Client) StartWarmInstance(ctx context.Context, id string) (InstanceInfo, error) {
+	if c == nil || c.Provider == nil {
+		return InstanceInfo{}, fmt.Errorf("client not initialized")
+	}
+	cc, err := core.NewComputeClientWithConfigurationProvider(c.Provider)
+	if err != nil {
+		return InstanceInfo{}, fmt.Errorf("compute client init: %w", err)
+	}
+	if c.Region != "" {
+		cc.SetRegion(c.Region)
+	}
+	if err := c.instanceAction(ctx, cc, id, core.InstanceActionActionStart, core.InstanceLifecycleStateRunning); err != nil {
+		return InstanceInfo{}, err
+	}
+	// A running instance that still carries the warm pool tag would count as pending in
+	// the pool forever, so stop it again when the tag cannot be removed.
+	rollback := func(err error) error {
+		if stopErr := c.instanceAction(ctx, cc, id, core.InstanceActionActionStop, core.InstanceLifecycleStateStopped); stopErr != nil {
+			return errors.Join(err, fmt.Errorf("stop %s again: %w", id, stopErr))
+		}
+		return err
+	}
+	resp, err := cc.GetInstance(ctx, core.GetInstanceRequest{InstanceId: &id})
+	if err != nil {
+		return InstanceInfo{}, rollback(fmt.Errorf("get instance %s: %w", id, err))
+	}
+	tags := map[string]string{}
+	for k, v := range resp.Instance.FreeformTags {
+		if k != WarmPoolTagKey {
+			tags[k] = v
+		}
+	}
+	if _, err := cc.UpdateInstance(ctx, core.UpdateInstanceRequest{
+		InstanceId:            &id,
+		UpdateInstanceDetails: core.UpdateInstanceDetails{FreeformTags: tags},
+	}); err != nil {
+		return InstanceInfo{}, rollback(fmt.Errorf("remove warm pool tag from %s: %w", id, err))
+	}
+	info := InstanceInfo{ID: id, Lifecycle: string(core.InstanceLifecycleStateRunning), Capacity: CapacityOnDemand}
+	if resp.Instance.DisplayName != nil {
+		info.DisplayName = *resp.Instance.DisplayName
+	}
+	if resp.Instance.PreemptibleInstanceConfig != nil {
+		info.Capacity = CapacityPreemptible
+	}
+	if resp.Instance.AvailabilityDomain != nil {
+		info.AvailabilityDomain = *resp.Instance.AvailabilityDomain
+	}
+	if resp.Instance.FaultDomain != nil {
+		info.FaultDomain = *resp.Instance.FaultDomain
+	}
+	if resp.Instance.Shape != nil {
+		info.Shape = *resp.Instance.Shape
+	}
+	info.fillDetails(resp.Instance)
+	if resp.Instance.CompartmentId != nil {
+		if addr, err := c.InstanceAddresses(ctx, *resp.Instance.CompartmentId, id); err == nil {
+			info.PrivateIP, info.PublicIP = addr.PrivateIP, addr.PublicIP
+		}
+	}
+	return info, nil
+}
+
 // TerminateInstances terminates the specified OCI instances.
 func (c *Client) TerminateInstances(ctx context.Context, ids []string) error {
 	if len(ids) == 0 {
@@ -526,14 +993,21 @@ func (c *Client) TerminateInstances(ctx context.Context, ids []string) error {
 	return nil
 }
 
+// InstancePrimaryPrivateIP returns the private IP of the instance's primary VNIC.
 func (c *Client) InstancePrimaryPrivateIP(ctx context.Context, compartmentId, instanceId string) (string, error) {
+	addr, err := c.InstanceAddresses(ctx, compartmentId, instanceId)
+	return addr.PrivateIP, err
+}
+
+// InstanceAddresses returns the private and public IP of the instance's primary VNIC.
+func (c *Client) InstanceAddresses(ctx context.Context, compartmentId, instanceId string) (Addresses, error) {
 	if c == nil || c.Provider == nil {
-		return "", fmt.Errorf("client not initialized")
+		return Addresses{}, fmt.Errorf("client not initialized")
 	}
 	// Compute client for listing VNIC attachments
 	cc, err := core.NewComputeClientWithConfigurationProvider(c.Provider)
 	if err != nil {
-		return "", fmt.Errorf("compute client init: %w", err)
+		return Addresses{}, fmt.Errorf("compute client init: %w", err)
 	}
 	if c.Region != "" {
 		cc.SetRegion(c.Region)
@@ -549,7 +1023,7 @@ func (c *Client) InstancePrimaryPrivateIP(ctx context.Context, compartmentId, in
 			Page:          page,
 		})
 		if err != nil {
-			return "", fmt.Errorf("list vnic attachments: %w", err)
+			return Addresses{}, fmt.Errorf("list vnic attachments: %w", err)
 		}
 		for _, va := range resp.Items {
 			att := va // capture
@@ -566,13 +1040,13 @@ func (c *Client) InstancePrimaryPrivateIP(ctx context.Context, compartmentId, in
 	}
 	chosen := firstAtt
 	if chosen == nil || chosen.VnicId == nil || *chosen.VnicId == "" {
-		return "", fmt.Errorf("no VNIC attachment found for instance %s", instanceId)
+		return Addresses{}, fmt.Errorf("no VNIC attachment found for instance %s", instanceId)
 	}
 
 	// Virtual network client to query the VNIC
 	vnc, err := core.NewVirtualNetworkClientWithConfigurationProvider(c.Provider)
 	if err != nil {
-		return "", fmt.Errorf("virtual network client init: %w", err)
+		return Addresses{}, fmt.Errorf("virtual network client init: %w", err)
 	}
 	if c.Region != "" {
 		vnc.SetRegion(c.Region)
@@ -580,12 +1054,16 @@ func (c *Client) InstancePrimaryPrivateIP(ctx context.Context, compartmentId, in
 	vnicID := *chosen.VnicId
 	vnicResp, err := vnc.GetVnic(ctx, core.GetVnicRequest{VnicId: &vnicID})
 	if err != nil {
-		return "", fmt.Errorf("get vnic %s: %w", vnicID, err)
+		return Addresses{}, fmt.Errorf("get vnic %s: %w", vnicID, err)
 	}
 	if vnicResp.Vnic.PrivateIp == nil || *vnicResp.Vnic.PrivateIp == "" {
-		return "", fmt.Errorf("vnic %s has no private IP", vnicID)
+		return Addresses{}, fmt.Errorf("vnic %s has no private IP", vnicID)
+	}
+	addr := Addresses{PrivateIP: *vnicResp.Vnic.PrivateIp}
+	if vnicResp.Vnic.PublicIp != nil {
+		addr.PublicIP = *vnicResp.Vnic.PublicIp
 	}
-	return *vnicResp.Vnic.PrivateIp, nil
+	return addr, nil
 }
 
 // Validate performs a lightweight API call to verify auth works.
@@ -3,6 +3,8 @@ package client
 
 import (
 	"context"
+	"errors"
+	"net"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -53,3 +55,63 @@ func TestValidateOnNilReceiver(t *testing.T) {
 		t.Fatalf("expected error when client is nil")
 	}
 }
+
+func TestIsCapacityError(t *testing.T) {
+	cases := map[string]bool{
+		"Service error:InternalError. Out of host capacity. http status code: 500":                   true,
+		"launch instance 1/1: no capacity in any of 3 placement candidate(s): Out of host capacity.": true,
+		"Service error:NotAuthorizedOrNotFound. http status code: 404":                               false,
+	}
+	for msg, want := range cases {
+		if got := IsCapacityError(errors.New(msg)); got != want {
+			t.Fatalf("IsCapacityError(%q) = %t, want %t", msg, got, want)
+		}
+	}
+}
+
+func TestLaunchShapeConfig(t *testing.T) {
+	if sc, err := launchShapeConfig("VM.Standard2.1", nil); err != nil || sc != nil {
+		t.Fatalf("fixed shape: got %v, %v", sc, err)
+	}
+	if _, err := launchShapeConfig("VM.Standard.E4.Flex", nil); err == nil {
+		t.Fatalf("expected error for Flex shape without shapeConfig")
+	}
+	sc, err := launchShapeConfig("VM.Standard.E4.Flex", &config.ShapeConfig{OCPUs: 2, MemoryInGBs: 16})
+	if err != nil || sc == nil || *sc.Ocpus != 2 || *sc.MemoryInGBs != 16 {
+		t.Fatalf("flex shape: got %v, %v", sc, err)
+	}
+}
+
+// serviceError is a common.ServiceError with a fixed HTTP status and message.
+type serviceError struct {
+	status int
+	msg    string
+}
+
+func (e serviceError) Error() string           { return e.msg }
+func (e serviceError) GetHTTPStatusCode() int  { return e.status }
+func (e serviceError) GetMessage() string      { return e.msg }
+func (e serviceError) GetCode() string         { return "" }
+func (e serviceError) GetOpcRequestID() string { return "" }
+
+func TestLaunchErrorClasses(t *testing.T) {
+	cases := []struct {
+		name            string
+		err             error
+		retry, rejected bool
+	}{
+		{"out of capacity", serviceError{500, "InternalError. Out of host capacity."}, false, true},
+		{"internal error", serviceError{500, "InternalError"}, true, false},
+		{"throttled", serviceError{429, "Too many requests"}, true, true},
+		{"bad request", serviceError{400, "InvalidParameter"}, false, true},
+		{"network error", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true, false},
+	}
+	for _, c := range cases {
+		if got := isRetryableLaunchError(c.err); got != c.retry {
+			t.Fatalf("%s: isRetryableLaunchError = %t, want %t", c.name, got, c.retry)
+		}
+		if got := isLaunchRejected(c.err); got != c.rejected {
+			t.Fatalf("%s: isLaunchRejected = %t, want %t", c.name, got, c.rejected)
+		}
+	}
+}
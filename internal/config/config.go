@@ -2,8 +2,13 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,6 +22,7 @@ type FleetConfig struct {
 
 type Metadata struct {
 	Name string `yaml:"name"`
+	UID  string `yaml:"uid"` // pins the fleet UID tagged on its load balancer; default: generated and kept in state
 }
 
 type Spec struct {
@@ -29,17 +35,45 @@ type Spec struct {
 	DisplayNamePrefix  string           `yaml:"displayNamePrefix"`
 	Scaling            Scaling          `yaml:"scaling"` // optional scaling configuration (bounded concurrency)
 	LoadBalancer       LoadBalancerSpec `yaml:"loadBalancer"`
+	Registrars         []RegistrarSpec  `yaml:"registrars"` // announce instances to HAProxy, nginx, Consul, ... besides loadBalancer
+	DNS                *DNSSpec         `yaml:"dns"`        // optional A records for instances and groups
 	Auth               Auth             `yaml:"auth"`
+	Lock               LockSpec         `yaml:"lock"`     // optional cross-process locking of fleet operations
+	WarmPool           *WarmPoolSpec    `yaml:"warmPool"` // optional pool of pre-provisioned STOPPED instances
+	State              StateSpec        `yaml:"state"`    // where fleet state is stored (default: JSON file)
 	// ... other fields
 	DefinedTags  map[string]string `yaml:"definedTags"` // or a more complex type
 	FreeformTags map[string]string `yaml:"freeformTags"`
 	Instances    []InstanceSpec    `yaml:"instances"`
+
+	// Launch fallback candidates, tried in order when OCI reports a capacity error
+	AvailabilityDomains []string      `yaml:"availabilityDomains"` // extra ADs (full names or suffixes) after availabilityDomain
+	FaultDomains        []string      `yaml:"faultDomains"`        // e.g. FAULT-DOMAIN-1; empty lets OCI choose
+	FallbackShapes      []ShapeOption `yaml:"fallbackShapes"`      // alternative shapes after shape/shapeConfig
+	Placement           string        `yaml:"placement"`           // "" (first candidate with capacity) or "spread" (balance ADs/fault domains)
+
+	// Disruptive operations (scale-down, rolling restart, replacements)
+	MaintenanceWindows *MaintenanceSpec  `yaml:"maintenanceWindows"` // when disruptive operations may run; nil = any time
+	DisruptionBudget   *DisruptionBudget `yaml:"disruptionBudget"`   // cap on instances disrupted per hour; nil = unlimited
 }
 
 type InstanceSpec struct {
-	Name     string `yaml:"name"`
-	Count    int    `yaml:"count"`
-	SubnetID string `yaml:"subnetId"` // optional per-group override; falls back to spec.subnetId
+	Name     string          `yaml:"name"`
+	Count    int             `yaml:"count"`
+	SubnetID string          `yaml:"subnetId"` // optional per-group override; falls back to spec.subnetId
+	Capacity *CapacityPolicy `yaml:"capacity"` // optional on-demand/preemptible mix; nil means all on-demand
+}
+
+// CapacityPolicy mixes on-demand and preemptible instances within a group.
+type CapacityPolicy struct {
+	OnDemandBase       int    `yaml:"onDemandBase"`       // the first N instances are always on-demand
+	PreemptiblePercent int    `yaml:"preemptiblePercent"` // share (0-100) of instances above the base launched as preemptible
+	PreemptionAction   string `yaml:"preemptionAction"`   // "terminate" (default) or "terminate-preserve-boot-volume"
+}
+
+// PreserveBootVolume reports whether reclaimed preemptible instances keep their boot volume.
+func (p *CapacityPolicy) PreserveBootVolume() bool {
+	return p != nil && strings.EqualFold(strings.TrimSpace(p.PreemptionAction), "terminate-preserve-boot-volume")
 }
 
 // ShapeConfig config for Flexible shapes (e.g., VM.Standard.*.Flex)
@@ -48,15 +82,58 @@ type ShapeConfig struct {
 	MemoryInGBs float32 `yaml:"memoryInGBs"`
 }
 
+// ShapeOption is an alternative shape (with its Flex shapeConfig) for launch fallback.
+type ShapeOption struct {
+	Shape       string       `yaml:"shape"`
+	ShapeConfig *ShapeConfig `yaml:"shapeConfig"`
+}
+
 // Scaling controls bounded concurrency for scale operations.
 type Scaling struct {
 	ParallelLaunch    int `yaml:"parallelLaunch"`    // max concurrent launches; default applied if zero
 	ParallelTerminate int `yaml:"parallelTerminate"` // max concurrent terminations; default applied if zero
+
+	// Control loop hysteresis: desired and actual must differ for this long before the loop
+	// acts. Unset means scale up immediately and scale down after 5m.
+	ScaleUpStabilization   *time.Duration `yaml:"scaleUpStabilization"`
+	ScaleDownStabilization *time.Duration `yaml:"scaleDownStabilization"`
+	OverrideTTL            time.Duration  `yaml:"overrideTTL"` // lifetime of desired overrides set by --scale or POST /scale; default 24h
+}
+
+// Defaults for Scaling durations.
+const (
+	DefaultScaleDownStabilization = 5 * time.Minute
+	DefaultOverrideTTL            = 24 * time.Hour
+)
+
+// UpStabilization returns how long a shortfall must persist before the control loop scales up.
+func (s Scaling) UpStabilization() time.Duration {
+	if s.ScaleUpStabilization == nil {
+		return 0
+	}
+	return *s.ScaleUpStabilization
+}
+
+// DownStabilization returns how long a surplus must persist before the control loop scales down.
+func (s Scaling) DownStabilization() time.Duration {
+	if s.ScaleDownStabilization == nil {
+		return DefaultScaleDownStabilization
+	}
+	return *s.ScaleDownStabilization
+}
+
+// DesiredTTL returns the lifetime of a desired override when the caller gives none.
+func (s Scaling) DesiredTTL() time.Duration {
+	if s.OverrideTTL <= 0 {
+		return DefaultOverrideTTL
+	}
+	return s.OverrideTTL
 }
 
 // LoadBalancerSpec defines configuration for the OCI Load Balancer.
 type LoadBalancerSpec struct {
 	Enabled          bool   `yaml:"enabled"`
+	Type             string `yaml:"type"` // "application" (default: Layer-7 load balancer) or "network" (network load balancer)
 	SubnetID         string `yaml:"subnetId"`
 	IsPrivate        bool   `yaml:"isPrivate"`
 	ListenerPort     int    `yaml:"listenerPort"`
@@ -65,6 +142,251 @@ type LoadBalancerSpec struct {
 	MaxBandwidthMbps int    `yaml:"maxBandwidthMbps"`
 	HealthPath       string `yaml:"healthPath"`
 	Policy           string `yaml:"policy"`
+
+	Protocol         string            `yaml:"protocol"`         // listener protocol: HTTP (default), HTTPS or TCP; type network: TCP (default), UDP, TCP_AND_UDP or ANY
+	TLS              *LBTLSSpec        `yaml:"tls"`              // certificate for HTTPS (or TLS-terminating TCP) listeners
+	RedirectHTTPPort int               `yaml:"redirectHttpPort"` // HTTPS only: also listen on this port (e.g. 80) and redirect to HTTPS
+	BackendTLS       *LBBackendTLSSpec `yaml:"backendTls"`       // TLS from the LB to the backends
+
+	// type network only: backends see the client's IP address (default true)
+	PreserveSourceIP *bool `yaml:"preserveSourceIp"`
+
+	HealthCheck        *LBHealthCheckSpec        `yaml:"healthCheck"`        // tuning of the backend health check
+	SessionPersistence *LBSessionPersistenceSpec `yaml:"sessionPersistence"` // cookie-based stickiness; nil = none
+
+	// Several backend sets and listeners. Without backendSets every instance lands in one
+	// backend set on backendPort; without listeners the single listener above is used.
+	BackendSets []LBBackendSetSpec `yaml:"backendSets"`
+	Listeners   []LBListenerSpec   `yaml:"listeners"`
+}
+
+// LBBackendSetSpec is a backend set serving some of the fleet's instance groups.
+type LBBackendSetSpec struct {
+	Name        string   `yaml:"name"`
+	Groups      []string `yaml:"groups"`      // instance groups registered here; empty = every group no other set lists
+	BackendPort int      `yaml:"backendPort"` // default loadBalancer.backendPort
+	HealthPath  string   `yaml:"healthPath"`  // default loadBalancer.healthPath; empty = TCP health check
+	Policy      string   `yaml:"policy"`      // default loadBalancer.policy
+
+	HealthCheck        *LBHealthCheckSpec        `yaml:"healthCheck"`        // default loadBalancer.healthCheck
+	SessionPersistence *LBSessionPersistenceSpec `yaml:"sessionPersistence"` // default loadBalancer.sessionPersistence
+}
+
+// LBHealthCheckSpec tunes the backend health check. The path is healthPath; unset fields
+// keep the OCI defaults.
+type LBHealthCheckSpec struct {
+	Protocol          string        `yaml:"protocol"`          // HTTP or TCP; default HTTP with a health path, else TCP
+	Port              int           `yaml:"port"`              // default: the backend port
+	Interval          time.Duration `yaml:"interval"`          // time between checks, e.g. 10s
+	Timeout           time.Duration `yaml:"timeout"`           // time to wait for a reply, e.g. 3s
+	Retries           int           `yaml:"retries"`           // failed checks before a backend is marked unhealthy
+	ReturnCode        int           `yaml:"returnCode"`        // HTTP only: expected status, e.g. 200
+	ResponseBodyRegex string        `yaml:"responseBodyRegex"` // HTTP only: the response body must match
+}
+
+// LBSessionPersistenceSpec keeps a client on the same backend with a cookie.
+type LBSessionPersistenceSpec struct {
+	Type            string `yaml:"type"`            // "lb-cookie" (the LB sets its own cookie) or "app-cookie" (follows an application cookie)
+	CookieName      string `yaml:"cookieName"`      // app-cookie: required ("*" = any cookie); lb-cookie: default X-Oracle-BMC-LBS-Route
+	DisableFallback bool   `yaml:"disableFallback"` // fail requests instead of re-balancing when the backend is gone
+
+	// lb-cookie only: attributes of the inserted cookie
+	Domain   string        `yaml:"domain"`
+	Path     string        `yaml:"path"`     // default /
+	MaxAge   time.Duration `yaml:"maxAge"`   // default: session cookie
+	Secure   *bool         `yaml:"secure"`   // default true for HTTPS listeners (OCI decides)
+	HTTPOnly *bool         `yaml:"httpOnly"` // default true
+}
+
+// LBListenerSpec is a listener routing to the backend sets by hostname and path.
+type LBListenerSpec struct {
+	Name       string        `yaml:"name"`
+	Port       int           `yaml:"port"`
+	Protocol   string        `yaml:"protocol"`   // HTTP (default), HTTPS or TCP; type network: as loadBalancer.protocol
+	TLS        *LBTLSSpec    `yaml:"tls"`        // as loadBalancer.tls
+	BackendSet string        `yaml:"backendSet"` // default backend set; defaults to the first one
+	Hostnames  []string      `yaml:"hostnames"`  // virtual hostnames this listener answers (HTTP/HTTPS)
+	Routes     []LBRouteSpec `yaml:"routes"`     // path rules picking another backend set (HTTP/HTTPS)
+}
+
+// LBRouteSpec sends requests whose path matches to a backend set.
+type LBRouteSpec struct {
+	Path       string `yaml:"path"`
+	Match      string `yaml:"match"` // "prefix" (default, longest prefix wins), "exact" or "suffix"
+	BackendSet string `yaml:"backendSet"`
+}
+
+// LBTLSSpec names the listener certificate: an OCI Certificates service certificate, or
+// PEM files uploaded to the load balancer. Uploaded bundles are named after the leaf
+// certificate's fingerprint, so replacing the files rotates the certificate on reconcile.
+type LBTLSSpec struct {
+	CertificateID string `yaml:"certificateId"` // OCI Certificates certificate OCID
+	CertFile      string `yaml:"certFile"`      // PEM certificate (leaf first, optionally with the chain)
+	KeyFile       string `yaml:"keyFile"`       // PEM private key for certFile
+	CAFile        string `yaml:"caFile"`        // optional PEM CA chain for certFile
+}
+
+// LBBackendTLSSpec makes the LB connect to backends over TLS, optionally verifying them.
+type LBBackendTLSSpec struct {
+	CAFile       string   `yaml:"caFile"`       // PEM CA bundle uploaded to the LB to verify backend certificates
+	TrustedCAIDs []string `yaml:"trustedCaIds"` // or OCI Certificates CA bundle OCIDs
+	VerifyPeer   bool     `yaml:"verifyPeer"`   // verify backend certificates against the CA
+	VerifyDepth  int      `yaml:"verifyDepth"`  // maximum chain depth when verifying; default 1
+}
+
+// ListenerProtocol returns the upper-cased listener protocol, HTTP when unset.
+func (l LoadBalancerSpec) ListenerProtocol() string {
+	return NormalizeProtocol(l.Protocol)
+}
+
+// NormalizeProtocol upper-cases a listener protocol, HTTP when unset.
+func NormalizeProtocol(p string) string {
+	if strings.TrimSpace(p) == "" {
+		return "HTTP"
+	}
+	return strings.ToUpper(strings.TrimSpace(p))
+}
+
+// LockSpec configures the advisory lock that serializes fleet operations across processes.
+// A flock on the state file is always used; Lease adds a distributed lease for multi-host setups.
+// RegistrarSpec announces the fleet's instances to something other than the OCI load
+// balancer. Type file renders Template to Path and runs ReloadCommand when the output
+// changes; type webhook POSTs every change to URL.
+type RegistrarSpec struct {
+	Name   string   `yaml:"name"`   // used in logs; default <type>-<index>
+	Type   string   `yaml:"type"`   // "file" or "webhook"
+	Groups []string `yaml:"groups"` // instance groups announced; default every group
+	Port   int      `yaml:"port"`   // backend port handed to the template or webhook as member port
+
+	Template      string   `yaml:"template"`      // file: Go text/template file rendered with the fleet's members
+	Path          string   `yaml:"path"`          // file: where the rendered output is written
+	ReloadCommand []string `yaml:"reloadCommand"` // file: argv run after the output changed, e.g. [systemctl, reload, haproxy]
+
+	URL      string            `yaml:"url"`      // webhook: endpoint receiving the JSON update
+	Headers  map[string]string `yaml:"headers"`  // webhook: extra request headers
+	TokenEnv string            `yaml:"tokenEnv"` // webhook: env var holding a bearer token
+
+	Timeout time.Duration `yaml:"timeout"` // reload command or webhook request; default 30s
+}
+
+// DNSSpec manages A records for the fleet's instances: <display name>.<zone> for each
+// instance and a round-robin <prefix><group>.<zone> for each instance group, where prefix
+// is displayNamePrefix (default <fleet>-).
+type DNSSpec struct {
+	Provider        string       `yaml:"provider"`        // "oci" (OCI DNS) or "rfc2136" (dynamic updates to BIND, Knot, PowerDNS, ...)
+	Zone            string       `yaml:"zone"`            // e.g. fleet.example.com
+	TTL             int          `yaml:"ttl"`             // record TTL in seconds; default 60
+	Groups          []string     `yaml:"groups"`          // instance groups with records; default every group
+	InstanceRecords *bool        `yaml:"instanceRecords"` // one record per instance; default true
+	GroupRecords    *bool        `yaml:"groupRecords"`    // round-robin record per group; default true
+	OCI             *OCIDNSSpec  `yaml:"oci"`             // provider oci settings
+	RFC2136         *RFC2136Spec `yaml:"rfc2136"`         // provider rfc2136 settings (required)
+}
+
+// OCIDNSSpec selects the OCI DNS zone holding the records.
+type OCIDNSSpec struct {
+	ZoneID        string `yaml:"zoneId"`        // zone OCID; default: the zone by name
+	Scope         string `yaml:"scope"`         // "global" (default) or "private"
+	ViewID        string `yaml:"viewId"`        // private zones: view OCID
+	CompartmentID string `yaml:"compartmentId"` // compartment of the zone; default spec.compartmentId
+}
+
+// RFC2136Spec points at the primary name server accepting dynamic updates.
+type RFC2136Spec struct {
+	Server        string        `yaml:"server"`        // host[:port] of the primary (TCP, default port 53); also serves the zone transfer
+	TSIGKey       string        `yaml:"tsigKey"`       // TSIG key name; empty sends unsigned messages
+	TSIGAlgorithm string        `yaml:"tsigAlgorithm"` // hmac-sha256 (default), hmac-sha512 or hmac-sha1
+	TSIGSecretEnv string        `yaml:"tsigSecretEnv"` // env var holding the base64 TSIG secret
+	Timeout       time.Duration `yaml:"timeout"`       // per exchange; default 10s
+}
+
+type LockSpec struct {
+	Lease              string        `yaml:"lease"`              // "" (flock only) or "oci" (compartment freeform-tag lease)
+	LeaseCompartmentID string        `yaml:"leaseCompartmentId"` // compartment holding the lease tag; defaults to spec.compartmentId
+	TTL                time.Duration `yaml:"ttl"`                // lease duration (renewed while held); default 10m
+}
+
+// StateSpec selects the backend that stores fleet state.
+type StateSpec struct {
+	Backend string       `yaml:"backend"` // "file" (default), "bolt" (embedded bbolt database) or "s3"
+	Path    string       `yaml:"path"`    // file/bolt: state file path (default: --state, next to the config)
+	S3      *S3StateSpec `yaml:"s3"`      // required for backend s3
+
+	Retention StateRetention `yaml:"retention"` // compaction of terminated records and automatic snapshots
+}
+
+// StateRetention bounds what the state keeps over time. Active records are never dropped.
+type StateRetention struct {
+	TerminatedMaxAge   *time.Duration `yaml:"terminatedMaxAge"`   // drop terminated/preempted records older than this; default 168h, 0s keeps them
+	TerminatedMaxCount *int           `yaml:"terminatedMaxCount"` // terminated/preempted records kept per fleet; default 100, 0 means no limit
+	Snapshots          int            `yaml:"snapshots"`          // automatic snapshots kept (manual backups are never pruned); default 20
+}
+
+// Defaults for StateRetention.
+const (
+	DefaultTerminatedMaxAge   = 7 * 24 * time.Hour
+	DefaultTerminatedMaxCount = 100
+	DefaultSnapshotsKept      = 20
+)
+
+// MaxAge returns how long terminated records are kept (0 = no age limit).
+func (r StateRetention) MaxAge() time.Duration {
+	if r.TerminatedMaxAge == nil {
+		return DefaultTerminatedMaxAge
+	}
+	return *r.TerminatedMaxAge
+}
+
+// MaxCount returns how many terminated records are kept per fleet (0 = no limit).
+func (r StateRetention) MaxCount() int {
+	if r.TerminatedMaxCount == nil {
+		return DefaultTerminatedMaxCount
+	}
+	return *r.TerminatedMaxCount
+}
+
+// SnapshotsKept returns how many automatic snapshots are kept.
+func (r StateRetention) SnapshotsKept() int {
+	if r.Snapshots <= 0 {
+		return DefaultSnapshotsKept
+	}
+	return r.Snapshots
+}
+
+// S3StateSpec locates the state object in S3-compatible object storage (OCI Object Storage
+// compatibility API, MinIO, AWS S3). Credentials are read from the environment.
+type S3StateSpec struct {
+	Endpoint           string `yaml:"endpoint"`           // e.g. https://<namespace>.compat.objectstorage.<region>.oraclecloud.com
+	Region             string `yaml:"region"`             // signing region; default us-east-1
+	Bucket             string `yaml:"bucket"`             // bucket name (path-style addressing)
+	Key                string `yaml:"key"`                // object key; default fleetctl/<fleet>.state.json
+	AccessKeyIDEnv     string `yaml:"accessKeyIdEnv"`     // env var with the access key; default AWS_ACCESS_KEY_ID
+	SecretAccessKeyEnv string `yaml:"secretAccessKeyEnv"` // env var with the secret key; default AWS_SECRET_ACCESS_KEY
+}
+
+// WarmPoolSpec keeps pre-provisioned instances STOPPED so scale-up can START them
+// instead of waiting for a full launch.
+type WarmPoolSpec struct {
+	Size  int    `yaml:"size"`  // number of stopped instances to keep ready; 0 disables the pool
+	Group string `yaml:"group"` // group the pool serves (subnet, naming); defaults to the first group
+}
+
+// MaintenanceSpec restricts disruptive operations to cron-style windows in a time zone.
+type MaintenanceSpec struct {
+	TimeZone      string              `yaml:"timeZone"`      // IANA name, e.g. Europe/Berlin; default UTC
+	Windows       []MaintenanceWindow `yaml:"windows"`       // a window is open for duration after each cron trigger
+	OutsideWindow string              `yaml:"outsideWindow"` // "reject" (default) or "queue" (API requests run when a window opens)
+}
+
+// MaintenanceWindow opens at each trigger of Cron (minute hour day-of-month month day-of-week) for Duration.
+type MaintenanceWindow struct {
+	Cron     string        `yaml:"cron"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// DisruptionBudget caps how many instances may be terminated or replaced per rolling hour.
+type DisruptionBudget struct {
+	MaxPerHour int `yaml:"maxPerHour"`
 }
 
 type Auth struct {
@@ -74,6 +396,78 @@ type Auth struct {
 	Region     string `yaml:"region"`     // optional region override
 }
 
+// Group returns the instance group with the given name, or nil if none matches.
+func (s Spec) Group(name string) *InstanceSpec {
+	for i := range s.Instances {
+		if s.Instances[i].Name == name {
+			return &s.Instances[i]
+		}
+	}
+	return nil
+}
+
+// AvailabilityDomainCandidates returns availabilityDomain followed by availabilityDomains,
+// trimmed and without duplicates. Empty means "first AD of the region".
+func (s Spec) AvailabilityDomainCandidates() []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, ad := range append([]string{s.AvailabilityDomain}, s.AvailabilityDomains...) {
+		ad = strings.TrimSpace(ad)
+		if ad == "" || seen[ad] {
+			continue
+		}
+		seen[ad] = true
+		out = append(out, ad)
+	}
+	return out
+}
+
+// PlacementSpread is the spec.placement value that balances groups across ADs and fault domains.
+const PlacementSpread = "spread"
+
+// Spread reports whether instances are balanced across availability and fault domains.
+func (s Spec) Spread() bool {
+	return strings.EqualFold(strings.TrimSpace(s.Placement), PlacementSpread)
+}
+
+// WarmPoolGroup returns the group served by the warm pool, or "" when the pool is disabled.
+func (s Spec) WarmPoolGroup() string {
+	if s.WarmPool == nil || s.WarmPool.Size <= 0 {
+		return ""
+	}
+	if g := strings.TrimSpace(s.WarmPool.Group); g != "" {
+		return g
+	}
+	if len(s.Instances) > 0 && s.Instances[0].Name != "" {
+		return s.Instances[0].Name
+	}
+	return "default"
+}
+
+// Revision is a short hash of the spec fields that determine what a launched instance
+// looks like (image, shapes, subnets, tags). Counts, scaling and LB settings do not change
+// it, so instances whose recorded revision differs were launched from an older template.
+func (s Spec) Revision() string {
+	subnets := map[string]string{}
+	for _, g := range s.Instances {
+		if g.SubnetID != "" {
+			subnets[g.Name] = g.SubnetID
+		}
+	}
+	data, _ := json.Marshal(struct {
+		ImageID        string
+		Shape          string
+		ShapeConfig    *ShapeConfig
+		FallbackShapes []ShapeOption
+		SubnetID       string
+		GroupSubnets   map[string]string
+		DefinedTags    map[string]string
+		FreeformTags   map[string]string
+	}{s.ImageID, s.Shape, s.ShapeConfig, s.FallbackShapes, s.SubnetID, subnets, s.DefinedTags, s.FreeformTags})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
 // ParseFile reads and parses a YAML configuration file
 func ParseFile(filename string) (*FleetConfig, error) {
 	data, err := os.ReadFile(filename)
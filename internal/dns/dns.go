@@ -0,0 +1,274 @@
+// internal/dns/dns.go
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"fleetctl/internal/config"
+	"fleetctl/internal/registrar"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+)
+
+// DefaultTTL is the record TTL used when spec.dns.ttl is unset.
+const DefaultTTL = 60
+
+// Record types managed by the registrar.
+const (
+	TypeA   = "A"
+	TypeTXT = "TXT"
+)
+
+// Record is one A or TXT record.
+type Record struct {
+	Name  string // fully qualified, lower case, without the trailing dot
+	Type  string // TypeA or TypeTXT
+	Value string // IPv4 address, or TXT text without quotes
+}
+
+// Change adds or removes one record.
+type Change struct {
+	Record
+	Remove bool
+}
+
+// Provider manages the A and TXT records of a DNS zone.
+type Provider interface {
+	// Records returns the zone's A and TXT records.
+	Records(ctx context.Context) ([]Record, error)
+	// Update applies changes in one request.
+	Update(ctx context.Context, changes []Change) error
+}
+
+// Open returns the registrar keeping spec.dns in line with the fleet. prefix is the
+// display name prefix (displayNamePrefix, default <fleet>-) group records are named
+// with; provider and region are the OCI credentials of the oci provider.
+func Open(provider common.ConfigurationProvider, region string, cfg config.FleetConfig, prefix string) (registrar.Registrar, error) {
+	spec := cfg.Spec.DNS
+	zone := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(spec.Zone), "."))
+	if zone == "" {
+		return nil, fmt.Errorf("dns.zone must be set")
+	}
+	ttl := spec.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	var (
+		p   Provider
+		err error
+	)
+	switch strings.ToLower(spec.Provider) {
+	case "oci":
+		p, err = NewOCI(provider, region, cfg, zone, ttl)
+	case "rfc2136":
+		if spec.RFC2136 == nil {
+			return nil, fmt.Errorf("dns.rfc2136 must be set for provider rfc2136")
+		}
+		p, err = NewRFC2136(*spec.RFC2136, zone, ttl)
+	default:
+		return nil, fmt.Errorf("dns.provider %q: use oci or rfc2136", spec.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Registrar{
+		Provider:  p,
+		Zone:      zone,
+		Fleet:     cfg.Metadata.Name,
+		Prefix:    prefix,
+		Instances: spec.InstanceRecords == nil || *spec.InstanceRecords,
+		Groups:    spec.GroupRecords == nil || *spec.GroupRecords,
+	}
+	return registrar.Scoped(r, spec.Groups, 0), nil
+}
+
+// Registrar keeps A records for fleet members: <display name>.<zone> per instance and a
+// round-robin <prefix><group>.<zone> per group. Labels are lower-cased and characters
+// other than letters, digits and '-' become '-'.
+//
+// Every name the fleet creates also gets a TXT record holding Owner(fleet), as
+// external-dns does. Only names carrying the fleet's TXT record are ever changed or
+// removed; a name with A records but no such TXT record belongs to someone else and is
+// left alone.
+type Registrar struct {
+	Provider  Provider
+	Zone      string
+	Fleet     string
+	Prefix    string
+	Instances bool // per-instance records
+	Groups    bool // per-group round-robin records
+}
+
+func (r *Registrar) Name() string { return "dns" }
+
+// Owner returns the TXT record text marking names owned by fleet.
+func Owner(fleet string) string { return "heritage=fleetctl,fleetctl/fleet=" + fleet }
+
+// zoneRecords is the part of a zone the registrar looks at.
+type zoneRecords struct {
+	a     map[string][]string // name -> addresses
+	owned map[string]bool     // names with the fleet's TXT record
+}
+
+// Apply reads the zone and changes the fleet's records. With u.Sync every owned name is
+// brought in line with u.Members; otherwise only the names of u.Added and u.Removed
+// change. Names of members that are taken by records of others are reported in the
+// error after the other changes are applied.
+func (r *Registrar) Apply(ctx context.Context, u registrar.Update) error {
+	recs, err := r.Provider.Records(ctx)
+	if err != nil {
+		return fmt.Errorf("read records: %w", err)
+	}
+	z := zoneRecords{a: map[string][]string{}, owned: map[string]bool{}}
+	owner := Owner(r.Fleet)
+	for _, rec := range recs {
+		switch {
+		case rec.Type == TypeA:
+			z.a[rec.Name] = append(z.a[rec.Name], rec.Value)
+		case rec.Type == TypeTXT && rec.Value == owner:
+			z.owned[rec.Name] = true
+		}
+	}
+	var changes []Change
+	var taken []string
+	if u.Sync {
+		changes, taken = r.diff(z, r.want(u.Members), nil)
+	} else {
+		changes, taken = r.update(z, u.Added, u.Removed)
+	}
+	if len(changes) > 0 {
+		if err := r.Provider.Update(ctx, changes); err != nil {
+			return fmt.Errorf("update %d records: %w", len(changes), err)
+		}
+	}
+	if len(taken) > 0 {
+		return fmt.Errorf("records not owned by fleet %s left alone: %s", r.Fleet, strings.Join(taken, ", "))
+	}
+	return nil
+}
+
+// want returns the addresses each name should hold for members.
+func (r *Registrar) want(members []registrar.Member) map[string][]string {
+	want := map[string][]string{}
+	for _, m := range members {
+		for _, name := range r.names(m) {
+			if !slices.Contains(want[name], m.IP) {
+				want[name] = append(want[name], m.IP)
+			}
+		}
+	}
+	return want
+}
+
+// update returns the changes for added and removed members: their names keep the
+// addresses they hold, without those of removed and with those of added.
+func (r *Registrar) update(z zoneRecords, added, removed []registrar.Member) ([]Change, []string) {
+	want := map[string][]string{}
+	scope := map[string]bool{}
+	for _, m := range slices.Concat(removed, added) {
+		for _, name := range r.names(m) {
+			if !scope[name] {
+				scope[name] = true
+				if z.owned[name] {
+					want[name] = slices.Clone(z.a[name])
+				}
+			}
+		}
+	}
+	for _, m := range removed {
+		for _, name := range r.names(m) {
+			want[name] = slices.DeleteFunc(want[name], func(ip string) bool { return ip == m.IP })
+		}
+	}
+	for _, m := range added {
+		for _, name := range r.names(m) {
+			if !slices.Contains(want[name], m.IP) {
+				want[name] = append(want[name], m.IP)
+			}
+		}
+	}
+	return r.diff(z, want, scope)
+}
+
+// diff returns the changes turning the owned names of z (only those in scope, unless
+// scope is nil) into want, removals first, each sorted by name and value, and the wanted
+// names left alone because others hold records there. Owned names no longer wanted lose
+// their TXT record too.
+func (r *Registrar) diff(z zoneRecords, want map[string][]string, scope map[string]bool) ([]Change, []string) {
+	owner := Owner(r.Fleet)
+	var rm, add []Change
+	var taken []string
+	for name := range z.owned {
+		if scope != nil && !scope[name] {
+			continue
+		}
+		for _, ip := range z.a[name] {
+			if !slices.Contains(want[name], ip) {
+				rm = append(rm, Change{Record: Record{Name: name, Type: TypeA, Value: ip}, Remove: true})
+			}
+		}
+		if len(want[name]) == 0 {
+			rm = append(rm, Change{Record: Record{Name: name, Type: TypeTXT, Value: owner}, Remove: true})
+		}
+	}
+	for name, ips := range want {
+		if len(ips) == 0 {
+			continue
+		}
+		if !z.owned[name] {
+			if len(z.a[name]) > 0 {
+				taken = append(taken, name)
+				continue
+			}
+			add = append(add, Change{Record: Record{Name: name, Type: TypeTXT, Value: owner}})
+		}
+		for _, ip := range ips {
+			if !slices.Contains(z.a[name], ip) {
+				add = append(add, Change{Record: Record{Name: name, Type: TypeA, Value: ip}})
+			}
+		}
+	}
+	byName := func(a, b Change) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Value, b.Value)
+	}
+	slices.SortFunc(rm, byName)
+	slices.SortFunc(add, byName)
+	slices.Sort(taken)
+	return append(rm, add...), taken
+}
+
+// names returns the record names m is published under.
+func (r *Registrar) names(m registrar.Member) []string {
+	var out []string
+	if r.Instances && m.Name != "" {
+		out = append(out, label(m.Name)+"."+r.Zone)
+	}
+	if r.Groups && m.Group != "" {
+		out = append(out, label(r.Prefix+m.Group)+"."+r.Zone)
+	}
+	return out
+}
+
+// label turns s into a DNS label: lower case letters, digits and '-', at most 63 bytes
+// and not ending in '-'.
+func label(s string) string {
+	b := []byte(strings.ToLower(s))
+	for i, c := range b {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			b[i] = '-'
+		}
+	}
+	if len(b) > 63 {
+		b = b[:63]
+	}
+	return strings.TrimRight(string(b), "-")
+}
@@ -0,0 +1,168 @@
+// internal/dns/dns_test.go
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"fleetctl/internal/registrar"
+)
+
+// fakeProvider keeps records in memory, applies updates to them and logs the updates.
+type fakeProvider struct {
+	records []Record
+	updates []string
+}
+
+func (p *fakeProvider) Records(context.Context) ([]Record, error) {
+	return slices.Clone(p.records), nil
+}
+
+func (p *fakeProvider) Update(_ context.Context, changes []Change) error {
+	for _, c := range changes {
+		op := "+"
+		if c.Remove {
+			op = "-"
+			p.records = slices.DeleteFunc(p.records, func(r Record) bool { return r == c.Record })
+		} else if !slices.Contains(p.records, c.Record) {
+			p.records = append(p.records, c.Record)
+		}
+		p.updates = append(p.updates, fmt.Sprintf("%s%s %s %s", op, c.Name, c.Type, c.Value))
+	}
+	return nil
+}
+
+// zone returns the provider's records as "name type value", sorted.
+func (p *fakeProvider) zone() []string {
+	var out []string
+	for _, r := range p.records {
+		out = append(out, r.Name+" "+r.Type+" "+r.Value)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func a(name, ip string) Record {
+	return Record{Name: name + ".fleet.example.com", Type: TypeA, Value: ip}
+}
+func owned(name, fleet string) Record {
+	return Record{Name: name + ".fleet.example.com", Type: TypeTXT, Value: Owner(fleet)}
+}
+
+func newRegistrar(p Provider, fleet string) *Registrar {
+	return &Registrar{Provider: p, Zone: "fleet.example.com", Fleet: fleet, Prefix: fleet + "-", Instances: true, Groups: true}
+}
+
+func TestRegistrarAnnouncesInstancesAndGroups(t *testing.T) {
+	p := &fakeProvider{records: []Record{
+		a("shop-web-20261018-1", "10.0.0.1"), owned("shop-web-20261018-1", "shop"),
+		a("shop-web", "10.0.0.1"), a("shop-web", "10.0.0.3"), owned("shop-web", "shop"),
+	}}
+	r := newRegistrar(p, "shop")
+	web1 := registrar.Member{ID: "a", Name: "shop-web-20261018_1", Group: "web", IP: "10.0.0.1"}
+	web2 := registrar.Member{ID: "b", Name: "shop-web-20261018_2", Group: "web", IP: "10.0.0.2"}
+	err := r.Apply(context.Background(), registrar.Update{Added: []registrar.Member{web2}, Removed: []registrar.Member{web1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-shop-web-20261018-1.fleet.example.com A 10.0.0.1 -shop-web-20261018-1.fleet.example.com TXT " + Owner("shop") +
+		" -shop-web.fleet.example.com A 10.0.0.1" +
+		" +shop-web-20261018-2.fleet.example.com A 10.0.0.2 +shop-web-20261018-2.fleet.example.com TXT " + Owner("shop") +
+		" +shop-web.fleet.example.com A 10.0.0.2"
+	if got := strings.Join(p.updates, " "); got != want {
+		t.Fatalf("updates = %s\nwant %s", got, want)
+	}
+}
+
+func TestRegistrarSyncTouchesOnlyOwnedRecords(t *testing.T) {
+	foreign := []Record{
+		a("shop-db", "10.2.0.1"),                                         // made by hand
+		a("shop-api-web", "10.1.0.1"), owned("shop-api-web", "shop-api"), // fleet shop-api
+		a("shop-api-web-1", "10.1.0.1"), owned("shop-api-web-1", "shop-api"),
+	}
+	p := &fakeProvider{records: append(slices.Clone(foreign),
+		a("shop-web", "10.0.0.1"), a("shop-web", "10.0.0.9"), owned("shop-web", "shop"), // 10.0.0.9 is gone
+		a("shop-web-1", "10.0.0.1"), owned("shop-web-1", "shop"),
+		a("shop-web-old", "10.0.0.9"), owned("shop-web-old", "shop"),
+	)}
+	shop := newRegistrar(p, "shop")
+	members := []registrar.Member{
+		{ID: "a", Name: "shop-web-1", Group: "web", IP: "10.0.0.1"},
+		{ID: "b", Name: "shop-web-2", Group: "web", IP: "10.0.0.2"},
+	}
+	ctx := context.Background()
+	if err := shop.Apply(ctx, registrar.Update{Members: members, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "-shop-web-old.fleet.example.com A 10.0.0.9 -shop-web-old.fleet.example.com TXT " + Owner("shop") +
+		" -shop-web.fleet.example.com A 10.0.0.9" +
+		" +shop-web-2.fleet.example.com A 10.0.0.2 +shop-web-2.fleet.example.com TXT " + Owner("shop") +
+		" +shop-web.fleet.example.com A 10.0.0.2"
+	if got := strings.Join(p.updates, " "); got != want {
+		t.Fatalf("updates = %s\nwant %s", got, want)
+	}
+	for _, r := range foreign {
+		if !slices.Contains(p.records, r) {
+			t.Fatalf("foreign record %+v removed", r)
+		}
+	}
+
+	// Both fleets reconciling the same zone settle: no updates on the next round.
+	api := newRegistrar(p, "shop-api")
+	apiMembers := []registrar.Member{{ID: "c", Name: "shop-api-web-1", Group: "web", IP: "10.1.0.1"}}
+	if err := api.Apply(ctx, registrar.Update{Members: apiMembers, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	p.updates = nil
+	if err := shop.Apply(ctx, registrar.Update{Members: members, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Apply(ctx, registrar.Update{Members: apiMembers, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.updates) != 0 {
+		t.Fatalf("records flap between fleets: %v", p.updates)
+	}
+
+	// Without instance records their names are removed.
+	shop.Instances = false
+	if err := shop.Apply(ctx, registrar.Update{Members: members, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p.zone(), "\n"); strings.Contains(got, "shop-web-1.") || strings.Contains(got, "shop-web-2.") {
+		t.Fatalf("instance records left:\n%s", got)
+	}
+}
+
+func TestRegistrarLeavesNamesTakenByOthers(t *testing.T) {
+	p := &fakeProvider{records: []Record{a("shop-web", "192.0.2.1")}}
+	r := newRegistrar(p, "shop")
+	m := registrar.Member{ID: "a", Name: "shop-web-1", Group: "web", IP: "10.0.0.1"}
+	err := r.Apply(context.Background(), registrar.Update{Added: []registrar.Member{m}})
+	if err == nil || !strings.Contains(err.Error(), "shop-web.fleet.example.com") {
+		t.Fatalf("err = %v, want the taken group name", err)
+	}
+	want := []string{
+		"shop-web-1.fleet.example.com A 10.0.0.1",
+		"shop-web-1.fleet.example.com TXT " + Owner("shop"),
+		"shop-web.fleet.example.com A 192.0.2.1",
+	}
+	if got := p.zone(); !slices.Equal(got, want) {
+		t.Fatalf("zone = %v, want %v", got, want)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	for in, want := range map[string]string{
+		"Shop_Web-20261018T120000-0":   "shop-web-20261018t120000-0",
+		"web.eu":                       "web-eu",
+		strings.Repeat("a", 62) + "-b": strings.Repeat("a", 62),
+	} {
+		if got := label(in); got != want {
+			t.Fatalf("label(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// internal/dns/oci.go
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	ocidns "github.com/oracle/oci-go-sdk/v65/dns"
+)
+
+// OCI manages A and TXT records in an OCI DNS zone through the zone records API.
+type OCI struct {
+	client      ocidns.DnsClient
+	zone        string // zone OCID or name
+	scope       string // GLOBAL or PRIVATE
+	viewID      string
+	compartment string
+	ttl         int
+}
+
+// NewOCI returns the OCI DNS provider for spec.dns.oci; the zone defaults to zone by name
+// in spec.compartmentId.
+func NewOCI(provider common.ConfigurationProvider, region string, cfg config.FleetConfig, zone string, ttl int) (*OCI, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("dns provider oci needs OCI credentials")
+	}
+	c, err := ocidns.NewDnsClientWithConfigurationProvider(provider)
+	if err != nil {
+		return nil, fmt.Errorf("dns client init: %w", err)
+	}
+	if region != "" {
+		c.SetRegion(region)
+	}
+	p := &OCI{client: c, zone: zone, scope: "GLOBAL", compartment: cfg.Spec.CompartmentID, ttl: ttl}
+	if o := cfg.Spec.DNS.OCI; o != nil {
+		if o.ZoneID != "" {
+			p.zone = o.ZoneID
+		}
+		if o.CompartmentID != "" {
+			p.compartment = o.CompartmentID
+		}
+		switch strings.ToUpper(o.Scope) {
+		case "", "GLOBAL":
+		case "PRIVATE":
+			p.scope, p.viewID = "PRIVATE", o.ViewID
+		default:
+			return nil, fmt.Errorf("dns.oci.scope %q: use global or private", o.Scope)
+		}
+	}
+	return p, nil
+}
+
+// Records lists every page of the zone's A and TXT records.
+func (p *OCI) Records(ctx context.Context) ([]Record, error) {
+	req := ocidns.GetZoneRecordsRequest{
+		ZoneNameOrId:  &p.zone,
+		Scope:         ocidns.GetZoneRecordsScopeEnum(p.scope),
+		CompartmentId: &p.compartment,
+	}
+	if p.viewID != "" {
+		req.ViewId = &p.viewID
+	}
+	var out []Record
+	for {
+		resp, err := p.client.GetZoneRecords(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("get zone records %s: %w", p.zone, err)
+		}
+		for _, r := range resp.Items {
+			if r.Domain == nil || r.Rdata == nil || r.Rtype == nil {
+				continue
+			}
+			rec := Record{Name: strings.ToLower(strings.TrimSuffix(*r.Domain, ".")), Type: strings.ToUpper(*r.Rtype), Value: strings.TrimSpace(*r.Rdata)}
+			switch rec.Type {
+			case TypeA:
+			case TypeTXT:
+				// OCI returns TXT data as quoted strings
+				rec.Value = strings.ReplaceAll(strings.Trim(rec.Value, `"`), `" "`, "")
+			default:
+				continue
+			}
+			out = append(out, rec)
+		}
+		if resp.OpcNextPage == nil {
+			return out, nil
+		}
+		req.Page = resp.OpcNextPage
+	}
+}
+
+// Update patches the zone with one ADD or REMOVE operation per change.
+func (p *OCI) Update(ctx context.Context, changes []Change) error {
+	ops := make([]ocidns.RecordOperation, 0, len(changes))
+	for _, c := range changes {
+		name, rdata, rtype, ttl := c.Name, c.Value, c.Type, p.ttl
+		if rtype == TypeTXT {
+			rdata = `"` + rdata + `"`
+		}
+		op := ocidns.RecordOperation{Domain: &name, Rtype: &rtype, Rdata: &rdata, Ttl: &ttl, Operation: ocidns.RecordOperationOperationAdd}
+		if c.Remove {
+			op.Operation = ocidns.RecordOperationOperationRemove
+		}
+		ops = append(ops, op)
+	}
+	req := ocidns.PatchZoneRecordsRequest{
+		ZoneNameOrId:            &p.zone,
+		PatchZoneRecordsDetails: ocidns.PatchZoneRecordsDetails{Items: ops},
+		Scope:                   ocidns.PatchZoneRecordsScopeEnum(p.scope),
+		CompartmentId:           &p.compartment,
+	}
+	if p.viewID != "" {
+		req.ViewId = &p.viewID
+	}
+	if _, err := p.client.PatchZoneRecords(ctx, req); err != nil {
+		return fmt.Errorf("patch zone records %s: %w", p.zone, err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// internal/dns/rfc2136.go
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+// DefaultRFC2136Timeout bounds one exchange with the name server when spec.dns.rfc2136.timeout is unset.
+const DefaultRFC2136Timeout = 10 * time.Second
+
+// RFC2136 manages A and TXT records with dynamic updates (RFC 2136) sent to the zone's primary
+// name server over TCP. Records are read with a zone transfer (AXFR), which the server
+// must allow for the same key. Messages are TSIG-signed when a key is configured;
+// responses are not verified.
+type RFC2136 struct {
+	server  string
+	zone    string
+	ttl     int
+	key     *tsigKey
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewRFC2136 returns the provider for spec.dns.rfc2136. The TSIG secret is read from
+// the environment variable tsigSecretEnv.
+func NewRFC2136(spec config.RFC2136Spec, zone string, ttl int) (*RFC2136, error) {
+	server := strings.TrimSpace(spec.Server)
+	if server == "" {
+		return nil, fmt.Errorf("dns.rfc2136.server must be set")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	p := &RFC2136{server: server, zone: zone, ttl: ttl, timeout: spec.Timeout, now: time.Now}
+	if p.timeout <= 0 {
+		p.timeout = DefaultRFC2136Timeout
+	}
+	if spec.TSIGKey != "" {
+		alg := strings.ToLower(strings.TrimSuffix(spec.TSIGAlgorithm, "."))
+		if alg == "" {
+			alg = "hmac-sha256"
+		}
+		if _, ok := tsigHashes[alg]; !ok {
+			return nil, fmt.Errorf("dns.rfc2136.tsigAlgorithm %q: use hmac-sha256, hmac-sha512 or hmac-sha1", spec.TSIGAlgorithm)
+		}
+		if spec.TSIGSecretEnv == "" {
+			return nil, fmt.Errorf("dns.rfc2136.tsigSecretEnv must name the variable holding the TSIG secret")
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(os.Getenv(spec.TSIGSecretEnv)))
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("dns.rfc2136: %s must hold the base64 TSIG secret", spec.TSIGSecretEnv)
+		}
+		p.key = &tsigKey{Name: strings.TrimSuffix(spec.TSIGKey, "."), Algorithm: alg, Secret: secret}
+	}
+	return p, nil
+}
+
+// Records transfers the zone and returns its A and TXT records.
+func (p *RFC2136) Records(ctx context.Context) ([]Record, error) {
+	m := message{ID: rand.N[uint16](0xffff), Questions: []question{{Name: p.zone, Type: typeAXFR, Class: classIN}}}
+	var out []Record
+	soas := 0
+	err := p.exchange(ctx, m, func(resp message) bool {
+		for _, r := range resp.Answers {
+			switch {
+			case r.Type == typeSOA:
+				soas++
+			case r.Type == typeA && len(r.Data) == 4:
+				out = append(out, Record{Name: r.Name, Type: TypeA, Value: netip.AddrFrom4([4]byte(r.Data)).String()})
+			case r.Type == typeTXT:
+				if text, err := txtText(r.Data); err == nil {
+					out = append(out, Record{Name: r.Name, Type: TypeTXT, Value: text})
+				}
+			}
+		}
+		// The transfer ends with the zone's SOA record repeated.
+		return soas >= 2
+	})
+	if err != nil {
+		return nil, fmt.Errorf("zone transfer %s from %s: %w", p.zone, p.server, err)
+	}
+	return out, nil
+}
+
+// Update sends changes as one UPDATE message: additions with the configured TTL,
+// removals as deletions of the single record (class NONE).
+func (p *RFC2136) Update(ctx context.Context, changes []Change) error {
+	m := message{
+		ID:        rand.N[uint16](0xffff),
+		Flags:     opcodeUpdate << 11,
+		Questions: []question{{Name: p.zone, Type: typeSOA, Class: classIN}},
+	}
+	for _, c := range changes {
+		r := rr{Name: c.Name, Class: classIN, TTL: uint32(p.ttl)}
+		switch c.Type {
+		case TypeA:
+			ip, err := netip.ParseAddr(c.Value)
+			if err != nil || !ip.Is4() {
+				return fmt.Errorf("record %s: %q is not an IPv4 address", c.Name, c.Value)
+			}
+			a := ip.As4()
+			r.Type, r.Data = typeA, a[:]
+		case TypeTXT:
+			r.Type, r.Data = typeTXT, txtData(c.Value)
+		default:
+			return fmt.Errorf("record %s: unsupported type %s", c.Name, c.Type)
+		}
+		if c.Remove {
+			r.Class, r.TTL = classNONE, 0
+		}
+		m.Authority = append(m.Authority, r)
+	}
+	err := p.exchange(ctx, m, func(message) bool { return true })
+	if err != nil {
+		return fmt.Errorf("update %s at %s: %w", p.zone, p.server, err)
+	}
+	return nil
+}
+
+// exchange sends m (signed when a key is set) and reads responses until done reports
+// true. A response with an error code ends the exchange with that error.
+func (p *RFC2136) exchange(ctx context.Context, m message, done func(message) bool) error {
+	var (
+		raw []byte
+		err error
+	)
+	if p.key != nil {
+		raw, err = p.key.sign(m, p.now())
+	} else {
+		raw, err = m.pack()
+	}
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", p.server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(raw)))); err != nil {
+		return err
+	}
+	if _, err := conn.Write(raw); err != nil {
+		return err
+	}
+	for {
+		var n [2]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		buf := make([]byte, binary.BigEndian.Uint16(n[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return err
+		}
+		resp, err := unpack(buf)
+		if err != nil {
+			return err
+		}
+		if resp.ID != m.ID {
+			return fmt.Errorf("response ID %d does not match query %d", resp.ID, m.ID)
+		}
+		if err := resp.rcodeError(); err != nil {
+			return err
+		}
+		if done(resp) {
+			return nil
+		}
+	}
+}
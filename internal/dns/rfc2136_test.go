@@ -0,0 +1,180 @@
+// internal/dns/rfc2136_test.go
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"fleetctl/internal/config"
+)
+
+// testServer is a minimal authoritative server for one zone over TCP: it applies
+// UPDATE messages and answers AXFR, rejecting messages without a valid TSIG.
+type testServer struct {
+	zone string
+	key  tsigKey
+	ln   net.Listener
+
+	mu      sync.Mutex
+	records []rr
+}
+
+func startServer(t *testing.T, zone string, key tsigKey) *testServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{zone: zone, key: key, ln: ln, records: []rr{{Name: "www." + zone, Type: typeA, Class: classIN, TTL: 60, Data: []byte{192, 0, 2, 80}}}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	var n [2]byte
+	if _, err := io.ReadFull(conn, n[:]); err != nil {
+		return
+	}
+	raw := make([]byte, binary.BigEndian.Uint16(n[:]))
+	if _, err := io.ReadFull(conn, raw); err != nil {
+		return
+	}
+	m, err := unpack(raw)
+	if err != nil {
+		return
+	}
+	resp := message{ID: m.ID, Flags: flagQR | m.Flags&(0xf<<11)}
+	switch {
+	case !s.verify(m):
+		resp.Flags |= 9 // NOTAUTH
+	case m.Flags>>11&0xf == opcodeUpdate:
+		s.mu.Lock()
+		for _, r := range m.Authority {
+			same := func(o rr) bool { return o.Name == r.Name && o.Type == r.Type && bytes.Equal(o.Data, r.Data) }
+			if r.Class == classNONE {
+				s.records = slices.DeleteFunc(s.records, same)
+			} else if !slices.ContainsFunc(s.records, same) {
+				s.records = append(s.records, rr{Name: r.Name, Type: r.Type, Class: classIN, TTL: r.TTL, Data: slices.Clone(r.Data)})
+			}
+		}
+		s.mu.Unlock()
+	case len(m.Questions) == 1 && m.Questions[0].Type == typeAXFR:
+		soa := rr{Name: s.zone, Type: typeSOA, Class: classIN, Data: make([]byte, 22)}
+		resp.Answers = append(resp.Answers, soa)
+		s.mu.Lock()
+		resp.Answers = append(resp.Answers, s.records...)
+		s.mu.Unlock()
+		resp.Answers = append(resp.Answers, soa)
+	default:
+		resp.Flags |= 4 // NOTIMP
+	}
+	out, _ := resp.pack()
+	conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(out))))
+	conn.Write(out)
+}
+
+// verify checks the TSIG record closing m against the server's key.
+func (s *testServer) verify(m message) bool {
+	if len(m.Additional) == 0 {
+		return false
+	}
+	t := m.Additional[len(m.Additional)-1]
+	if t.Type != typeTSIG || t.Name != s.key.Name {
+		return false
+	}
+	alg, off, err := readName(t.Data, 0)
+	if err != nil || alg != s.key.Algorithm || off+10 > len(t.Data) {
+		return false
+	}
+	signed := uint64(binary.BigEndian.Uint16(t.Data[off:]))<<32 | uint64(binary.BigEndian.Uint32(t.Data[off+2:]))
+	fudge := binary.BigEndian.Uint16(t.Data[off+6:])
+	size := int(binary.BigEndian.Uint16(t.Data[off+8:]))
+	if off+10+size > len(t.Data) {
+		return false
+	}
+	m.Additional = m.Additional[:len(m.Additional)-1]
+	raw, err := m.pack()
+	if err != nil {
+		return false
+	}
+	want, err := s.key.mac(raw, signed, fudge)
+	return err == nil && hmac.Equal(t.Data[off+10:off+10+size], want)
+}
+
+func TestRFC2136UpdatesAndTransfersTheZone(t *testing.T) {
+	secret := []byte("fleetctl test secret")
+	srv := startServer(t, "fleet.example.com", tsigKey{Name: "fleetctl", Algorithm: "hmac-sha256", Secret: secret})
+	t.Setenv("TSIG_SECRET", base64.StdEncoding.EncodeToString(secret))
+	p, err := NewRFC2136(config.RFC2136Spec{Server: srv.ln.Addr().String(), TSIGKey: "fleetctl.", TSIGSecretEnv: "TSIG_SECRET"}, "fleet.example.com", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	owner := Record{Name: "shop-web.fleet.example.com", Type: TypeTXT, Value: Owner("shop") + strings.Repeat(".", 300)}
+	err = p.Update(ctx, []Change{
+		{Record: Record{Name: "shop-web.fleet.example.com", Type: TypeA, Value: "10.0.0.1"}},
+		{Record: Record{Name: "shop-web.fleet.example.com", Type: TypeA, Value: "10.0.0.2"}},
+		{Record: owner},
+		{Record: Record{Name: "www.fleet.example.com", Type: TypeA, Value: "192.0.2.80"}, Remove: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := p.Records(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{
+		{Name: "shop-web.fleet.example.com", Type: TypeA, Value: "10.0.0.1"},
+		{Name: "shop-web.fleet.example.com", Type: TypeA, Value: "10.0.0.2"},
+		owner,
+	}
+	if !slices.Equal(recs, want) {
+		t.Fatalf("records = %v", recs)
+	}
+
+	// A wrong secret is refused
+	t.Setenv("TSIG_SECRET", base64.StdEncoding.EncodeToString([]byte("wrong")))
+	bad, _ := NewRFC2136(config.RFC2136Spec{Server: srv.ln.Addr().String(), TSIGKey: "fleetctl", TSIGSecretEnv: "TSIG_SECRET"}, "fleet.example.com", 60)
+	if err := bad.Update(ctx, []Change{{Record: Record{Name: "x.fleet.example.com", Type: TypeA, Value: "10.0.0.3"}}}); err == nil || !strings.Contains(err.Error(), "NOTAUTH") {
+		t.Fatalf("update with a wrong secret: err = %v", err)
+	}
+}
+
+func TestNewRFC2136Validates(t *testing.T) {
+	for _, tc := range []struct {
+		spec config.RFC2136Spec
+		want string
+	}{
+		{config.RFC2136Spec{}, "server must be set"},
+		{config.RFC2136Spec{Server: "ns1", TSIGKey: "k", TSIGAlgorithm: "hmac-md5", TSIGSecretEnv: "X"}, `tsigAlgorithm "hmac-md5"`},
+		{config.RFC2136Spec{Server: "ns1", TSIGKey: "k"}, "tsigSecretEnv must name"},
+		{config.RFC2136Spec{Server: "ns1", TSIGKey: "k", TSIGSecretEnv: "FLEETCTL_UNSET_SECRET"}, "must hold the base64 TSIG secret"},
+	} {
+		if _, err := NewRFC2136(tc.spec, "fleet.example.com", 60); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("NewRFC2136(%+v): err = %v, want %q", tc.spec, err, tc.want)
+		}
+	}
+	p, err := NewRFC2136(config.RFC2136Spec{Server: "ns1.example.com"}, "fleet.example.com", 60)
+	if err != nil || p.server != "ns1.example.com:53" || p.key != nil {
+		t.Fatalf("unsigned provider = %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,322 @@
+// internal/dns/wire.go
+package dns
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// DNS wire format (RFC 1035) for the RFC 2136 provider: UPDATE and AXFR messages,
+// optionally signed with TSIG (RFC 8945). Names are packed without compression;
+// compressed names are accepted when unpacking.
+
+const (
+	typeA    = 1
+	typeSOA  = 6
+	typeTXT  = 16
+	typeTSIG = 250
+	typeAXFR = 252
+
+	classIN   = 1
+	classNONE = 254
+	classANY  = 255
+
+	opcodeUpdate = 5
+	flagQR       = 1 << 15
+	tsigFudge    = 300 // seconds of clock skew the server accepts
+)
+
+var errShort = errors.New("dns: message too short")
+
+// rcodeNames are the response codes of RFC 1035 and RFC 2136.
+var rcodeNames = map[int]string{
+	0: "NOERROR", 1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+type question struct {
+	Name        string
+	Type, Class uint16
+}
+
+// rr is a resource record. Names are lower case without the trailing dot.
+type rr struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+// message is a DNS message. For UPDATE the sections are zone, prerequisites, updates and
+// additional records.
+type message struct {
+	ID         uint16
+	Flags      uint16 // QR, opcode, AA, TC, RD, RA and rcode
+	Questions  []question
+	Answers    []rr
+	Authority  []rr
+	Additional []rr
+}
+
+func (m message) rcode() int { return int(m.Flags & 0xf) }
+
+// rcodeError returns nil for NOERROR, else an error naming the response code.
+func (m message) rcodeError() error {
+	rc := m.rcode()
+	if rc == 0 {
+		return nil
+	}
+	name, ok := rcodeNames[rc]
+	if !ok {
+		name = fmt.Sprintf("RCODE%d", rc)
+	}
+	return fmt.Errorf("server answered %s", name)
+}
+
+func (m message) pack() ([]byte, error) {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	binary.BigEndian.PutUint16(b[2:], m.Flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.Authority)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additional)))
+	var err error
+	for _, q := range m.Questions {
+		if b, err = appendName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, q.Class)
+	}
+	for _, sec := range [][]rr{m.Answers, m.Authority, m.Additional} {
+		for _, r := range sec {
+			if b, err = appendRR(b, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+func appendName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, l := range strings.Split(name, ".") {
+			if l == "" || len(l) > 63 {
+				return nil, fmt.Errorf("dns: invalid name %q", name)
+			}
+			b = append(b, byte(len(l)))
+			b = append(b, l...)
+		}
+	}
+	return append(b, 0), nil
+}
+
+func appendRR(b []byte, r rr) ([]byte, error) {
+	b, err := appendName(b, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint16(b, r.Type)
+	b = binary.BigEndian.AppendUint16(b, r.Class)
+	b = binary.BigEndian.AppendUint32(b, r.TTL)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(r.Data)))
+	return append(b, r.Data...), nil
+}
+
+func unpack(b []byte) (message, error) {
+	if len(b) < 12 {
+		return message{}, errShort
+	}
+	m := message{ID: binary.BigEndian.Uint16(b[0:]), Flags: binary.BigEndian.Uint16(b[2:])}
+	counts := [4]int{}
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(b[4+2*i:]))
+	}
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readName(b, off)
+		if err != nil {
+			return message{}, err
+		}
+		if next+4 > len(b) {
+			return message{}, errShort
+		}
+		m.Questions = append(m.Questions, question{Name: name, Type: binary.BigEndian.Uint16(b[next:]), Class: binary.BigEndian.Uint16(b[next+2:])})
+		off = next + 4
+	}
+	for i, sec := range []*[]rr{&m.Answers, &m.Authority, &m.Additional} {
+		for j := 0; j < counts[i+1]; j++ {
+			r, next, err := readRR(b, off)
+			if err != nil {
+				return message{}, err
+			}
+			*sec = append(*sec, r)
+			off = next
+		}
+	}
+	return m, nil
+}
+
+func readRR(b []byte, off int) (rr, int, error) {
+	name, off, err := readName(b, off)
+	if err != nil {
+		return rr{}, 0, err
+	}
+	if off+10 > len(b) {
+		return rr{}, 0, errShort
+	}
+	r := rr{
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(b[off:]),
+		Class: binary.BigEndian.Uint16(b[off+2:]),
+		TTL:   binary.BigEndian.Uint32(b[off+4:]),
+	}
+	n := int(binary.BigEndian.Uint16(b[off+8:]))
+	off += 10
+	if off+n > len(b) {
+		return rr{}, 0, errShort
+	}
+	r.Data = b[off : off+n]
+	return r, off + n, nil
+}
+
+// readName reads the name at off, following compression pointers, and returns it with
+// the offset after it.
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for hops := 0; hops < 128; hops++ {
+		if off >= len(b) {
+			return "", 0, errShort
+		}
+		l := int(b[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), end, nil
+		case l&0xc0 == 0xc0:
+			if off+2 > len(b) {
+				return "", 0, errShort
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+		default:
+			if off+1+l > len(b) {
+				return "", 0, errShort
+			}
+			labels = append(labels, string(b[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+	return "", 0, errors.New("dns: name compression loop")
+}
+
+// tsigKey signs messages with a shared secret (TSIG, RFC 8945).
+type tsigKey struct {
+	Name      string
+	Algorithm string // e.g. hmac-sha256
+	Secret    []byte
+}
+
+var tsigHashes = map[string]func() hash.Hash{
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+	"hmac-sha1":   sha1.New,
+}
+
+// sign packs m with a TSIG record signed at now appended to the additional section.
+func (k tsigKey) sign(m message, now time.Time) ([]byte, error) {
+	raw, err := m.pack()
+	if err != nil {
+		return nil, err
+	}
+	mac, err := k.mac(raw, uint64(now.Unix()), tsigFudge)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := appendName(nil, k.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	data := appendTime(alg, uint64(now.Unix()))
+	data = binary.BigEndian.AppendUint16(data, tsigFudge)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(mac)))
+	data = append(data, mac...)
+	data = binary.BigEndian.AppendUint16(data, m.ID) // original ID
+	data = binary.BigEndian.AppendUint16(data, 0)    // error
+	data = binary.BigEndian.AppendUint16(data, 0)    // other len
+	m.Additional = append(m.Additional, rr{Name: strings.ToLower(k.Name), Type: typeTSIG, Class: classANY, Data: data})
+	return m.pack()
+}
+
+// mac is the HMAC over the message packed without its TSIG record followed by the TSIG variables.
+func (k tsigKey) mac(raw []byte, signed uint64, fudge uint16) ([]byte, error) {
+	newHash, ok := tsigHashes[k.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("dns: unsupported TSIG algorithm %q (use hmac-sha256, hmac-sha512 or hmac-sha1)", k.Algorithm)
+	}
+	vars, err := appendName(nil, strings.ToLower(k.Name))
+	if err != nil {
+		return nil, err
+	}
+	vars = binary.BigEndian.AppendUint16(vars, classANY)
+	vars = binary.BigEndian.AppendUint32(vars, 0)
+	if vars, err = appendName(vars, k.Algorithm); err != nil {
+		return nil, err
+	}
+	vars = appendTime(vars, signed)
+	vars = binary.BigEndian.AppendUint16(vars, fudge)
+	vars = binary.BigEndian.AppendUint16(vars, 0) // error
+	vars = binary.BigEndian.AppendUint16(vars, 0) // other len
+	h := hmac.New(newHash, k.Secret)
+	h.Write(raw)
+	h.Write(vars)
+	return h.Sum(nil), nil
+}
+
+// txtData encodes s as TXT record data: character strings of at most 255 bytes.
+func txtData(s string) []byte {
+	var b []byte
+	for {
+		n := min(len(s), 255)
+		b = append(append(b, byte(n)), s[:n]...)
+		if s = s[n:]; s == "" {
+			return b
+		}
+	}
+}
+
+// txtText joins the character strings of TXT record data.
+func txtText(b []byte) (string, error) {
+	var sb strings.Builder
+	for len(b) > 0 {
+		n := int(b[0])
+		if 1+n > len(b) {
+			return "", errShort
+		}
+		sb.Write(b[1 : 1+n])
+		b = b[1+n:]
+	}
+	return sb.String(), nil
+}
+
+// appendTime appends a 48-bit TSIG time.
+func appendTime(b []byte, t uint64) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(t>>32))
+	return binary.BigEndian.AppendUint32(b, uint32(t))
+}
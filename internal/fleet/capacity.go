@@ -0,0 +1,206 @@
+// internal/fleet/capacity.go
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/config"
+	"fleetctl/internal/state"
+)
+
+// capacitySplit counts on-demand and preemptible instances.
+type capacitySplit struct {
+	OnDemand    int
+	Preemptible int
+}
+
+// planPreemptible returns how many of add new instances should be preemptible so the
+// group converges on the policy: the first OnDemandBase instances are on-demand and
+// PreemptiblePercent of the remainder (rounded down) is preemptible.
+func planPreemptible(p *config.CapacityPolicy, cur capacitySplit, add int) int {
+	if p == nil || add <= 0 || p.PreemptiblePercent <= 0 {
+		return 0
+	}
+	pct := p.PreemptiblePercent
+	if pct > 100 {
+		pct = 100
+	}
+	total := cur.OnDemand + cur.Preemptible + add
+	above := total - p.OnDemandBase
+	if above <= 0 {
+		return 0
+	}
+	want := above * pct / 100
+	n := want - cur.Preemptible
+	if n < 0 {
+		n = 0
+	}
+	if n > add {
+		n = add
+	}
+	return n
+}
+
+// namePrefix is the display name prefix of the fleet's instances: displayNamePrefix,
+// default <fleet>-.
+func (f *Fleet) namePrefix() string {
+	if prefix := f.Config.Spec.DisplayNamePrefix; strings.TrimSpace(prefix) != "" {
+		return prefix
+	}
+	return f.Config.Metadata.Name + "-"
+}
+
+// groupFromName parses the group from a display name <prefix><group>-<timestamp>-<idx>.
+func (f *Fleet) groupFromName(displayName string) string {
+	prefix := f.namePrefix()
+	if strings.HasPrefix(displayName, prefix) {
+		rest := strings.TrimPrefix(displayName, prefix)
+		if idx := strings.Index(rest, "-"); idx > 0 {
+			return rest[:idx]
+		}
+	}
+	return "default"
+}
+
+// GroupCounts counts remote instances per group, parsed from their display names.
+func (f *Fleet) GroupCounts(instances []client.InstanceInfo) map[string]int {
+	out := map[string]int{}
+	for _, it := range instances {
+		out[f.groupFromName(it.DisplayName)]++
+	}
+	return out
+}
+
+// capacityByGroup tallies the on-demand/preemptible split of remote instances per group.
+func (f *Fleet) capacityByGroup(instances []client.InstanceInfo) map[string]capacitySplit {
+	out := map[string]capacitySplit{}
+	for _, it := range instances {
+		g := f.groupFromName(it.DisplayName)
+		cs := out[g]
+		if it.Capacity == client.CapacityPreemptible {
+			cs.Preemptible++
+		} else {
+			cs.OnDemand++
+		}
+		out[g] = cs
+	}
+	return out
+}
+
+// launchOne launches a single instance in group, falling back to on-demand when
+// preemptible capacity is unavailable. placements overrides the candidate order (nil = default).
+func (f *Fleet) launchOne(ctx context.Context, group string, preemptible bool, placements []client.Placement) (client.InstanceInfo, error) {
+	var policy *config.CapacityPolicy
+	if g := f.Config.Spec.Group(group); g != nil {
+		policy = g.Capacity
+	}
+	journal := f.journal()
+	opts := client.LaunchOptions{Preemptible: preemptible, PreserveBootVolume: policy.PreserveBootVolume(), Placements: placements, Journal: journal}
+	created, err := f.Client.LaunchInstances(ctx, f.Config, group, 1, opts)
+	if err != nil && preemptible && client.IsCapacityError(err) {
+		log.Printf("Launch: preemptible capacity unavailable for group %s (%v); falling back to on-demand", group, err)
+		created, err = f.Client.LaunchInstances(ctx, f.Config, group, 1, client.LaunchOptions{Placements: placements, Journal: journal})
+	}
+	if err != nil {
+		return client.InstanceInfo{}, err
+	}
+	if len(created) == 0 {
+		return client.InstanceInfo{}, fmt.Errorf("launch returned no instance")
+	}
+	return created[0], nil
+}
+
+// DetectReclaimed marks locally active preemptible instances that no longer exist in OCI
+// as preempted, so the control loop sees the shortfall and backfills them.
+func (f *Fleet) DetectReclaimed(ctx context.Context) ([]string, error) {
+	if f.Client == nil {
+		return nil, fmt.Errorf("OCI client not initialized")
+	}
+	f.opMu.Lock()
+	defer f.opMu.Unlock()
+	ctx, unlock, err := f.acquireLock(ctx, "detect-reclaimed")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	fleetName := f.Config.Metadata.Name
+	n, err := f.Store.CountActive(fleetName)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	recs, err := f.Store.ActiveRecordsFIFO(fleetName, n)
+	if err != nil {
+		return nil, err
+	}
+	insts, err := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, fleetName)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+	live := make(map[string]struct{}, len(insts))
+	for _, it := range insts {
+		live[it.ID] = struct{}{}
+	}
+	var reclaimed []string
+	for _, r := range recs {
+		if r.Capacity != client.CapacityPreemptible {
+			continue
+		}
+		if _, ok := live[r.ID]; !ok {
+			reclaimed = append(reclaimed, r.ID)
+		}
+	}
+	if len(reclaimed) == 0 {
+		return nil, nil
+	}
+	if err := f.Store.MarkPreemptedByIDs(fleetName, reclaimed); err != nil {
+		return nil, fmt.Errorf("mark preempted: %w", err)
+	}
+	log.Printf("DetectReclaimed: %d preemptible instance(s) reclaimed by OCI: %s", len(reclaimed), strings.Join(reclaimed, ", "))
+	return reclaimed, nil
+}
+
+// capacitySummary renders the per-group on-demand/preemptible split for status output.
+func (f *Fleet) capacitySummary(instances []client.InstanceInfo) string {
+	byGroup := f.capacityByGroup(instances)
+	groups := make([]string, 0, len(byGroup))
+	total := capacitySplit{}
+	for g, cs := range byGroup {
+		groups = append(groups, g)
+		total.OnDemand += cs.OnDemand
+		total.Preemptible += cs.Preemptible
+	}
+	sort.Strings(groups)
+	out := fmt.Sprintf("Capacity (remote): on-demand=%d preemptible=%d", total.OnDemand, total.Preemptible)
+	for _, g := range groups {
+		cs := byGroup[g]
+		out += fmt.Sprintf("\n  - %s: on-demand=%d preemptible=%d", g, cs.OnDemand, cs.Preemptible)
+	}
+	return out
+}
+
+// recordFor builds the state record for a launched or discovered instance.
+func recordFor(group string, inst client.InstanceInfo) state.InstanceRecord {
+	return state.InstanceRecord{
+		ID:        inst.ID,
+		Group:     group,
+		Name:      inst.DisplayName,
+		Capacity:  inst.Capacity,
+		CreatedAt: inst.TimeCreated,
+
+		AvailabilityDomain: inst.AvailabilityDomain,
+		FaultDomain:        inst.FaultDomain,
+		Shape:              inst.Shape,
+
+		PrivateIP:      inst.PrivateIP,
+		PublicIP:       inst.PublicIP,
+		ImageID:        inst.ImageID,
+		ConfigRevision: inst.ConfigRevision,
+		LaunchToken:    inst.LaunchToken,
+	}
+}
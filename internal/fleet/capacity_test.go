@@ -0,0 +1,36 @@
+// internal/fleet/capacity_test.go
+package fleet
+
+import (
+	"testing"
+
+	"fleetctl/internal/config"
+)
+
+func TestPlanPreemptible(t *testing.T) {
+	half := &config.CapacityPolicy{OnDemandBase: 2, PreemptiblePercent: 50}
+	all := &config.CapacityPolicy{PreemptiblePercent: 100}
+
+	tests := []struct {
+		name   string
+		policy *config.CapacityPolicy
+		cur    capacitySplit
+		add    int
+		want   int
+	}{
+		{"no policy", nil, capacitySplit{}, 4, 0},
+		{"zero percent", &config.CapacityPolicy{OnDemandBase: 1}, capacitySplit{}, 4, 0},
+		{"within base", half, capacitySplit{}, 2, 0},
+		{"above base from empty", half, capacitySplit{}, 6, 2},
+		{"above base from existing", half, capacitySplit{OnDemand: 2}, 4, 2},
+		{"already balanced", half, capacitySplit{OnDemand: 3, Preemptible: 1}, 1, 0},
+		{"backfill reclaimed", half, capacitySplit{OnDemand: 4}, 2, 2},
+		{"all preemptible", all, capacitySplit{Preemptible: 1}, 3, 3},
+		{"clamped to add", all, capacitySplit{OnDemand: 5}, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := planPreemptible(tt.policy, tt.cur, tt.add); got != tt.want {
+			t.Fatalf("%s: planPreemptible = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
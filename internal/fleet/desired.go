@@ -0,0 +1,170 @@
+// internal/fleet/desired.go
+package fleet
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fleetctl/internal/config"
+	"fleetctl/internal/state"
+)
+
+// Sources of the fleet's desired size.
+const (
+	DesiredFromConfig   = "config"
+	DesiredFromOverride = "override"
+)
+
+// DesiredState is the fleet size the control loop converges on: the config baseline,
+// or an unexpired operator override persisted in state.
+type DesiredState struct {
+	Baseline int                    `json:"baseline"` // sum of spec.instances[].count
+	Target   int                    `json:"target"`
+	Source   string                 `json:"source"` // DesiredFromConfig or DesiredFromOverride
+	Override *state.DesiredOverride `json:"override,omitempty"`
+	Groups   map[string]int         `json:"groups"` // Target split per instance group, see splitDesired
+}
+
+// Baseline returns the fleet size configured by spec.instances[].count.
+func (f *Fleet) Baseline() int {
+	n := 0
+	for _, g := range f.Config.Spec.Instances {
+		n += g.Count
+	}
+	return n
+}
+
+// Desired resolves the current desired size. An expired override is cleared and recorded
+// in history, after which the config baseline applies again. When another process
+// replaced the override in the meantime, the new one is resolved instead.
+func (f *Fleet) Desired() (DesiredState, error) {
+	fleetName := f.Config.Metadata.Name
+	ds := DesiredState{Baseline: f.Baseline(), Target: f.Baseline(), Source: DesiredFromConfig}
+	ds.Groups = splitDesired(f.Config.Spec.Instances, ds.Target)
+	o, ok, err := f.Store.DesiredOverride(fleetName)
+	if err != nil {
+		return ds, fmt.Errorf("read desired override: %w", err)
+	}
+	if !ok {
+		return ds, nil
+	}
+	if o.Expired(time.Now()) {
+		cleared, err := f.Store.ClearDesiredOverrideIf(fleetName, o)
+		if err != nil {
+			return ds, fmt.Errorf("clear expired desired override: %w", err)
+		}
+		if !cleared {
+			return f.Desired()
+		}
+		log.Printf("Desired: override %d (set by %s) expired; back to config baseline %d", o.Desired, o.Source, ds.Baseline)
+		f.recordHistory("desired-expired", OpOptions{Source: o.Source}, 0, fmt.Sprintf("override %d expired; baseline %d", o.Desired, ds.Baseline), nil)
+		return ds, nil
+	}
+	ds.Target = o.Desired
+	ds.Source = DesiredFromOverride
+	ds.Override = &o
+	ds.Groups = splitDesired(f.Config.Spec.Instances, ds.Target)
+	return ds, nil
+}
+
+// splitDesired splits target over the instance groups: each group keeps its count and
+// the difference to the baseline lands on the first group, where scale-up launches.
+// A target below the baseline is taken from the groups in order. Without groups the
+// whole target belongs to group default.
+func splitDesired(groups []config.InstanceSpec, target int) map[string]int {
+	out := map[string]int{}
+	if len(groups) == 0 || groups[0].Name == "" {
+		out["default"] = max(target, 0)
+		return out
+	}
+	baseline := 0
+	for _, g := range groups {
+		out[g.Name] += g.Count
+		baseline += g.Count
+	}
+	if target >= baseline {
+		out[groups[0].Name] += target - baseline
+		return out
+	}
+	cut := baseline - max(target, 0)
+	for _, g := range groups {
+		n := min(cut, out[g.Name])
+		out[g.Name] -= n
+		cut -= n
+	}
+	return out
+}
+
+// SetDesired persists desired as an override of the config baseline for ttl
+// (spec.scaling.overrideTTL when ttl is zero), so the control loop keeps it.
+func (f *Fleet) SetDesired(desired int, ttl time.Duration, opts OpOptions) (state.DesiredOverride, error) {
+	if desired < 0 {
+		return state.DesiredOverride{}, fmt.Errorf("desired must be >= 0")
+	}
+	if ttl <= 0 {
+		ttl = f.Config.Spec.Scaling.DesiredTTL()
+	}
+	now := time.Now()
+	o := state.DesiredOverride{Desired: desired, Source: opts.Source, SetAt: now, ExpiresAt: now.Add(ttl)}
+	if err := f.Store.SetDesiredOverride(f.Config.Metadata.Name, o); err != nil {
+		return o, fmt.Errorf("store desired override: %w", err)
+	}
+	f.recordHistory("desired-set", opts, 0, fmt.Sprintf("desired %d until %s", desired, o.ExpiresAt.Format(time.RFC3339)), nil)
+	return o, nil
+}
+
+// ClearDesired drops the desired override; the control loop returns to the config baseline.
+func (f *Fleet) ClearDesired(opts OpOptions) error {
+	if err := f.Store.ClearDesiredOverride(f.Config.Metadata.Name); err != nil {
+		return fmt.Errorf("clear desired override: %w", err)
+	}
+	f.recordHistory("desired-cleared", opts, 0, fmt.Sprintf("back to config baseline %d", f.Baseline()), nil)
+	return nil
+}
+
+// Stabilizer adds hysteresis to the control loop: a difference between target and actual
+// must persist for the stabilization window of its direction before it is acted on.
+// While a scale-down is pending, the highest target seen is used, so a brief dip in the
+// desired size does not remove instances that are wanted again moments later.
+type Stabilizer struct {
+	dir   int       // +1 scale-up pending, -1 scale-down pending, 0 none
+	since time.Time // when the pending direction was first observed
+	peak  int       // target to act on once the window has passed
+}
+
+// Recommend returns the size to scale to now (actual when nothing should happen yet) and,
+// while a change is held back, how long until the window passes.
+func (s *Stabilizer) Recommend(now time.Time, actual, target int, up, down time.Duration) (int, time.Duration) {
+	if target == actual {
+		*s = Stabilizer{}
+		return actual, 0
+	}
+	dir, window := 1, up
+	if target < actual {
+		dir, window = -1, down
+	}
+	switch {
+	case dir != s.dir:
+		*s = Stabilizer{dir: dir, since: now, peak: target}
+	case dir < 0 && target > s.peak:
+		s.peak = target
+	case dir > 0:
+		s.peak = target
+	}
+	if elapsed := now.Sub(s.since); elapsed < window {
+		return actual, window - elapsed
+	}
+	return s.peak, 0
+}
+
+// Pending reports the direction of a held-back change: "up", "down" or "".
+func (s *Stabilizer) Pending() string {
+	switch s.dir {
+	case 1:
+		return "up"
+	case -1:
+		return "down"
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+// internal/fleet/desired_test.go
+package fleet
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+func TestStabilizerRecommend(t *testing.T) {
+	t0 := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	up, down := time.Minute, 5*time.Minute
+	type step struct {
+		at             time.Duration
+		actual, target int
+		want           int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"in sync", []step{{0, 3, 3, 3}}},
+		{"scale-up after window", []step{{0, 3, 5, 3}, {30 * time.Second, 3, 6, 3}, {time.Minute, 3, 6, 6}}},
+		{"scale-down waits for window", []step{{0, 5, 3, 5}, {4 * time.Minute, 5, 3, 5}, {5 * time.Minute, 5, 3, 3}}},
+		{"scale-down uses highest target seen", []step{{0, 5, 2, 5}, {time.Minute, 5, 4, 5}, {2 * time.Minute, 5, 3, 5}, {5 * time.Minute, 5, 3, 4}}},
+		{"direction change restarts window", []step{{0, 5, 3, 5}, {4 * time.Minute, 5, 6, 5}, {5 * time.Minute, 5, 3, 5}, {9 * time.Minute, 5, 3, 5}, {10 * time.Minute, 5, 3, 3}}},
+		{"back in sync resets", []step{{0, 5, 3, 5}, {4 * time.Minute, 5, 5, 5}, {5 * time.Minute, 5, 3, 5}}},
+	}
+	for _, tt := range tests {
+		var s Stabilizer
+		for i, st := range tt.steps {
+			got, _ := s.Recommend(t0.Add(st.at), st.actual, st.target, up, down)
+			if got != st.want {
+				t.Fatalf("%s: step %d: Recommend = %d, want %d", tt.name, i, got, st.want)
+			}
+		}
+	}
+}
+
+func TestStabilizerZeroWindowActsImmediately(t *testing.T) {
+	var s Stabilizer
+	got, wait := s.Recommend(time.Now(), 4, 2, 0, 0)
+	if got != 2 || wait != 0 {
+		t.Fatalf("Recommend = %d, %s; want 2, 0s", got, wait)
+	}
+}
+
+func TestSplitDesired(t *testing.T) {
+	groups := []config.InstanceSpec{{Name: "web", Count: 3}, {Name: "worker", Count: 2}}
+	tests := []struct {
+		groups []config.InstanceSpec
+		target int
+		want   map[string]int
+	}{
+		{groups, 5, map[string]int{"web": 3, "worker": 2}},
+		{groups, 8, map[string]int{"web": 6, "worker": 2}},
+		{groups, 4, map[string]int{"web": 2, "worker": 2}},
+		{groups, 1, map[string]int{"web": 0, "worker": 1}},
+		{groups, 0, map[string]int{"web": 0, "worker": 0}},
+		{nil, 4, map[string]int{"default": 4}},
+	}
+	for _, tt := range tests {
+		if got := splitDesired(tt.groups, tt.target); !maps.Equal(got, tt.want) {
+			t.Fatalf("splitDesired(%v, %d) = %v, want %v", tt.groups, tt.target, got, tt.want)
+		}
+	}
+}
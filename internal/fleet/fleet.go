@@ -3,6 +3,7 @@ package fleet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -12,29 +13,45 @@ import (
 	"fleetctl/internal/client"
 	"fleetctl/internal/config"
 	"fleetctl/internal/lb"
+	"fleetctl/internal/lock"
 	"fleetctl/internal/metrics"
+	"fleetctl/internal/registrar"
 	"fleetctl/internal/state"
 )
 
 // Fleet holds the current state and configuration of the fleet
 type Fleet struct {
-	Config config.FleetConfig
-	Client *client.Client
-	Store  *state.Store
-	opMu   sync.Mutex
+	Config  config.FleetConfig
+	Client  *client.Client
+	Store   *state.Store
+	Lock    lock.Locker      // optional cross-process lock; nil disables locking
+	Metrics *metrics.Metrics // operation progress and totals of this fleet
+	opMu    sync.Mutex
+	warm    warmFill // warm pool launches running in the background
 }
 
-// New creates a new Fleet instance
-func New(cfg config.FleetConfig, c *client.Client, s *state.Store) *Fleet {
+// New creates a new Fleet instance. m collects its operation metrics; nil gives the
+// fleet metrics of its own.
+func New(cfg config.FleetConfig, c *client.Client, s *state.Store, m *metrics.Metrics) *Fleet {
+	if m == nil {
+		m = metrics.New()
+	}
 	return &Fleet{
-		Config: cfg,
-		Client: c,
-		Store:  s,
+		Config:  cfg,
+		Client:  c,
+		Store:   s,
+		Metrics: m,
 	}
 }
 
 // Scale scales the fleet to the desired total number of instances using OCI
 func (f *Fleet) Scale(desiredTotal int) error {
+	return f.ScaleWithOptions(desiredTotal, OpOptions{})
+}
+
+// ScaleWithOptions is Scale with caller context. Scale-downs are subject to maintenance
+// windows (unless opts.OverrideWindow is set) and the disruption budget.
+func (f *Fleet) ScaleWithOptions(desiredTotal int, opts OpOptions) error {
 	if desiredTotal < 0 {
 		return fmt.Errorf("desiredTotal must be >= 0")
 	}
@@ -46,11 +63,21 @@ func (f *Fleet) Scale(desiredTotal int) error {
 	defer f.opMu.Unlock()
 
 	// dequeue only if this desired is at the head of the queue (FIFO)
-	metrics.PopScaleQueueIfHead(desiredTotal)
+	f.Metrics.PopScaleQueueIfHead(desiredTotal)
+
+	ctx, unlock, err := f.acquireLock(context.Background(), "scale")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	ctx := context.Background()
 	fleetName := f.Config.Metadata.Name
 
+	// Settle launches a crashed run left half done before counting what exists.
+	if _, err := f.recoverLaunches(ctx); err != nil {
+		log.Printf("Scale: warning: recover pending launches: %v", err)
+	}
+
 	current, err := f.Store.CountActive(fleetName)
 	if err != nil {
 		return fmt.Errorf("reading state: %w", err)
@@ -58,9 +85,11 @@ func (f *Fleet) Scale(desiredTotal int) error {
 
 	// Determine remote actual count to avoid relying solely on local state.
 	remoteCurrent := current
+	var remoteInst []client.InstanceInfo
 	if f.Client != nil {
 		if inst, err := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, fleetName); err == nil {
 			remoteCurrent = len(inst)
+			remoteInst = inst
 		} else {
 			log.Printf("Scale: warning: could not list remote instances: %v (falling back to local state)", err)
 		}
@@ -81,12 +110,35 @@ func (f *Fleet) Scale(desiredTotal int) error {
 		// Scale up: launch missing instances in OCI (parallel with bounded concurrency)
 		missing := desiredTotal - remoteCurrent
 
-		metrics.Reset("scale-up")
-		metrics.SetScaleTargets(remoteCurrent, desiredTotal)
-		metrics.SetPhase("launch")
-		metrics.IncLaunchRequested(missing)
+		f.Metrics.Reset("scale-up")
+		f.Metrics.SetScaleTargets(remoteCurrent, desiredTotal)
+		f.Metrics.SetPhase("launch")
+		f.Metrics.IncLaunchRequested(missing)
 		newInstances := make([]client.InstanceInfo, 0, missing)
 
+		// Warm pool first: START stopped instances and put them behind the LB before
+		// falling back to full launches for the remainder.
+		warmStarted := 0
+		if warm := f.startWarm(ctx, group, missing); len(warm) > 0 {
+			warmStarted = len(warm)
+			err := f.Store.Batch(func() error {
+				for _, inst := range warm {
+					if err := f.Store.AddInstanceRecord(fleetName, recordFor(group, inst)); err != nil {
+						return fmt.Errorf("record instance %s: %w", inst.ID, err)
+					}
+					f.Metrics.IncLaunchSucceeded()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			f.registerBackends(ctx, warm)
+			remoteInst = append(remoteInst, warm...)
+			missing -= len(warm)
+			log.Printf("Scale: started %d warm pool instance(s); %d left to launch", len(warm), missing)
+		}
+
 		type launchRes struct {
 			inst client.InstanceInfo
 			err  error
@@ -100,22 +152,36 @@ func (f *Fleet) Scale(desiredTotal int) error {
 		}
 		sem := make(chan struct{}, parLaunch)
 
+		// Capacity policy: decide how many of the new instances are preemptible
+		var policy *config.CapacityPolicy
+		if g := f.Config.Spec.Group(group); g != nil {
+			policy = g.Capacity
+		}
+		nPreempt := planPreemptible(policy, f.capacityByGroup(remoteInst)[group], missing)
+		if nPreempt > 0 {
+			log.Printf("Scale: launching %d preemptible and %d on-demand instances in group %s", nPreempt, missing-nPreempt, group)
+		}
+		// Placement: with spread, each launch prefers the least crowded domain
+		plans, err := f.launchPlans(ctx, group, remoteInst, missing)
+		if err != nil {
+			f.Metrics.SetError(err.Error())
+			return err
+		}
+
 		for i := 0; i < missing; i++ {
+			preemptible := i < nPreempt
+			placements := plans[i]
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
 				sem <- struct{}{}
 				defer func() { <-sem }()
-				created, err := f.Client.LaunchInstances(ctx, f.Config, group, 1)
+				inst, err := f.launchOne(ctx, group, preemptible, placements)
 				if err != nil {
 					resCh <- launchRes{err: err}
 					return
 				}
-				if len(created) == 0 {
-					resCh <- launchRes{err: fmt.Errorf("launch returned no instance")}
-					return
-				}
-				resCh <- launchRes{inst: created[0]}
+				resCh <- launchRes{inst: inst}
 			}()
 		}
 
@@ -123,138 +189,93 @@ func (f *Fleet) Scale(desiredTotal int) error {
 		close(resCh)
 
 		count := 0
-		for r := range resCh {
-			if r.err != nil {
-				metrics.IncLaunchFailed(r.err.Error())
-				return fmt.Errorf("launch OCI instances: %w", r.err)
-			}
-			if err := f.Store.AddActiveRecord(fleetName, group, r.inst.ID, r.inst.DisplayName); err != nil {
-				return fmt.Errorf("record instance %s: %w", r.inst.ID, err)
+		// One state write for the whole wave rather than one per instance.
+		err = f.Store.Batch(func() error {
+			for r := range resCh {
+				if r.err != nil {
+					f.Metrics.IncLaunchFailed(r.err.Error())
+					return fmt.Errorf("launch OCI instances: %w", r.err)
+				}
+				if err := f.Store.AddInstanceRecord(fleetName, recordFor(group, r.inst)); err != nil {
+					return fmt.Errorf("record instance %s: %w", r.inst.ID, err)
+				}
+				newInstances = append(newInstances, r.inst)
+				f.Metrics.IncLaunchSucceeded()
+				count++
 			}
-			newInstances = append(newInstances, r.inst)
-			metrics.IncLaunchSucceeded()
-			count++
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		log.Printf("Scale: launched %d instances to reach %d", count, desiredTotal)
+		f.recordHistory("scale-up", opts, 0, fmt.Sprintf("%d -> %d (%d started from warm pool, %d launched)", remoteCurrent, desiredTotal, warmStarted, count), nil)
 
 		// If LB enabled, ensure it exists and register new instances as backends
-		if f.Config.Spec.LoadBalancer.Enabled && f.Client != nil {
-			spec := f.Config.Spec.LoadBalancer
-			lbs := lb.New(f.Client.Provider, f.Client.Region)
-			lbID, bsName, lsn, err := lbs.Ensure(ctx, f.Config)
-			if err != nil {
-				log.Printf("LB ensure failed: %v", err)
-			} else {
-				for _, inst := range newInstances {
-					ip, ierr := f.Client.InstancePrimaryPrivateIP(ctx, f.Config.Spec.CompartmentID, inst.ID)
-					if ierr != nil {
-						log.Printf("LB resolve IP for %s: %v", inst.ID, ierr)
-						continue
-					}
-					if err := lbs.AddBackend(ctx, lbID, bsName, ip, spec.BackendPort); err != nil {
-						log.Printf("LB add backend %s:%d: %v", ip, spec.BackendPort, err)
-					}
-				}
-				if n, cerr := lbs.CountBackends(ctx, lbID, bsName); cerr == nil {
-					metrics.UpdateLB(true, lbID, n)
-					if f.Store != nil {
-						_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-						_ = f.Store.SetLBBackendsCount(fleetName, n)
-					}
-				} else {
-					metrics.UpdateLB(true, lbID, 0)
-					if f.Store != nil {
-						_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-						_ = f.Store.SetLBBackendsCount(fleetName, 0)
-					}
-				}
-			}
-		}
+		f.registerBackends(ctx, newInstances)
 
-		metrics.SetPhase("verify")
+		f.Metrics.SetPhase("verify")
 		if err := f.verifyActualMatches(ctx, desiredTotal); err != nil {
-			metrics.SetError(err.Error())
+			f.Metrics.SetError(err.Error())
 			return err
 		}
-		if err := f.SyncState(); err != nil {
+		if err := f.syncState(ctx); err != nil {
 			return fmt.Errorf("sync state after scale up: %w", err)
 		}
 		// Post-scale LB reconcile to ensure metrics reflect actual backend count
-		if err := f.ReconcileLoadBalancer(ctx); err != nil {
+		if err := f.reconcileBackends(ctx); err != nil {
 			log.Printf("post-scale LB reconcile (up): %v", err)
 		}
-		metrics.Done()
+		f.Metrics.Done()
 		return nil
 	}
 
-	// Scale down: terminate excess instances in OCI (FIFO from local state; remove oldest first)
+	// Scale down: terminate excess instances in OCI (FIFO from local state; remove oldest first).
+	// With spread placement, the oldest instance of the most crowded domain goes first.
 	toRemove := current - desiredTotal
-	recs, err := f.Store.ActiveRecordsFIFO(fleetName, toRemove)
-	if err != nil {
-		return fmt.Errorf("select instances to remove: %w", err)
+	if toRemove > 0 {
+		if err := f.checkDisruption("scale-down", toRemove, opts); err != nil {
+			return err
+		}
+	}
+	var recs []state.InstanceRecord
+	if f.Config.Spec.Spread() {
+		all, err := f.Store.ActiveRecordsFIFO(fleetName, current)
+		if err != nil {
+			return fmt.Errorf("select instances to remove: %w", err)
+		}
+		live := make(map[string]client.InstanceInfo, len(remoteInst))
+		for _, it := range remoteInst {
+			live[it.ID] = it
+		}
+		recs = pickScaleIn(all, toRemove, recordDomain(live))
+	} else {
+		recs, err = f.Store.ActiveRecordsFIFO(fleetName, toRemove)
+		if err != nil {
+			return fmt.Errorf("select instances to remove: %w", err)
+		}
 	}
 	ids := make([]string, 0, len(recs))
 	for _, r := range recs {
 		ids = append(ids, r.ID)
 	}
-	// Terminate instances in parallel with bounded concurrency, then mark terminated
-	// If LB enabled, deregister targets before terminating instances
-	if f.Config.Spec.LoadBalancer.Enabled && f.Client != nil && len(ids) > 0 {
-		spec := f.Config.Spec.LoadBalancer
-		lbs := lb.New(f.Client.Provider, f.Client.Region)
-		if lbID, bsName, lsn, err := lbs.Ensure(ctx, f.Config); err != nil {
-			log.Printf("LB ensure failed (scale-down): %v", err)
-		} else {
-			// Optimistically update LB metrics to reflect immediate backend removals
-			snap := metrics.Snapshot()
-			curr := 0
-			if v, ok := snap["lbBackends"].(int); ok {
-				curr = v
-			} else if df, ok := snap["lbBackends"].(float64); ok {
-				curr = int(df)
-			}
-			for _, id := range ids {
-				// optimistic decrement before initiating removal
-				if curr > 0 {
-					curr--
-				}
-				metrics.UpdateLB(true, lbID, curr)
-				if f.Store != nil {
-					_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-					_ = f.Store.SetLBBackendsCount(fleetName, curr)
-				}
-
-				ip, ierr := f.Client.InstancePrimaryPrivateIP(ctx, f.Config.Spec.CompartmentID, id)
-				if ierr != nil {
-					log.Printf("LB resolve IP for %s: %v", id, ierr)
-					continue
-				}
-				if err := lbs.RemoveBackend(ctx, lbID, bsName, ip, spec.BackendPort); err != nil {
-					log.Printf("LB remove backend %s:%d: %v", ip, spec.BackendPort, err)
-				}
-			}
-			// After removals, refresh authoritative count
-			if n, cerr := lbs.CountBackends(ctx, lbID, bsName); cerr == nil {
-				metrics.UpdateLB(true, lbID, n)
-				if f.Store != nil {
-					_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-					_ = f.Store.SetLBBackendsCount(fleetName, n)
-				}
-			} else {
-				metrics.UpdateLB(true, lbID, 0)
-				if f.Store != nil {
-					_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-					_ = f.Store.SetLBBackendsCount(fleetName, 0)
-				}
-			}
+	// Terminate instances in parallel with bounded concurrency, then mark terminated.
+	// Deregister them from the LB and other registrars first.
+	if len(ids) > 0 {
+		regs, err := f.registrars(ctx)
+		if err != nil {
+			log.Printf("Scale: registrars (scale-down): %v", err)
+		}
+		if len(regs) > 0 {
+			f.announce(ctx, regs, nil, f.recordMembers(ctx, recs))
 		}
 	}
 
-	metrics.Reset("scale-down")
-	metrics.SetScaleTargets(remoteCurrent, desiredTotal)
-	metrics.SetPhase("terminate")
-	metrics.IncTerminateRequested(len(ids))
+	f.Metrics.Reset("scale-down")
+	f.Metrics.SetScaleTargets(remoteCurrent, desiredTotal)
+	f.Metrics.SetPhase("terminate")
+	f.Metrics.IncTerminateRequested(len(ids))
 
 	var twg sync.WaitGroup
 	terrCh := make(chan error, len(ids))
@@ -273,11 +294,11 @@ func (f *Fleet) Scale(desiredTotal int) error {
 			tsem <- struct{}{}
 			defer func() { <-tsem }()
 			if err := f.Client.TerminateInstances(ctx, []string{id}); err != nil {
-				metrics.IncTerminateFailed(err.Error())
+				f.Metrics.IncTerminateFailed(err.Error())
 				terrCh <- fmt.Errorf("terminate %s: %w", id, err)
 				return
 			}
-			metrics.IncTerminateSucceeded()
+			f.Metrics.IncTerminateSucceeded()
 		}()
 	}
 
@@ -285,31 +306,160 @@ func (f *Fleet) Scale(desiredTotal int) error {
 	close(terrCh)
 	for e := range terrCh {
 		if e != nil {
-			return fmt.Errorf("terminate instances: %w", e)
+			err := fmt.Errorf("terminate instances: %w", e)
+			f.recordHistory("scale-down", opts, len(ids), fmt.Sprintf("%d -> %d", remoteCurrent, desiredTotal), err)
+			return err
 		}
 	}
+	f.recordHistory("scale-down", opts, len(ids), fmt.Sprintf("%d -> %d (%d terminated)", remoteCurrent, desiredTotal, len(ids)), nil)
 
 	if err := f.Store.MarkTerminatedByIDs(fleetName, ids); err != nil {
 		return fmt.Errorf("update state: %w", err)
 	}
 	log.Printf("Scale: terminated %d instances to reach %d", len(ids), desiredTotal)
-	metrics.SetPhase("verify")
+	f.Metrics.SetPhase("verify")
 	if err := f.verifyActualMatches(ctx, desiredTotal); err != nil {
-		metrics.SetError(err.Error())
+		f.Metrics.SetError(err.Error())
 		return err
 	}
-	if err := f.SyncState(); err != nil {
+	if err := f.syncState(ctx); err != nil {
 		return fmt.Errorf("sync state after scale down: %w", err)
 	}
 	// Post-scale LB reconcile to ensure metrics reflect actual backend count
-	if err := f.ReconcileLoadBalancer(ctx); err != nil {
+	if err := f.reconcileBackends(ctx); err != nil {
 		log.Printf("post-scale LB reconcile (down): %v", err)
 	}
-	metrics.Done()
+	f.Metrics.Done()
 	return nil
 }
 
-// SyncState queries OCI for instances tagged to this fleet and rebuilds local state.
+// registerBackends announces insts to the fleet's registrars (see registrars).
+func (f *Fleet) registerBackends(ctx context.Context, insts []client.InstanceInfo) {
+	if len(insts) == 0 {
+		return
+	}
+	regs, err := f.registrars(ctx)
+	if err != nil {
+		log.Printf("registrars: %v", err)
+	}
+	if len(regs) > 0 {
+		f.announce(ctx, regs, f.instanceMembers(ctx, insts), nil)
+	}
+}
+
+// applyLB applies backend changes in as few work requests as possible (see
+// lb.Balancer.ApplyChanges) and counts the work requests in metrics.
+func (f *Fleet) applyLB(ctx context.Context, lbs lb.Balancer, topo lb.Topology, changes []lb.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	res, err := lbs.ApplyChanges(ctx, topo.ID, changes)
+	if err != nil {
+		log.Printf("LB backend changes: %v", err)
+	}
+	f.Metrics.AddLBWorkRequests(res.Requests, res.Saved, res.Fallbacks)
+}
+
+// ensureLB opens the load balancer of spec.loadBalancer.type, brings it in line with the
+// spec and publishes the settings that need it replaced in metrics.
+func (f *Fleet) ensureLB(ctx context.Context) (lb.Balancer, lb.Topology, error) {
+	lbs, err := lb.Open(f.Client.Provider, f.Client.Region, f.Config.Spec.LoadBalancer)
+	if err != nil {
+		return nil, lb.Topology{}, err
+	}
+	topo, err := lbs.Ensure(ctx, f.Config, f.lbIdentity())
+	if err == nil {
+		f.Metrics.SetLBNeedsReplacement(topo.NeedsReplacement)
+	}
+	return lbs, topo, err
+}
+
+// lbIdentity is how Ensure finds the fleet's load balancer: the OCID recorded in state
+// and the fleet UID (metadata.uid, else the one generated and kept in state).
+func (f *Fleet) lbIdentity() lb.Identity {
+	who := lb.Identity{UID: f.Config.Metadata.UID}
+	if f.Store == nil {
+		return who
+	}
+	name := f.Config.Metadata.Name
+	if who.UID == "" {
+		uid, err := f.Store.FleetUID(name)
+		if err != nil {
+			log.Printf("LB: fleet UID: %v; matching the load balancer by fleet tag and name", err)
+		}
+		who.UID = uid
+	}
+	if st, ok, _ := f.Store.GetLBInfo(name); ok {
+		who.ID = st.ID
+	}
+	return who
+}
+
+// lbTarget returns the backend set instances of group are registered in. Groups no
+// backend set takes are logged and left out of the load balancer.
+func lbTarget(topo lb.Topology, group string) (lb.Target, bool) {
+	tg, ok := topo.Target(group)
+	if !ok {
+		log.Printf("LB: no backend set takes instance group %q; not registered", group)
+	}
+	return tg, ok
+}
+
+// refreshLBCount records the backend count across the fleet's backend sets in metrics and state.
+func (f *Fleet) refreshLBCount(ctx context.Context, lbs lb.Balancer, topo lb.Topology) {
+	n, err := lbs.CountAllBackends(ctx, topo)
+	if err != nil {
+		log.Printf("LB count backends: %v", err)
+		n = 0
+	}
+	f.Metrics.UpdateLB(true, topo.ID, n)
+	if f.Store != nil {
+		f.recordLB(topo, n)
+	}
+}
+
+// acquireLock takes the cross-process fleet lock for op and returns the context the
+// operation runs under with the lock's release func. When the lock can be lost (an OCI
+// lease taken over by another host) the context is cancelled with lock.ErrLost, so the
+// operation stops instead of racing the new holder. Callers must already hold opMu; the
+// lock is a no-op when f.Lock is nil.
+func (f *Fleet) acquireLock(ctx context.Context, op string) (context.Context, func(), error) {
+	if f.Lock == nil {
+		return ctx, func() {}, nil
+	}
+	if err := f.Lock.Acquire(ctx, op); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	if w, ok := f.Lock.(lock.LossWatcher); ok && w.Lost() != nil {
+		lost := w.Lost()
+		go func() {
+			select {
+			case <-lost:
+				log.Printf("%s: fleet lock lost; stopping", op)
+				cancel(lock.ErrLost)
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, func() {
+		cancel(nil)
+		if err := f.Lock.Release(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("%s: release fleet lock: %v", op, err)
+		}
+	}, nil
+}
+
+// LockStatus reports the cross-process lock state; ok is false when locking is disabled.
+func (f *Fleet) LockStatus(ctx context.Context) (lock.Status, bool, error) {
+	if f.Lock == nil {
+		return lock.Status{}, false, nil
+	}
+	st, err := f.Lock.Status(ctx)
+	return st, true, err
+}
+
+// verifyActualMatches polls OCI until the fleet's instance count equals desired.
 func (f *Fleet) verifyActualMatches(ctx context.Context, desired int) error {
 	if f.Client == nil {
 		return fmt.Errorf("OCI client not initialized")
@@ -333,11 +483,30 @@ func (f *Fleet) verifyActualMatches(ctx context.Context, desired int) error {
 	}
 }
 
+// SyncState queries OCI for instances tagged to this fleet and rebuilds local state.
 func (f *Fleet) SyncState() error {
 	if f.Client == nil {
 		return fmt.Errorf("OCI client not initialized")
 	}
-	ctx := context.Background()
+	f.opMu.Lock()
+	defer f.opMu.Unlock()
+	ctx, unlock, err := f.acquireLock(context.Background(), "sync-state")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// Only requested rebuilds are snapshotted; the resync after every scale is routine.
+	if _, err := f.Store.SnapshotBefore("sync-state"); err != nil {
+		return err
+	}
+	return f.syncState(ctx)
+}
+
+// syncState rebuilds local state from OCI; callers hold opMu and the fleet lock.
+func (f *Fleet) syncState(ctx context.Context) error {
+	if f.Client == nil {
+		return fmt.Errorf("OCI client not initialized")
+	}
 	fleetName := f.Config.Metadata.Name
 
 	instances, err := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, fleetName)
@@ -345,30 +514,25 @@ func (f *Fleet) SyncState() error {
 		return fmt.Errorf("list fleet instances: %w", err)
 	}
 
-	now := time.Now()
+	// Listings carry no addresses: keep the ones already recorded and look up the rest.
+	prev := map[string]state.InstanceRecord{}
+	if recs, err := f.Store.ActiveRecords(fleetName); err == nil {
+		for _, r := range recs {
+			prev[r.ID] = r
+		}
+	}
 	records := make([]state.InstanceRecord, 0, len(instances))
 	for _, it := range instances {
 		// Best-effort group parsing from display name: <fleet>-<group>-<timestamp>-<idx>
-		group := "default"
-		prefix := f.Config.Spec.DisplayNamePrefix
-		if strings.TrimSpace(prefix) == "" {
-			prefix = f.Config.Metadata.Name + "-"
-		}
-		if strings.HasPrefix(it.DisplayName, prefix) {
-			rest := strings.TrimPrefix(it.DisplayName, prefix)
-			if idx := strings.Index(rest, "-"); idx > 0 {
-				group = rest[:idx]
+		rec := mergeRecord(recordFor(f.groupFromName(it.DisplayName), it), prev[it.ID])
+		if rec.PrivateIP == "" {
+			if addr, err := f.Client.InstanceAddresses(ctx, f.Config.Spec.CompartmentID, it.ID); err == nil {
+				rec.PrivateIP, rec.PublicIP = addr.PrivateIP, addr.PublicIP
+			} else {
+				log.Printf("SyncState: resolve addresses of %s: %v", it.ID, err)
 			}
 		}
-
-		records = append(records, state.InstanceRecord{
-			ID:        it.ID,
-			Group:     group,
-			Name:      it.DisplayName,
-			Status:    state.StatusActive,
-			CreatedAt: now, // unknown; set to now for reconstruction
-			UpdatedAt: now,
-		})
+		records = append(records, rec)
 	}
 
 	if err := f.Store.ResetFleetActive(fleetName, records); err != nil {
@@ -414,6 +578,15 @@ func (f *Fleet) StatusCompare() (string, error) {
 	} else {
 		out += "\n\nLocal and actual counts match."
 	}
+	if recs, err := f.Store.ActiveRecords(fleetName); err == nil {
+		out += "\n\n" + f.instancesSummary(recs, time.Now())
+	}
+	out += "\n\n" + f.capacitySummary(actual)
+	out += "\n\n" + f.placementSummary(actual)
+	if wp := f.warmPoolSummary(ctx); wp != "" {
+		out += "\n\n" + wp
+	}
+	out += "\n\n" + f.maintenanceSummary()
 
 	// Append Load Balancer snapshot from local state (if available)
 	if f.Store != nil {
@@ -424,22 +597,57 @@ func (f *Fleet) StatusCompare() (string, error) {
 			out += fmt.Sprintf("\n  BackendSet: %s", lb.BackendSet)
 			out += fmt.Sprintf("\n  Listener: %s", lb.Listener)
 			out += fmt.Sprintf("\n  Backends: %d", lb.BackendsCount)
+			if len(lb.NeedsReplacement) > 0 {
+				out += fmt.Sprintf("\n  Needs replacement: %s (delete the LB to have it recreated)", strings.Join(lb.NeedsReplacement, "; "))
+			}
 			out += fmt.Sprintf("\n  UpdatedAt: %s", lb.UpdatedAt.Format(time.RFC3339))
 		} else {
 			out += "\n\nLoad Balancer: (no snapshot)"
 		}
 	}
+
+	// Append cross-process lock state (if locking is configured)
+	if ls, ok, err := f.LockStatus(ctx); ok {
+		out += "\n\nLock:"
+		switch {
+		case err != nil:
+			out += fmt.Sprintf(" (unavailable: %v)", err)
+		case !ls.Held:
+			out += fmt.Sprintf("\n  Backend: %s\n  Held: false", ls.Backend)
+		default:
+			out += fmt.Sprintf("\n  Backend: %s\n  Held: true", ls.Backend)
+			if ls.Info != nil {
+				out += fmt.Sprintf("\n  Holder: %s", ls.Info.Holder)
+				out += fmt.Sprintf("\n  Operation: %s", ls.Info.Operation)
+				out += fmt.Sprintf("\n  Expires: %s", ls.Info.ExpiresAt.Format(time.RFC3339))
+			}
+		}
+	}
 	return out, nil
 }
 
 // RollingRestart performs a simple one-by-one replacement of active instances.
 func (f *Fleet) RollingRestart() error {
+	return f.RollingRestartWithOptions(OpOptions{})
+}
+
+// RollingRestartWithOptions is RollingRestart with caller context. Each replacement is
+// subject to the maintenance window (unless opts.OverrideWindow is set) and the disruption
+// budget; when either blocks, the restart stops after the instances already replaced.
+func (f *Fleet) RollingRestartWithOptions(opts OpOptions) error {
 	if f.Client == nil {
 		return fmt.Errorf("OCI client not initialized")
 	}
 	f.opMu.Lock()
 	defer f.opMu.Unlock()
-	ctx := context.Background()
+	ctx, unlock, err := f.acquireLock(context.Background(), "rolling-restart")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := f.checkDisruption("rolling-restart", 1, opts); err != nil {
+		return err
+	}
 	fleetName := f.Config.Metadata.Name
 
 	current, err := f.Store.CountActive(fleetName)
@@ -456,93 +664,85 @@ func (f *Fleet) RollingRestart() error {
 		return fmt.Errorf("list instances to restart: %w", err)
 	}
 
-	metrics.Reset("rolling-restart")
-	metrics.SetRollingRestart(0, current)
-
-	// Prepare LB context if enabled
-	lbEnabled := f.Config.Spec.LoadBalancer.Enabled && f.Client != nil
-	var (
-		lbs    *lb.Service
-		lbID   string
-		bsName string
-		lsn    string
-		lbCurr int
-	)
-	if lbEnabled {
-		lbs = lb.New(f.Client.Provider, f.Client.Region)
-		if id, bs, l, err := lbs.Ensure(ctx, f.Config); err != nil {
-			log.Printf("LB ensure failed (rolling-restart): %v", err)
-			lbEnabled = false
-		} else {
-			lbID, bsName, lsn = id, bs, l
-			// initialize current LB backend count from metrics snapshot
-			snap := metrics.Snapshot()
-			if v, ok := snap["lbBackends"].(int); ok {
-				lbCurr = v
-			} else if df, ok := snap["lbBackends"].(float64); ok {
-				lbCurr = int(df)
-			}
-		}
+	f.Metrics.Reset("rolling-restart")
+	f.Metrics.SetRollingRestart(0, current)
+
+	// Registrars (LB, upstream files, webhooks). A replacement is announced together with
+	// the next instance's removal, so each step costs one LB work request instead of two.
+	regs, err := f.registrars(ctx)
+	if err != nil {
+		log.Printf("RollingRestart: registrars: %v", err)
 	}
+	var pending []registrar.Member
+	defer func() {
+		// Stopped early: still register the last replacement
+		f.announce(ctx, regs, pending, nil)
+	}()
 
 	for i := range recs {
 		r := recs[i]
-		metrics.SetRollingRestart(i+1, current)
-
-		// If LB enabled, deregister this backend before termination
-		if lbEnabled {
-			spec := f.Config.Spec.LoadBalancer
-			// optimistic decrement before initiating removal
-			if lbCurr > 0 {
-				lbCurr--
-			}
-			metrics.UpdateLB(true, lbID, lbCurr)
-			if f.Store != nil {
-				_ = f.Store.SetLBBackendsCount(fleetName, lbCurr)
-			}
-			if ip, err := f.Client.InstancePrimaryPrivateIP(ctx, f.Config.Spec.CompartmentID, r.ID); err == nil {
-				if err := lbs.RemoveBackend(ctx, lbID, bsName, ip, spec.BackendPort); err != nil {
-					log.Printf("LB remove backend %s:%d: %v", ip, spec.BackendPort, err)
-				}
-			} else {
-				log.Printf("LB resolve IP for %s: %v", r.ID, err)
+		if i > 0 {
+			if err := f.checkDisruption("rolling-restart", 1, opts); err != nil {
+				f.Metrics.SetError(err.Error())
+				return fmt.Errorf("stopped after %d of %d replacements: %w", i, current, err)
 			}
 		}
+		f.Metrics.SetRollingRestart(i+1, current)
+
+		// Deregister this instance before termination
+		var removed []registrar.Member
+		if len(regs) > 0 {
+			removed = f.recordMembers(ctx, []state.InstanceRecord{r})
+			f.announce(ctx, regs, pending, removed)
+			pending = nil
+		}
 
 		// 1) Terminate this instance
-		metrics.SetPhase("terminate")
+		f.Metrics.SetPhase("terminate")
 		if err := f.Client.TerminateInstances(ctx, []string{r.ID}); err != nil {
-			metrics.IncTerminateFailed(err.Error())
+			f.Metrics.IncTerminateFailed(err.Error())
+			f.recordHistory("rolling-restart", opts, 0, fmt.Sprintf("terminate %s", r.ID), err)
+			// The instance keeps running: the deferred announce registers it again
+			pending = removed
 			return fmt.Errorf("terminate instance %s: %w", r.ID, err)
 		}
-		metrics.IncTerminateSucceeded()
+		f.Metrics.IncTerminateSucceeded()
 		if err := f.Store.MarkTerminatedByIDs(fleetName, []string{r.ID}); err != nil {
 			return fmt.Errorf("update state for %s: %w", r.ID, err)
 		}
 		log.Printf("RollingRestart: terminated %s (%s)", r.ID, r.Name)
 
-		// 2) Launch a replacement in the same group
-		metrics.SetPhase("launch")
-		created, err := f.Client.LaunchInstances(ctx, f.Config, r.Group, 1)
+		// 2) Launch a replacement in the same group with the same capacity type
+		f.Metrics.SetPhase("launch")
+		var placements []client.Placement
+		if f.Config.Spec.Spread() {
+			live, err := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, fleetName)
+			if err != nil {
+				return fmt.Errorf("list instances for placement: %w", err)
+			}
+			plans, err := f.launchPlans(ctx, r.Group, live, 1)
+			if err != nil {
+				return err
+			}
+			placements = plans[0]
+		}
+		replacement, err := f.launchOne(ctx, r.Group, r.Capacity == client.CapacityPreemptible, placements)
 		if err != nil {
-			metrics.IncLaunchFailed(err.Error())
+			f.Metrics.IncLaunchFailed(err.Error())
+			f.recordHistory("rolling-restart", opts, 1, fmt.Sprintf("replace %s", r.ID), err)
 			return fmt.Errorf("launch replacement for %s: %w", r.ID, err)
 		}
-		for _, inst := range created {
-			if err := f.Store.AddActiveRecord(fleetName, r.Group, inst.ID, inst.DisplayName); err != nil {
+		f.recordHistory("rolling-restart", opts, 1, fmt.Sprintf("replaced %s with %s (%d/%d)", r.ID, replacement.ID, i+1, current), nil)
+		for _, inst := range []client.InstanceInfo{replacement} {
+			if err := f.Store.AddInstanceRecord(fleetName, recordFor(r.Group, inst)); err != nil {
 				return fmt.Errorf("record replacement %s: %w", inst.ID, err)
 			}
-			metrics.IncLaunchSucceeded()
-
-			// If LB enabled, register the new instance backend
-			if lbEnabled {
-				spec := f.Config.Spec.LoadBalancer
-				if ip, err := f.Client.InstancePrimaryPrivateIP(ctx, f.Config.Spec.CompartmentID, inst.ID); err == nil {
-					if err := lbs.AddBackend(ctx, lbID, bsName, ip, spec.BackendPort); err != nil {
-						log.Printf("LB add backend %s:%d: %v", ip, spec.BackendPort, err)
-					}
-				} else {
-					log.Printf("LB resolve IP for %s: %v", inst.ID, err)
+			f.Metrics.IncLaunchSucceeded()
+
+			// Register the new instance with the next removal
+			if len(regs) > 0 {
+				if ip := f.privateIPs(ctx, []string{inst.ID})[inst.ID]; ip != "" {
+					pending = append(pending, registrar.Member{ID: inst.ID, Name: inst.DisplayName, Group: r.Group, IP: ip})
 				}
 			}
 
@@ -550,122 +750,63 @@ func (f *Fleet) RollingRestart() error {
 		}
 	}
 
-	// Update LB snapshot after rolling restart completes
-	if lbEnabled {
-		if n, err := lbs.CountBackends(ctx, lbID, bsName); err == nil {
-			metrics.UpdateLB(true, lbID, n)
-			if f.Store != nil {
-				_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-				_ = f.Store.SetLBBackendsCount(fleetName, n)
-			}
-		} else {
-			metrics.UpdateLB(true, lbID, 0)
-			if f.Store != nil {
-				_ = f.Store.SetLBInfo(fleetName, true, lbID, bsName, lsn)
-				_ = f.Store.SetLBBackendsCount(fleetName, 0)
-			}
-		}
-	}
+	// Register the last replacement
+	f.announce(ctx, regs, pending, nil)
+	pending = nil
 
-	metrics.Done()
+	f.Metrics.Done()
 	return nil
 }
 
-func (f *Fleet) ReconcileLoadBalancer(ctx context.Context) error {
+// ReconcileBackends makes the LB backend sets and every other registrar match the fleet's
+// active instances.
+func (f *Fleet) ReconcileBackends(ctx context.Context) error {
 	if f.Client == nil {
 		return fmt.Errorf("OCI client not initialized")
 	}
-	spec := f.Config.Spec.LoadBalancer
-	if !spec.Enabled {
-		metrics.UpdateLB(false, "", 0)
-		if f.Store != nil {
-			_ = f.Store.ClearLB(f.Config.Metadata.Name)
-		}
-		return nil
-	}
-	lbs := lb.New(f.Client.Provider, f.Client.Region)
-
-	lbID, bsName, lsn, err := lbs.Ensure(ctx, f.Config)
+	f.opMu.Lock()
+	defer f.opMu.Unlock()
+	ctx, unlock, err := f.acquireLock(ctx, "backend-reconcile")
 	if err != nil {
-		metrics.SetError(fmt.Sprintf("lb ensure: %v", err))
 		return err
 	}
+	defer unlock()
+	return f.reconcileBackends(ctx)
+}
 
-	// Desired backends from active instances
-	insts, err := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, f.Config.Metadata.Name)
-	if err != nil {
-		return fmt.Errorf("list instances for lb reconcile: %w", err)
+// reconcileBackends is ReconcileBackends for callers already holding opMu and the fleet lock.
+// A registrar failing does not stop the others; the errors are joined.
+func (f *Fleet) reconcileBackends(ctx context.Context) error {
+	if f.Client == nil {
+		return fmt.Errorf("OCI client not initialized")
 	}
-	desired := map[string]struct{}{}
-	for _, it := range insts {
-		ip, ierr := f.Client.InstancePrimaryPrivateIP(ctx, f.Config.Spec.CompartmentID, it.ID)
-		if ierr != nil {
-			log.Printf("LB ip for %s: %v", it.ID, ierr)
-			continue
+	if !f.Config.Spec.LoadBalancer.Enabled {
+		f.Metrics.UpdateLB(false, "", 0)
+		if f.Store != nil {
+			_ = f.Store.ClearLB(f.Config.Metadata.Name)
 		}
-		desired[ip] = struct{}{}
 	}
-
-	// Current backends
-	backends, err := lbs.ListBackends(ctx, lbID, bsName)
+	regs, err := f.registrars(ctx)
 	if err != nil {
-		return fmt.Errorf("list backends: %w", err)
+		f.Metrics.SetError(err.Error())
 	}
-	current := map[string]struct{}{}
-	for _, b := range backends {
-		if b.IpAddress != nil {
-			current[*b.IpAddress] = struct{}{}
-		}
+	if len(regs) == 0 {
+		return err
 	}
+	errs := []error{err}
 
-	// Remove stale
-	curr := len(current)
-	for ip := range current {
-		if _, ok := desired[ip]; !ok {
-			// optimistic decrement before initiating removal
-			if curr > 0 {
-				curr--
-			}
-			metrics.UpdateLB(true, lbID, curr)
-			if f.Store != nil {
-				_ = f.Store.SetLBInfo(f.Config.Metadata.Name, true, lbID, bsName, lsn)
-				_ = f.Store.SetLBBackendsCount(f.Config.Metadata.Name, curr)
-			}
-			if err := lbs.RemoveBackend(ctx, lbID, bsName, ip, spec.BackendPort); err != nil {
-				log.Printf("LB remove stale %s: %v", ip, err)
-			}
-		}
-	}
-	// Add missing
-	for ip := range desired {
-		if _, ok := current[ip]; !ok {
-			if err := lbs.AddBackend(ctx, lbID, bsName, ip, spec.BackendPort); err != nil {
-				log.Printf("LB add missing %s: %v", ip, err)
-			}
-		}
+	// Desired members: the active instances in OCI
+	insts, lerr := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, f.Config.Metadata.Name)
+	if lerr != nil {
+		return errors.Join(append(errs, fmt.Errorf("list instances for backend reconcile: %w", lerr))...)
 	}
-
-	// Refresh backend list for state and metrics
-	if items, e := lbs.ListBackends(ctx, lbID, bsName); e == nil {
-		ips := make([]string, 0, len(items))
-		for _, b := range items {
-			if b.IpAddress != nil {
-				ips = append(ips, *b.IpAddress)
-			}
-		}
-		metrics.UpdateLB(true, lbID, len(ips))
-		if f.Store != nil {
-			_ = f.Store.SetLBInfo(f.Config.Metadata.Name, true, lbID, bsName, lsn)
-			_ = f.Store.SetLBBackends(f.Config.Metadata.Name, ips)
-		}
-	} else {
-		metrics.UpdateLB(true, lbID, 0)
-		if f.Store != nil {
-			_ = f.Store.SetLBInfo(f.Config.Metadata.Name, true, lbID, bsName, lsn)
-			_ = f.Store.SetLBBackendsCount(f.Config.Metadata.Name, 0)
+	u := registrar.Update{Members: f.instanceMembers(ctx, insts), Sync: true}
+	for _, r := range regs {
+		if err := r.Apply(ctx, u); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name(), err))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // Summary returns a simple string describing the loaded config
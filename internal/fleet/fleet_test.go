@@ -0,0 +1,64 @@
+// internal/fleet/fleet_test.go
+package fleet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fleetctl/internal/lock"
+)
+
+// losableLock is a lock.Locker whose loss the test triggers by closing lost.
+type losableLock struct {
+	lost     chan struct{}
+	released bool
+}
+
+func (l *losableLock) Acquire(context.Context, string) error { return nil }
+func (l *losableLock) Release(context.Context) error         { l.released = true; return nil }
+func (l *losableLock) Status(context.Context) (lock.Status, error) {
+	return lock.Status{}, nil
+}
+func (l *losableLock) ForceUnlock(context.Context) error { return nil }
+func (l *losableLock) Lost() <-chan struct{}             { return l.lost }
+
+func TestAcquireLockCancelsOnLoss(t *testing.T) {
+	lk := &losableLock{lost: make(chan struct{})}
+	f := &Fleet{Lock: lock.Multi{lk}}
+
+	ctx, unlock, err := f.acquireLock(context.Background(), "scale")
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("context done before the lock was lost: %v", ctx.Err())
+	}
+	close(lk.lost)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("context not cancelled after the lock was lost")
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, lock.ErrLost) {
+		t.Fatalf("cause = %v, want lock.ErrLost", cause)
+	}
+	unlock()
+	if !lk.released {
+		t.Fatalf("unlock did not release the lock")
+	}
+}
+
+func TestAcquireLockUnlockCancels(t *testing.T) {
+	lk := &losableLock{lost: make(chan struct{})}
+	f := &Fleet{Lock: lk}
+	ctx, unlock, err := f.acquireLock(context.Background(), "sync-state")
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	unlock()
+	if ctx.Err() == nil || context.Cause(ctx) == lock.ErrLost {
+		t.Fatalf("context after unlock: err=%v cause=%v", ctx.Err(), context.Cause(ctx))
+	}
+}
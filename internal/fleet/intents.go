@@ -0,0 +1,147 @@
+// internal/fleet/intents.go
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/state"
+)
+
+// stateJournal writes launch intents to the state store (client.LaunchJournal).
+type stateJournal struct {
+	store *state.Store
+	fleet string
+}
+
+func (j stateJournal) Begin(in client.LaunchIntent) error {
+	return j.store.BeginLaunch(j.fleet, state.InstanceRecord{
+		Group:              in.Group,
+		Name:               in.Name,
+		Capacity:           in.Capacity,
+		AvailabilityDomain: in.Placement.AvailabilityDomain,
+		FaultDomain:        in.Placement.FaultDomain,
+		Shape:              in.Placement.Shape,
+		LaunchToken:        in.Token,
+	})
+}
+
+func (j stateJournal) Abandon(token string) error {
+	return j.store.AbandonLaunch(j.fleet, token)
+}
+
+// journal returns the launch journal for this fleet, or nil without a store.
+func (f *Fleet) journal() client.LaunchJournal {
+	if f.Store == nil {
+		return nil
+	}
+	return stateJournal{store: f.Store, fleet: f.Config.Metadata.Name}
+}
+
+// launchRecovery is the outcome of matching launch intents against OCI.
+type launchRecovery struct {
+	adopt map[string]client.InstanceInfo // intent token -> the instance it created
+	drop  []string                       // tokens whose request created no live instance
+}
+
+// resolveIntents matches intents to live fleet instances by their launch token tag.
+// Instances already tracked as active settle their intent without a second record.
+func resolveIntents(intents []state.InstanceRecord, live []client.InstanceInfo, tracked map[string]bool) launchRecovery {
+	byToken := make(map[string]client.InstanceInfo, len(live))
+	for _, inst := range live {
+		if inst.LaunchToken != "" {
+			byToken[inst.LaunchToken] = inst
+		}
+	}
+	out := launchRecovery{adopt: map[string]client.InstanceInfo{}}
+	for _, in := range intents {
+		inst, ok := byToken[in.LaunchToken]
+		if !ok || tracked[inst.ID] {
+			out.drop = append(out.drop, in.LaunchToken)
+			continue
+		}
+		out.adopt[in.LaunchToken] = inst
+	}
+	return out
+}
+
+// RecoverLaunches resolves launch intents left by a process that stopped mid-launch: an
+// instance carrying the intent's token is recorded as active (and registered with the LB),
+// an intent without one is dropped. It returns the IDs of the adopted instances.
+func (f *Fleet) RecoverLaunches(ctx context.Context) ([]string, error) {
+	if f.Client == nil {
+		return nil, fmt.Errorf("OCI client not initialized")
+	}
+	f.opMu.Lock()
+	defer f.opMu.Unlock()
+	ctx, unlock, err := f.acquireLock(ctx, "recover-launches")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return f.recoverLaunches(ctx)
+}
+
+// recoverLaunches is RecoverLaunches for callers holding opMu and the fleet lock.
+func (f *Fleet) recoverLaunches(ctx context.Context) ([]string, error) {
+	if f.Store == nil {
+		return nil, nil
+	}
+	fleetName := f.Config.Metadata.Name
+	intents, err := f.Store.PendingLaunches(fleetName)
+	if err != nil || len(intents) == 0 {
+		return nil, err
+	}
+	live, err := f.Client.ListInstancesByFleet(ctx, f.Config.Spec.CompartmentID, fleetName)
+	if err != nil {
+		return nil, fmt.Errorf("list instances for %d pending launch(es): %w", len(intents), err)
+	}
+	active, err := f.Store.ActiveRecords(fleetName)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]bool, len(active))
+	for _, r := range active {
+		tracked[r.ID] = true
+	}
+	res := resolveIntents(intents, live, tracked)
+
+	var adopted []client.InstanceInfo
+	groups := map[string]string{}
+	for _, in := range intents {
+		inst, ok := res.adopt[in.LaunchToken]
+		if !ok {
+			continue
+		}
+		groups[inst.ID] = in.Group
+		if addr, err := f.Client.InstanceAddresses(ctx, f.Config.Spec.CompartmentID, inst.ID); err == nil {
+			inst.PrivateIP, inst.PublicIP = addr.PrivateIP, addr.PublicIP
+		}
+		adopted = append(adopted, inst)
+	}
+	err = f.Store.Batch(func() error {
+		for _, inst := range adopted {
+			if err := f.Store.AddInstanceRecord(fleetName, recordFor(groups[inst.ID], inst)); err != nil {
+				return fmt.Errorf("record recovered instance %s: %w", inst.ID, err)
+			}
+		}
+		for _, token := range res.drop {
+			if err := f.Store.AbandonLaunch(fleetName, token); err != nil {
+				return fmt.Errorf("drop launch intent %s: %w", token, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(adopted))
+	for _, inst := range adopted {
+		ids = append(ids, inst.ID)
+	}
+	log.Printf("Recover: %d launch intent(s): adopted %d instance(s) %v, dropped %d without an instance", len(intents), len(adopted), ids, len(res.drop))
+	f.registerBackends(ctx, adopted)
+	return ids, nil
+}
@@ -0,0 +1,29 @@
+// internal/fleet/intents_test.go
+package fleet
+
+import (
+	"testing"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/state"
+)
+
+func TestResolveIntents(t *testing.T) {
+	intents := []state.InstanceRecord{
+		{LaunchToken: "created", Group: "web"},
+		{LaunchToken: "never-sent", Group: "web"},
+		{LaunchToken: "already-tracked", Group: "web"},
+	}
+	live := []client.InstanceInfo{
+		{ID: "i1", LaunchToken: "created"},
+		{ID: "i2", LaunchToken: "already-tracked"},
+		{ID: "i3"}, // launched before intents existed
+	}
+	res := resolveIntents(intents, live, map[string]bool{"i2": true})
+	if len(res.adopt) != 1 || res.adopt["created"].ID != "i1" {
+		t.Fatalf("adopt = %+v; want created -> i1", res.adopt)
+	}
+	if len(res.drop) != 2 || res.drop[0] != "never-sent" || res.drop[1] != "already-tracked" {
+		t.Fatalf("drop = %v", res.drop)
+	}
+}
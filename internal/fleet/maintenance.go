@@ -0,0 +1,239 @@
+// internal/fleet/maintenance.go
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"fleetctl/internal/maintenance"
+	"fleetctl/internal/state"
+)
+
+// Sources of fleet operations, recorded in history.
+const (
+	SourceCLI         = "cli"
+	SourceAPI         = "api"
+	SourceControlLoop = "control-loop"
+)
+
+// OpOptions carries caller context for operations that may disrupt instances.
+type OpOptions struct {
+	Source         string // SourceCLI, SourceAPI or SourceControlLoop
+	OverrideWindow string // reason for running outside maintenance windows; recorded in history
+}
+
+// policy parses spec.maintenanceWindows; a nil policy means always open.
+func (f *Fleet) policy() (*maintenance.Policy, error) {
+	return maintenance.NewPolicy(f.Config.Spec.MaintenanceWindows)
+}
+
+// checkWindow returns a *maintenance.BlockedError when op may not run now.
+func (f *Fleet) checkWindow(op string, opts OpOptions) error {
+	p, err := f.policy()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if p.Open(now) {
+		return nil
+	}
+	if opts.OverrideWindow != "" {
+		log.Printf("%s: running outside maintenance windows (override: %s)", op, opts.OverrideWindow)
+		return nil
+	}
+	next, _ := p.NextOpen(now)
+	return &maintenance.BlockedError{
+		Operation: op,
+		Reason:    fmt.Sprintf("outside maintenance windows %s", p.Describe()),
+		NextOpen:  next,
+	}
+}
+
+// DisruptionAllowance returns how many more instances may be disrupted in the current
+// rolling hour; limited is false when no disruption budget is configured.
+func (f *Fleet) DisruptionAllowance() (remaining int, limited bool, err error) {
+	b := f.Config.Spec.DisruptionBudget
+	if b == nil || b.MaxPerHour <= 0 {
+		return 0, false, nil
+	}
+	used, err := f.Store.DisruptionsSince(f.Config.Metadata.Name, time.Now().Add(-time.Hour))
+	if err != nil {
+		return 0, true, fmt.Errorf("read disruption history: %w", err)
+	}
+	if used >= b.MaxPerHour {
+		return 0, true, nil
+	}
+	return b.MaxPerHour - used, true, nil
+}
+
+// checkBudget returns a *maintenance.BlockedError when disrupting n more instances would
+// exceed the disruption budget.
+func (f *Fleet) checkBudget(op string, n int) error {
+	remaining, limited, err := f.DisruptionAllowance()
+	if err != nil || !limited || n <= remaining {
+		return err
+	}
+	return &maintenance.BlockedError{
+		Operation: op,
+		Reason: fmt.Sprintf("disruption budget of %d instance(s) per hour allows %d more, %d requested",
+			f.Config.Spec.DisruptionBudget.MaxPerHour, remaining, n),
+	}
+}
+
+// checkDisruption applies the maintenance window and the disruption budget to op.
+func (f *Fleet) checkDisruption(op string, n int, opts OpOptions) error {
+	if err := f.checkWindow(op, opts); err != nil {
+		return err
+	}
+	return f.checkBudget(op, n)
+}
+
+// admit decides whether an API request may start now. Outside a window with
+// outsideWindow: queue, the request is queued and a BlockedError with Queued set is returned.
+func (f *Fleet) admit(op string, n int, opts OpOptions, q state.QueuedOp) error {
+	err := f.checkDisruption(op, n, opts)
+	var be *maintenance.BlockedError
+	if !errors.As(err, &be) || be.NextOpen.IsZero() {
+		return err
+	}
+	p, perr := f.policy()
+	if perr != nil || !p.Queue {
+		return err
+	}
+	q.Source = opts.Source
+	if qerr := f.Store.QueueOp(f.Config.Metadata.Name, q); qerr != nil {
+		return fmt.Errorf("queue %s: %w", op, qerr)
+	}
+	be.Queued = true
+	log.Printf("%s: %v", op, be)
+	return be
+}
+
+// AdmitScale checks a scale request before it is run asynchronously. Scale-ups are always
+// admitted; scale-downs are subject to maintenance windows and the disruption budget.
+func (f *Fleet) AdmitScale(desired int, opts OpOptions) error {
+	current, err := f.Store.CountActive(f.Config.Metadata.Name)
+	if err != nil {
+		return fmt.Errorf("reading state: %w", err)
+	}
+	if desired >= current {
+		return nil
+	}
+	return f.admit("scale-down", current-desired, opts, state.QueuedOp{Operation: "scale", Desired: desired})
+}
+
+// AdmitRollingRestart checks a rolling restart request before it runs.
+func (f *Fleet) AdmitRollingRestart(opts OpOptions) error {
+	return f.admit("rolling-restart", 1, opts, state.QueuedOp{Operation: "rolling-restart"})
+}
+
+// RunQueued runs operations queued for the maintenance window once it is open. It is
+// called by the control loop; nothing runs while the window is closed.
+func (f *Fleet) RunQueued() ([]state.QueuedOp, error) {
+	fleetName := f.Config.Metadata.Name
+	queued, err := f.Store.Queued(fleetName)
+	if err != nil || len(queued) == 0 {
+		return nil, err
+	}
+	p, err := f.policy()
+	if err != nil {
+		return nil, err
+	}
+	if !p.Open(time.Now()) {
+		return nil, nil
+	}
+	ops, err := f.Store.TakeQueued(fleetName)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, q := range ops {
+		opts := OpOptions{Source: q.Source}
+		log.Printf("RunQueued: running %s queued at %s", q.Operation, q.RequestedAt.Format(time.RFC3339))
+		switch q.Operation {
+		case "scale":
+			err = f.ScaleWithOptions(q.Desired, opts)
+		case "rolling-restart":
+			err = f.RollingRestartWithOptions(opts)
+		default:
+			err = fmt.Errorf("unknown queued operation %q", q.Operation)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("queued %s: %w", q.Operation, err))
+		}
+	}
+	return ops, errors.Join(errs...)
+}
+
+// recordHistory appends an entry to the fleet history; failures are logged only.
+func (f *Fleet) recordHistory(op string, opts OpOptions, disrupted int, detail string, opErr error) {
+	if f.Store == nil {
+		return
+	}
+	e := state.HistoryEntry{
+		Operation: op,
+		Source:    opts.Source,
+		Disrupted: disrupted,
+		Detail:    detail,
+		Override:  opts.OverrideWindow,
+	}
+	if opErr != nil {
+		e.Error = opErr.Error()
+	}
+	if err := f.Store.AppendHistory(f.Config.Metadata.Name, e); err != nil {
+		log.Printf("%s: record history: %v", op, err)
+	}
+}
+
+// maintenanceSummary renders windows, budget, queued operations and recent history for status output.
+func (f *Fleet) maintenanceSummary() string {
+	fleetName := f.Config.Metadata.Name
+	out := "Maintenance:"
+	p, err := f.policy()
+	if err != nil {
+		out += fmt.Sprintf("\n  Windows: invalid (%v)", err)
+	} else {
+		now := time.Now()
+		out += fmt.Sprintf("\n  Windows: %s", p.Describe())
+		if p != nil {
+			if p.Open(now) {
+				out += "\n  Window open: true"
+			} else if next, ok := p.NextOpen(now); ok {
+				out += fmt.Sprintf("\n  Window open: false (next %s)", next.Format(time.RFC3339))
+			} else {
+				out += "\n  Window open: false"
+			}
+		}
+	}
+	if remaining, limited, err := f.DisruptionAllowance(); err == nil && limited {
+		out += fmt.Sprintf("\n  Disruption budget: %d/hour, %d remaining", f.Config.Spec.DisruptionBudget.MaxPerHour, remaining)
+	}
+	if queued, err := f.Store.Queued(fleetName); err == nil {
+		for _, q := range queued {
+			out += fmt.Sprintf("\n  Queued: %s", q.Operation)
+			if q.Operation == "scale" {
+				out += fmt.Sprintf(" to %d", q.Desired)
+			}
+			out += fmt.Sprintf(" (requested %s)", q.RequestedAt.Format(time.RFC3339))
+		}
+	}
+	if hist, err := f.Store.History(fleetName, 5); err == nil && len(hist) > 0 {
+		out += "\n  Recent history:"
+		for _, e := range hist {
+			line := fmt.Sprintf("\n    %s %s", e.Time.Format(time.RFC3339), e.Operation)
+			if e.Detail != "" {
+				line += ": " + e.Detail
+			}
+			if e.Override != "" {
+				line += fmt.Sprintf(" [override: %s]", e.Override)
+			}
+			if e.Error != "" {
+				line += fmt.Sprintf(" [error: %s]", e.Error)
+			}
+			out += line
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+// internal/fleet/placement.go
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/state"
+)
+
+// domainKey names the failure domain an instance lives in, e.g. "PHX-AD-1/FAULT-DOMAIN-2".
+// The tenancy prefix of the AD name is dropped; an unknown fault domain leaves just the AD.
+func domainKey(ad, fd string) string {
+	if i := strings.LastIndex(ad, ":"); i >= 0 {
+		ad = ad[i+1:]
+	}
+	if ad == "" {
+		return "unknown"
+	}
+	if fd == "" {
+		return ad
+	}
+	return ad + "/" + fd
+}
+
+func placementDomain(p client.Placement) string {
+	return domainKey(p.AvailabilityDomain, p.FaultDomain)
+}
+
+// spreadOrder reorders candidates so the least crowded domains come first. The sort is
+// stable, so within equally crowded domains the configured order (primary shape first) is kept.
+func spreadOrder(cands []client.Placement, counts map[string]int) []client.Placement {
+	out := append([]client.Placement(nil), cands...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return counts[placementDomain(out[i])] < counts[placementDomain(out[j])]
+	})
+	return out
+}
+
+// planSpread returns per-launch candidate orders for n new instances of one group, assuming
+// each launch lands on its first choice. counts holds the group's current per-domain tally.
+func planSpread(cands []client.Placement, counts map[string]int, n int) [][]client.Placement {
+	tally := make(map[string]int, len(counts))
+	for k, v := range counts {
+		tally[k] = v
+	}
+	plans := make([][]client.Placement, 0, n)
+	for i := 0; i < n; i++ {
+		order := spreadOrder(cands, tally)
+		if len(order) > 0 {
+			tally[placementDomain(order[0])]++
+		}
+		plans = append(plans, order)
+	}
+	return plans
+}
+
+// groupDomainCounts tallies remote instances of group per domain.
+func (f *Fleet) groupDomainCounts(instances []client.InstanceInfo, group string) map[string]int {
+	counts := map[string]int{}
+	for _, it := range instances {
+		if f.groupFromName(it.DisplayName) == group {
+			counts[domainKey(it.AvailabilityDomain, it.FaultDomain)]++
+		}
+	}
+	return counts
+}
+
+// launchPlans returns the candidate order for each of n launches in group. Without spread
+// placement every launch uses the client's default order (nil).
+func (f *Fleet) launchPlans(ctx context.Context, group string, instances []client.InstanceInfo, n int) ([][]client.Placement, error) {
+	plans := make([][]client.Placement, n)
+	if !f.Config.Spec.Spread() || n <= 0 {
+		return plans, nil
+	}
+	cands, err := f.Client.PlacementCandidates(ctx, f.Config)
+	if err != nil {
+		return nil, fmt.Errorf("placement candidates: %w", err)
+	}
+	return planSpread(cands, f.groupDomainCounts(instances, group), n), nil
+}
+
+// pickScaleIn selects n records to remove, oldest first within the most crowded
+// (group, domain) each time, so scale-in keeps groups balanced. recs must be in FIFO order.
+func pickScaleIn(recs []state.InstanceRecord, n int, domainOf func(state.InstanceRecord) string) []state.InstanceRecord {
+	counts := map[string]int{}
+	key := func(r state.InstanceRecord) string { return r.Group + "|" + domainOf(r) }
+	for _, r := range recs {
+		counts[key(r)]++
+	}
+	left := append([]state.InstanceRecord(nil), recs...)
+	out := make([]state.InstanceRecord, 0, n)
+	for len(out) < n && len(left) > 0 {
+		best := 0
+		for i := range left {
+			if counts[key(left[i])] > counts[key(left[best])] {
+				best = i
+			}
+		}
+		r := left[best]
+		counts[key(r)]--
+		out = append(out, r)
+		left = append(left[:best], left[best+1:]...)
+	}
+	return out
+}
+
+// recordDomain returns the domain of a state record, falling back to the live instance
+// when the record predates placement tracking.
+func recordDomain(live map[string]client.InstanceInfo) func(state.InstanceRecord) string {
+	return func(r state.InstanceRecord) string {
+		if r.AvailabilityDomain != "" {
+			return domainKey(r.AvailabilityDomain, r.FaultDomain)
+		}
+		if it, ok := live[r.ID]; ok {
+			return domainKey(it.AvailabilityDomain, it.FaultDomain)
+		}
+		return "unknown"
+	}
+}
+
+// placementSummary renders the per-group distribution across domains for status output.
+func (f *Fleet) placementSummary(instances []client.InstanceInfo) string {
+	byGroup := map[string]map[string]int{}
+	for _, it := range instances {
+		g := f.groupFromName(it.DisplayName)
+		if byGroup[g] == nil {
+			byGroup[g] = map[string]int{}
+		}
+		byGroup[g][domainKey(it.AvailabilityDomain, it.FaultDomain)]++
+	}
+	strategy := "first-fit"
+	if f.Config.Spec.Spread() {
+		strategy = "spread"
+	}
+	out := fmt.Sprintf("Placement (%s):", strategy)
+	groups := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		domains := make([]string, 0, len(byGroup[g]))
+		for d := range byGroup[g] {
+			domains = append(domains, d)
+		}
+		sort.Strings(domains)
+		parts := make([]string, 0, len(domains))
+		for _, d := range domains {
+			parts = append(parts, fmt.Sprintf("%s=%d", d, byGroup[g][d]))
+		}
+		out += fmt.Sprintf("\n  - %s: %s", g, strings.Join(parts, " "))
+	}
+	if len(groups) == 0 {
+		out += " (no instances)"
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+// internal/fleet/placement_test.go
+package fleet
+
+import (
+	"testing"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/state"
+)
+
+func TestDomainKey(t *testing.T) {
+	tests := []struct {
+		ad, fd, want string
+	}{
+		{"kIdk:PHX-AD-1", "FAULT-DOMAIN-2", "PHX-AD-1/FAULT-DOMAIN-2"},
+		{"PHX-AD-1", "", "PHX-AD-1"},
+		{"", "", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := domainKey(tt.ad, tt.fd); got != tt.want {
+			t.Fatalf("domainKey(%q, %q) = %q, want %q", tt.ad, tt.fd, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSpreadBalancesDomains(t *testing.T) {
+	cands := []client.Placement{
+		{AvailabilityDomain: "x:AD-1", FaultDomain: "FD-1", Shape: "A"},
+		{AvailabilityDomain: "x:AD-2", FaultDomain: "FD-1", Shape: "A"},
+		{AvailabilityDomain: "x:AD-1", FaultDomain: "FD-1", Shape: "B"},
+		{AvailabilityDomain: "x:AD-2", FaultDomain: "FD-1", Shape: "B"},
+	}
+	counts := map[string]int{"AD-1/FD-1": 2}
+
+	plans := planSpread(cands, counts, 3)
+	got := []string{}
+	for _, p := range plans {
+		got = append(got, placementDomain(p[0])+":"+p[0].Shape)
+	}
+	want := []string{"AD-2/FD-1:A", "AD-2/FD-1:A", "AD-1/FD-1:A"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("plan %d first choice = %s, want %s (all: %v)", i, got[i], want[i], got)
+		}
+	}
+	// The fallback shape in the preferred domain comes before the crowded domain.
+	if p := plans[0]; p[1].Shape != "B" || placementDomain(p[1]) != "AD-2/FD-1" {
+		t.Fatalf("plan 0 second choice = %+v, want AD-2 shape B", p[1])
+	}
+	if counts["AD-1/FD-1"] != 2 || len(counts) != 1 {
+		t.Fatalf("planSpread mutated counts: %v", counts)
+	}
+}
+
+func TestPickScaleInFromMostCrowded(t *testing.T) {
+	recs := []state.InstanceRecord{
+		{ID: "a1", Group: "web", AvailabilityDomain: "AD-1"},
+		{ID: "b1", Group: "web", AvailabilityDomain: "AD-2"},
+		{ID: "a2", Group: "web", AvailabilityDomain: "AD-1"},
+		{ID: "a3", Group: "web", AvailabilityDomain: "AD-1"},
+		{ID: "b2", Group: "web", AvailabilityDomain: "AD-2"},
+	}
+	got := pickScaleIn(recs, 3, recordDomain(nil))
+	want := []string{"a1", "b1", "a2"} // ties go to the oldest record
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Fatalf("pick %d = %s, want %s", i, got[i].ID, want[i])
+		}
+	}
+}
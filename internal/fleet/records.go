@@ -0,0 +1,114 @@
+// internal/fleet/records.go
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fleetctl/internal/lb"
+	"fleetctl/internal/state"
+)
+
+// privateIPs returns the primary private IP of each instance in ids. Addresses come from
+// the state records; only instances without a recorded address are looked up in OCI, and
+// what is found is written back so the next call does not repeat the lookup.
+func (f *Fleet) privateIPs(ctx context.Context, ids []string) map[string]string {
+	fleetName := f.Config.Metadata.Name
+	known := map[string]string{}
+	if f.Store != nil {
+		if recs, err := f.Store.ActiveRecords(fleetName); err == nil {
+			for _, r := range recs {
+				if r.PrivateIP != "" {
+					known[r.ID] = r.PrivateIP
+				}
+			}
+		}
+	}
+	out := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if ip, ok := known[id]; ok {
+			out[id] = ip
+			continue
+		}
+		if f.Client == nil {
+			continue
+		}
+		addr, err := f.Client.InstanceAddresses(ctx, f.Config.Spec.CompartmentID, id)
+		if err != nil {
+			log.Printf("LB resolve IP for %s: %v", id, err)
+			continue
+		}
+		out[id] = addr.PrivateIP
+		if f.Store != nil {
+			if err := f.Store.SetInstanceAddresses(fleetName, id, addr.PrivateIP, addr.PublicIP); err != nil {
+				log.Printf("record addresses of %s: %v", id, err)
+			}
+		}
+	}
+	return out
+}
+
+// mergeRecord fills what OCI listings do not return (addresses, and the revision of
+// instances launched before it was tagged) from the record previously held for the instance.
+func mergeRecord(rec, prev state.InstanceRecord) state.InstanceRecord {
+	if rec.PrivateIP == "" {
+		rec.PrivateIP, rec.PublicIP = prev.PrivateIP, prev.PublicIP
+	}
+	if rec.ConfigRevision == "" {
+		rec.ConfigRevision = prev.ConfigRevision
+	}
+	if rec.ImageID == "" {
+		rec.ImageID = prev.ImageID
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = prev.CreatedAt
+	}
+	return rec
+}
+
+// instancesSummary renders the recorded details of active instances for status output;
+// instances launched from an older config revision are marked stale.
+func (f *Fleet) instancesSummary(recs []state.InstanceRecord, now time.Time) string {
+	rev := f.Config.Spec.Revision()
+	out := fmt.Sprintf("Instances (local, config revision %s):", rev)
+	if len(recs) == 0 {
+		return out + " (none)"
+	}
+	for _, r := range recs {
+		ips := orDash(r.PrivateIP)
+		if r.PublicIP != "" {
+			ips += "/" + r.PublicIP
+		}
+		age := "-"
+		if !r.CreatedAt.IsZero() {
+			age = now.Sub(r.CreatedAt).Truncate(time.Minute).String()
+		}
+		line := fmt.Sprintf("\n  - %s %s group=%s %s shape=%s ip=%s age=%s rev=%s",
+			orDash(r.Name), r.ID, r.Group, domainKey(r.AvailabilityDomain, r.FaultDomain), orDash(r.Shape), ips, age, orDash(r.ConfigRevision))
+		if r.ConfigRevision != "" && r.ConfigRevision != rev {
+			line += " (stale)"
+		}
+		out += line
+	}
+	return out
+}
+
+func orDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
+
+// recordLB stores the LB identity, pending replacements and backend count in one state write.
+func (f *Fleet) recordLB(topo lb.Topology, backends int) {
+	fleetName := f.Config.Metadata.Name
+	_ = f.Store.Batch(func() error {
+		_ = f.Store.SetLBInfo(fleetName, true, topo.ID, topo.BackendSetNames(), topo.Listener)
+		_ = f.Store.SetLBNeedsReplacement(fleetName, topo.NeedsReplacement)
+		return f.Store.SetLBBackendsCount(fleetName, backends)
+	})
+}
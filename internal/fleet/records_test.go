@@ -0,0 +1,51 @@
+// internal/fleet/records_test.go
+package fleet
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+	"fleetctl/internal/state"
+)
+
+func TestMergeRecordKeepsRecordedDetails(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	prev := state.InstanceRecord{ID: "i1", PrivateIP: "10.0.0.5", PublicIP: "203.0.113.5", ConfigRevision: "old", CreatedAt: created}
+
+	got := mergeRecord(state.InstanceRecord{ID: "i1", ConfigRevision: "new", ImageID: "img"}, prev)
+	if got.PrivateIP != "10.0.0.5" || got.PublicIP != "203.0.113.5" || got.ConfigRevision != "new" || got.ImageID != "img" || !got.CreatedAt.Equal(created) {
+		t.Fatalf("merge = %+v", got)
+	}
+	// Addresses found on this sync win over the recorded ones
+	got = mergeRecord(state.InstanceRecord{ID: "i1", PrivateIP: "10.0.0.9"}, prev)
+	if got.PrivateIP != "10.0.0.9" || got.PublicIP != "" {
+		t.Fatalf("merge with fresh address = %+v", got)
+	}
+}
+
+func TestInstancesSummaryMarksStale(t *testing.T) {
+	cfg := config.FleetConfig{Spec: config.Spec{ImageID: "img-2", Shape: "VM.Standard.E4.Flex"}}
+	f := &Fleet{Config: cfg}
+	rev := cfg.Spec.Revision()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recs := []state.InstanceRecord{
+		{ID: "i1", Name: "web-1", Group: "web", ConfigRevision: rev, PrivateIP: "10.0.0.1", CreatedAt: now.Add(-90 * time.Minute)},
+		{ID: "i2", Name: "web-2", Group: "web", ConfigRevision: "0123456789ab"},
+	}
+	out := f.instancesSummary(recs, now)
+	lines := strings.Split(out, "\n")
+	if len(lines) != 3 || strings.Contains(lines[1], "stale") || !strings.Contains(lines[1], "age=1h30m0s") || !strings.HasSuffix(lines[2], "(stale)") {
+		t.Fatalf("summary:\n%s", out)
+	}
+
+	cfg.Spec.Scaling.ParallelLaunch = 9
+	if cfg.Spec.Revision() != rev {
+		t.Fatalf("scaling settings must not change the revision")
+	}
+	cfg.Spec.ImageID = "img-3"
+	if cfg.Spec.Revision() == rev {
+		t.Fatalf("a new image must change the revision")
+	}
+}
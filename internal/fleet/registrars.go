@@ -0,0 +1,246 @@
+// internal/fleet/registrars.go
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"fleetctl/internal/client"
+	"fleetctl/internal/dns"
+	"fleetctl/internal/lb"
+	"fleetctl/internal/registrar"
+	"fleetctl/internal/state"
+)
+
+// registrars opens the fleet's registrars: the load balancer when spec.loadBalancer is
+// enabled (brought in line with the spec, see ensureLB), the DNS records of spec.dns,
+// then those of spec.registrars. Registrars that fail to open are left out and their
+// errors joined.
+func (f *Fleet) registrars(ctx context.Context) ([]registrar.Registrar, error) {
+	var (
+		regs []registrar.Registrar
+		errs []error
+	)
+	if f.Config.Spec.LoadBalancer.Enabled && f.Client != nil {
+		if lbs, topo, err := f.ensureLB(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("lb ensure: %w", err))
+		} else {
+			regs = append(regs, &lbRegistrar{f: f, lbs: lbs, topo: topo})
+		}
+	}
+	if f.Config.Spec.DNS != nil && f.Client != nil {
+		if r, err := dns.Open(f.Client.Provider, f.Client.Region, f.Config, f.namePrefix()); err != nil {
+			errs = append(errs, fmt.Errorf("dns: %w", err))
+		} else {
+			regs = append(regs, r)
+		}
+	}
+	more, err := registrar.Open(f.Config.Metadata.Name, f.Config.Spec.Registrars)
+	return append(regs, more...), errors.Join(append(errs, err)...)
+}
+
+// announce registers added and deregisters removed instances with every registrar.
+// Failures are logged: the next reconcile retries them.
+func (f *Fleet) announce(ctx context.Context, regs []registrar.Registrar, added, removed []registrar.Member) {
+	if len(regs) == 0 || len(added)+len(removed) == 0 {
+		return
+	}
+	u := registrar.Update{Members: f.members(ctx, added, removed), Added: added, Removed: removed}
+	for _, r := range regs {
+		if err := r.Apply(ctx, u); err != nil {
+			log.Printf("registrar %s: %v", r.Name(), err)
+		}
+	}
+}
+
+// members returns the active instances in state, without removed and with added, as
+// registrar members: the fleet as it receives traffic once an update is applied.
+func (f *Fleet) members(ctx context.Context, added, removed []registrar.Member) []registrar.Member {
+	var recs []state.InstanceRecord
+	if f.Store != nil {
+		var err error
+		if recs, err = f.Store.ActiveRecords(f.Config.Metadata.Name); err != nil {
+			log.Printf("registrar members: %v", err)
+		}
+	}
+	gone := map[string]bool{}
+	for _, m := range removed {
+		gone[m.ID] = true
+	}
+	out := make([]registrar.Member, 0, len(recs)+len(added))
+	for _, m := range f.recordMembers(ctx, recs) {
+		if !gone[m.ID] {
+			out = append(out, m)
+		}
+	}
+	for _, m := range added {
+		if !slices.ContainsFunc(out, func(o registrar.Member) bool { return o.ID == m.ID }) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// recordMembers turns state records into members. Instances without a private IP are left out.
+func (f *Fleet) recordMembers(ctx context.Context, recs []state.InstanceRecord) []registrar.Member {
+	ids := make([]string, 0, len(recs))
+	for _, r := range recs {
+		ids = append(ids, r.ID)
+	}
+	ips := f.privateIPs(ctx, ids)
+	out := make([]registrar.Member, 0, len(recs))
+	for _, r := range recs {
+		if ip, ok := ips[r.ID]; ok && ip != "" {
+			out = append(out, registrar.Member{ID: r.ID, Name: r.Name, Group: r.Group, IP: ip})
+		}
+	}
+	return out
+}
+
+// instanceMembers turns OCI instances into members, grouped by display name. Instances
+// without a private IP are left out.
+func (f *Fleet) instanceMembers(ctx context.Context, insts []client.InstanceInfo) []registrar.Member {
+	ids := make([]string, 0, len(insts))
+	for _, it := range insts {
+		ids = append(ids, it.ID)
+	}
+	ips := f.privateIPs(ctx, ids)
+	out := make([]registrar.Member, 0, len(insts))
+	for _, it := range insts {
+		if ip, ok := ips[it.ID]; ok && ip != "" {
+			out = append(out, registrar.Member{ID: it.ID, Name: it.DisplayName, Group: f.groupFromName(it.DisplayName), IP: ip})
+		}
+	}
+	return out
+}
+
+// lbRegistrar is the OCI load balancer of spec.loadBalancer as a registrar: members go
+// to the backend set of their group (see lbTarget) and every change is reflected in
+// the LB metrics and state.
+type lbRegistrar struct {
+	f    *Fleet
+	lbs  lb.Balancer
+	topo lb.Topology
+}
+
+func (r *lbRegistrar) Name() string { return "loadBalancer" }
+
+func (r *lbRegistrar) Apply(ctx context.Context, u registrar.Update) error {
+	if u.Sync {
+		return r.sync(ctx, u.Members)
+	}
+	var changes []lb.Change
+	for _, m := range u.Removed {
+		if tg, ok := lbTarget(r.topo, m.Group); ok {
+			changes = append(changes, lb.Change{BackendSet: tg.BackendSet, Addr: lb.Addr{IP: m.IP, Port: tg.Port}, Remove: true})
+		}
+	}
+	if removed := len(changes); removed > 0 {
+		// optimistic decrement before initiating removal
+		curr := max(r.f.Metrics.LBBackends()-removed, 0)
+		r.f.Metrics.UpdateLB(true, r.topo.ID, curr)
+		if r.f.Store != nil {
+			r.f.recordLB(r.topo, curr)
+		}
+	}
+	for _, m := range u.Added {
+		if tg, ok := lbTarget(r.topo, m.Group); ok {
+			changes = append(changes, lb.Change{BackendSet: tg.BackendSet, Addr: lb.Addr{IP: m.IP, Port: tg.Port}})
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	r.f.applyLB(ctx, r.lbs, r.topo, changes)
+	// After the changes, refresh the authoritative count
+	r.f.refreshLBCount(ctx, r.lbs, r.topo)
+	return nil
+}
+
+// sync makes every backend set hold exactly the members of its groups, removing backends
+// of instances that are gone, moved to another set or sit on an old port.
+func (r *lbRegistrar) sync(ctx context.Context, members []registrar.Member) error {
+	f, topo := r.f, r.topo
+	desired := map[string]map[string]struct{}{} // backend set -> IPs
+	for _, m := range members {
+		tg, ok := lbTarget(topo, m.Group)
+		if !ok {
+			continue
+		}
+		if desired[tg.BackendSet] == nil {
+			desired[tg.BackendSet] = map[string]struct{}{}
+		}
+		desired[tg.BackendSet][m.IP] = struct{}{}
+	}
+
+	// Current backends, per set
+	current := map[string][]lb.Addr{}
+	curr := 0
+	for _, tg := range topo.Targets() {
+		backends, err := r.lbs.BackendAddrs(ctx, topo.ID, tg.BackendSet)
+		if err != nil {
+			return fmt.Errorf("list backends: %w", err)
+		}
+		current[tg.BackendSet] = backends
+		curr += len(backends)
+	}
+
+	var changes []lb.Change
+	removed := 0
+	for _, tg := range topo.Targets() {
+		want := desired[tg.BackendSet]
+		have := map[string]struct{}{}
+		// Remove stale: instances gone, moved to another set, or on an old port
+		for _, b := range current[tg.BackendSet] {
+			if _, ok := want[b.IP]; ok && b.Port == tg.Port {
+				have[b.IP] = struct{}{}
+				continue
+			}
+			changes = append(changes, lb.Change{BackendSet: tg.BackendSet, Addr: b, Remove: true})
+			removed++
+		}
+		// Add missing
+		for ip := range want {
+			if _, ok := have[ip]; !ok {
+				changes = append(changes, lb.Change{BackendSet: tg.BackendSet, Addr: lb.Addr{IP: ip, Port: tg.Port}})
+			}
+		}
+	}
+	if removed > 0 {
+		// optimistic decrement before initiating removal
+		curr = max(curr-removed, 0)
+		f.Metrics.UpdateLB(true, topo.ID, curr)
+		if f.Store != nil {
+			f.recordLB(topo, curr)
+		}
+	}
+	f.applyLB(ctx, r.lbs, topo, changes)
+
+	// Refresh backend list for state and metrics
+	all := []string{}
+	for _, tg := range topo.Targets() {
+		items, e := r.lbs.BackendAddrs(ctx, topo.ID, tg.BackendSet)
+		if e != nil {
+			f.Metrics.UpdateLB(true, topo.ID, 0)
+			if f.Store != nil {
+				f.recordLB(topo, 0)
+			}
+			return nil
+		}
+		for _, b := range items {
+			all = append(all, b.IP)
+		}
+	}
+	f.Metrics.UpdateLB(true, topo.ID, len(all))
+	if f.Store != nil {
+		_ = f.Store.Batch(func() error {
+			_ = f.Store.SetLBInfo(f.Config.Metadata.Name, true, topo.ID, topo.BackendSetNames(), topo.Listener)
+			_ = f.Store.SetLBNeedsReplacement(f.Config.Metadata.Name, topo.NeedsReplacement)
+			return f.Store.SetLBBackends(f.Config.Metadata.Name, all)
+		})
+	}
+	return nil
+}
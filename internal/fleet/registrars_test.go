@@ -0,0 +1,39 @@
+// internal/fleet/registrars_test.go
+package fleet
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"fleetctl/internal/config"
+	"fleetctl/internal/registrar"
+	"fleetctl/internal/state"
+)
+
+func TestMembersAfterAnUpdate(t *testing.T) {
+	st := state.New(filepath.Join(t.TempDir(), "state.json"))
+	cfg := config.FleetConfig{}
+	cfg.Metadata.Name = "dev"
+	f := New(cfg, nil, st, nil)
+	err := st.ResetFleetActive("dev", []state.InstanceRecord{
+		{ID: "a", Name: "dev-web-a", Group: "web", PrivateIP: "10.0.0.1"},
+		{ID: "b", Name: "dev-web-b", Group: "web", PrivateIP: "10.0.0.2"},
+		{ID: "c", Name: "dev-web-c", Group: "web"}, // no address and no client to look it up
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := []registrar.Member{
+		{ID: "b", Name: "dev-web-b", Group: "web", IP: "10.0.0.2"},
+		{ID: "d", Name: "dev-api-d", Group: "api", IP: "10.0.1.4"},
+	}
+	got := f.members(context.Background(), added, []registrar.Member{{ID: "a"}})
+	ids := ""
+	for _, m := range got {
+		ids += m.ID + "=" + m.IP + " "
+	}
+	if ids != "b=10.0.0.2 d=10.0.1.4 " {
+		t.Fatalf("members = %s, want b and d", ids)
+	}
+}
@@ -0,0 +1,195 @@
+// internal/fleet/warmpool.go
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"fleetctl/internal/client"
+)
+
+// WarmPoolStatus summarizes the warm pool for status output and the control loop.
+type WarmPoolStatus struct {
+	Size      int `json:"size"`      // configured size (0 when disabled)
+	Ready     int `json:"ready"`     // STOPPED instances ready to start
+	Pending   int `json:"pending"`   // instances still provisioning or stopping
+	Launching int `json:"launching"` // launches of the fill running in the background
+	Launched  int `json:"launched"`  // instances launched by the last completed fill
+}
+
+// warmFill tracks the warm pool launches RefillWarmPool runs in the background, at most
+// one batch per fleet at a time.
+type warmFill struct {
+	mu        sync.Mutex
+	launching int   // instances the running fill launches; 0 when idle
+	launched  int   // instances the last completed fill launched
+	err       error // error of the last completed fill, reported by the next refill
+}
+
+// warmReady reports whether a pool instance can be started right away.
+func warmReady(it client.InstanceInfo) bool {
+	return it.Lifecycle == "STOPPED"
+}
+
+// startWarm starts up to n ready warm pool instances of group and returns the ones that
+// reached RUNNING. Failures are logged and skipped; the caller launches the remainder.
+// Callers hold opMu and the fleet lock.
+func (f *Fleet) startWarm(ctx context.Context, group string, n int) []client.InstanceInfo {
+	if n <= 0 || f.Config.Spec.WarmPoolGroup() != group {
+		return nil
+	}
+	pool, err := f.Client.ListWarmPool(ctx, f.Config.Spec.CompartmentID, f.Config.Metadata.Name)
+	if err != nil {
+		log.Printf("WarmPool: list pool: %v (launching instead)", err)
+		return nil
+	}
+	var ready []client.InstanceInfo
+	for _, it := range pool {
+		if warmReady(it) && f.groupFromName(it.DisplayName) == group && len(ready) < n {
+			ready = append(ready, it)
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+	log.Printf("WarmPool: starting %d stopped instance(s) in group %s", len(ready), group)
+
+	parLaunch := f.Config.Spec.Scaling.ParallelLaunch
+	if parLaunch <= 0 {
+		parLaunch = 5
+	}
+	sem := make(chan struct{}, parLaunch)
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		started []client.InstanceInfo
+	)
+	for _, it := range ready {
+		it := it
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			inst, err := f.Client.StartWarmInstance(ctx, it.ID)
+			if err != nil {
+				log.Printf("WarmPool: start %s: %v", it.ID, err)
+				return
+			}
+			f.Metrics.IncWarmStarted()
+			mu.Lock()
+			started = append(started, inst)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return started
+}
+
+// RefillWarmPool terminates surplus pool instances when the pool shrinks or is disabled
+// and starts launching warm instances until the pool reaches spec.warmPool.size, at
+// most spec.scaling.parallelLaunch per call. The launches, each waiting for provisioning
+// and a STOP, run in the background without opMu or the fleet lock, so scale-ups are not
+// held up; a new batch starts only after the previous one finished. Launched instances
+// carry the warm pool tag from their launch request, so later refills count them as
+// pending. The error of a finished batch is returned by the next call.
+func (f *Fleet) RefillWarmPool(ctx context.Context) (WarmPoolStatus, error) {
+	if f.Client == nil {
+		return WarmPoolStatus{}, fmt.Errorf("OCI client not initialized")
+	}
+	st, missing, err := f.planWarmPool(ctx)
+	f.warm.mu.Lock()
+	defer f.warm.mu.Unlock()
+	st.Launching, st.Launched = f.warm.launching, f.warm.launched
+	fillErr := f.warm.err
+	f.warm.err = nil
+	if fillErr != nil {
+		err = errors.Join(err, fmt.Errorf("launch warm instances: %w", fillErr))
+	}
+	if missing <= 0 || f.warm.launching > 0 {
+		return st, err
+	}
+	cfg, group := f.Config, f.Config.Spec.WarmPoolGroup()
+	log.Printf("WarmPool: launching %d instance(s) into the pool (size=%d ready=%d pending=%d)", missing, st.Size, st.Ready, st.Pending)
+	f.warm.launching = missing
+	st.Launching = missing
+	go func() {
+		created, err := f.Client.LaunchInstances(ctx, cfg, group, missing, client.LaunchOptions{Warm: true})
+		if err != nil {
+			log.Printf("WarmPool: launch: %v", err)
+		}
+		f.warm.mu.Lock()
+		f.warm.launching, f.warm.launched, f.warm.err = 0, len(created), err
+		f.warm.mu.Unlock()
+	}()
+	return st, err
+}
+
+// planWarmPool terminates surplus pool instances and returns the pool status with the
+// number of instances to launch. It holds opMu and the fleet lock.
+func (f *Fleet) planWarmPool(ctx context.Context) (WarmPoolStatus, int, error) {
+	f.opMu.Lock()
+	defer f.opMu.Unlock()
+	ctx, unlock, err := f.acquireLock(ctx, "warm-pool-refill")
+	if err != nil {
+		return WarmPoolStatus{}, 0, err
+	}
+	defer unlock()
+
+	st := WarmPoolStatus{}
+	group := f.Config.Spec.WarmPoolGroup()
+	if group != "" {
+		st.Size = f.Config.Spec.WarmPool.Size
+	}
+	pool, err := f.Client.ListWarmPool(ctx, f.Config.Spec.CompartmentID, f.Config.Metadata.Name)
+	if err != nil {
+		return st, 0, fmt.Errorf("list warm pool: %w", err)
+	}
+	var surplus []string
+	for _, it := range pool {
+		switch {
+		case f.groupFromName(it.DisplayName) != group || st.Ready+st.Pending >= st.Size:
+			surplus = append(surplus, it.ID)
+		case warmReady(it):
+			st.Ready++
+		default:
+			st.Pending++
+		}
+	}
+
+	if len(surplus) > 0 {
+		log.Printf("WarmPool: terminating %d surplus instance(s)", len(surplus))
+		if err := f.Client.TerminateInstances(ctx, surplus); err != nil {
+			return st, 0, fmt.Errorf("terminate surplus warm instances: %w", err)
+		}
+	}
+	f.Metrics.SetWarmPool(st.Size, st.Ready)
+
+	parLaunch := f.Config.Spec.Scaling.ParallelLaunch
+	if parLaunch <= 0 {
+		parLaunch = 5
+	}
+	return st, min(st.Size-st.Ready-st.Pending, parLaunch), nil
+}
+
+// warmPoolSummary renders the warm pool for status output ("" when no pool is configured).
+func (f *Fleet) warmPoolSummary(ctx context.Context) string {
+	if f.Config.Spec.WarmPool == nil {
+		return ""
+	}
+	pool, err := f.Client.ListWarmPool(ctx, f.Config.Spec.CompartmentID, f.Config.Metadata.Name)
+	if err != nil {
+		return fmt.Sprintf("Warm pool: (unavailable: %v)", err)
+	}
+	ready := 0
+	for _, it := range pool {
+		if warmReady(it) {
+			ready++
+		}
+	}
+	return fmt.Sprintf("Warm pool (group %s): size=%d ready=%d pending=%d",
+		f.Config.Spec.WarmPoolGroup(), f.Config.Spec.WarmPool.Size, ready, len(pool)-ready)
+}
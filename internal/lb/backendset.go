@@ -0,0 +1,297 @@
+// internal/lb/backendset.go
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+// Session persistence types of LBSessionPersistenceSpec.
+const (
+	persistLBCookie  = "lb-cookie"
+	persistAppCookie = "app-cookie"
+)
+
+// planHealth builds the health checker of a backend set. path is the health path, port the
+// backend port and proto the protocol used when the spec does not name one.
+func planHealth(field string, hc *config.LBHealthCheckSpec, path string, port int, proto string) (loadbalancer.HealthCheckerDetails, error) {
+	if hc == nil {
+		hc = &config.LBHealthCheckSpec{}
+	}
+	if p := strings.ToUpper(strings.TrimSpace(hc.Protocol)); p != "" {
+		switch {
+		case p != "HTTP" && p != "TCP":
+			return loadbalancer.HealthCheckerDetails{}, fmt.Errorf("%s.healthCheck.protocol %q: use HTTP or TCP", field, hc.Protocol)
+		case p == "HTTP" && path == "":
+			return loadbalancer.HealthCheckerDetails{}, fmt.Errorf("%s.healthCheck.protocol HTTP requires healthPath", field)
+		}
+		proto = p
+	}
+	if hc.Port > 0 {
+		port = hc.Port
+	}
+	d := loadbalancer.HealthCheckerDetails{Protocol: &proto, Port: &port}
+	if proto == "HTTP" {
+		d.UrlPath = &path
+	} else if hc.ReturnCode != 0 || hc.ResponseBodyRegex != "" {
+		return d, fmt.Errorf("%s.healthCheck: returnCode and responseBodyRegex need an HTTP health check", field)
+	}
+	ms := func(name string, v time.Duration) (*int, error) {
+		switch {
+		case v == 0:
+			return nil, nil
+		case v < time.Millisecond:
+			return nil, fmt.Errorf("%s.healthCheck.%s must be at least 1ms", field, name)
+		}
+		n := int(v / time.Millisecond)
+		return &n, nil
+	}
+	var err error
+	if d.IntervalInMillis, err = ms("interval", hc.Interval); err != nil {
+		return d, err
+	}
+	if d.TimeoutInMillis, err = ms("timeout", hc.Timeout); err != nil {
+		return d, err
+	}
+	if hc.Interval > 0 && hc.Timeout > hc.Interval {
+		return d, fmt.Errorf("%s.healthCheck.timeout must not exceed interval", field)
+	}
+	if hc.Retries < 0 || hc.ReturnCode < 0 {
+		return d, fmt.Errorf("%s.healthCheck: retries and returnCode must not be negative", field)
+	}
+	if hc.Retries > 0 {
+		d.Retries = &hc.Retries
+	}
+	if hc.ReturnCode > 0 {
+		d.ReturnCode = &hc.ReturnCode
+	}
+	if hc.ResponseBodyRegex != "" {
+		d.ResponseBodyRegex = &hc.ResponseBodyRegex
+	}
+	return d, nil
+}
+
+// planPersistence builds the app-cookie or LB-cookie session persistence of a backend set;
+// both are nil without persistence.
+func planPersistence(field string, sp *config.LBSessionPersistenceSpec) (*loadbalancer.SessionPersistenceConfigurationDetails, *loadbalancer.LbCookieSessionPersistenceConfigurationDetails, error) {
+	if sp == nil {
+		return nil, nil, nil
+	}
+	field += ".sessionPersistence"
+	fallback := sp.DisableFallback
+	var cookie *string
+	if sp.CookieName != "" {
+		cookie = &sp.CookieName
+	}
+	switch strings.ToLower(strings.TrimSpace(sp.Type)) {
+	case persistAppCookie:
+		switch {
+		case cookie == nil:
+			return nil, nil, fmt.Errorf("%s: app-cookie requires cookieName", field)
+		case sp.Domain != "" || sp.Path != "" || sp.MaxAge != 0 || sp.Secure != nil || sp.HTTPOnly != nil:
+			return nil, nil, fmt.Errorf("%s: domain, path, maxAge, secure and httpOnly apply to lb-cookie only", field)
+		}
+		return &loadbalancer.SessionPersistenceConfigurationDetails{CookieName: cookie, DisableFallback: &fallback}, nil, nil
+	case persistLBCookie:
+		lc := &loadbalancer.LbCookieSessionPersistenceConfigurationDetails{
+			CookieName:      cookie,
+			DisableFallback: &fallback,
+			IsSecure:        sp.Secure,
+			IsHttpOnly:      sp.HTTPOnly,
+		}
+		if sp.Domain != "" {
+			lc.Domain = &sp.Domain
+		}
+		if sp.Path != "" {
+			lc.Path = &sp.Path
+		}
+		if sp.MaxAge < 0 {
+			return nil, nil, fmt.Errorf("%s.maxAge must not be negative", field)
+		}
+		if sp.MaxAge > 0 {
+			secs := int(sp.MaxAge / time.Second)
+			lc.MaxAgeInSeconds = &secs
+		}
+		return nil, lc, nil
+	}
+	return nil, nil, fmt.Errorf("%s.type %q: use lb-cookie or app-cookie", field, sp.Type)
+}
+
+// healthDrift lists how a backend set's health checker differs from want. Optional
+// settings the spec leaves unset are not compared, so OCI defaults are not drift.
+func healthDrift(cur *loadbalancer.HealthChecker, want loadbalancer.HealthCheckerDetails) []string {
+	if cur == nil {
+		return []string{"health checker missing"}
+	}
+	var out []string
+	cmpS := func(name string, have, need *string) {
+		if need != nil && derefS(have) != *need {
+			out = append(out, fmt.Sprintf("health %s %q -> %q", name, derefS(have), *need))
+		}
+	}
+	cmpI := func(name string, have, need *int) {
+		if need != nil && deref(have) != *need {
+			out = append(out, fmt.Sprintf("health %s %d -> %d", name, deref(have), *need))
+		}
+	}
+	if !strings.EqualFold(derefS(cur.Protocol), derefS(want.Protocol)) {
+		out = append(out, fmt.Sprintf("health protocol %s -> %s", derefS(cur.Protocol), derefS(want.Protocol)))
+	}
+	cmpI("port", cur.Port, want.Port)
+	cmpS("path", cur.UrlPath, want.UrlPath)
+	cmpI("interval ms", cur.IntervalInMillis, want.IntervalInMillis)
+	cmpI("timeout ms", cur.TimeoutInMillis, want.TimeoutInMillis)
+	cmpI("retries", cur.Retries, want.Retries)
+	cmpI("return code", cur.ReturnCode, want.ReturnCode)
+	cmpS("body regex", cur.ResponseBodyRegex, want.ResponseBodyRegex)
+	return out
+}
+
+// persistenceDrift reports how a backend set's session persistence differs from want ("" if none).
+func persistenceDrift(cur loadbalancer.BackendSet, want backendSetPlan) string {
+	kind := func(app *loadbalancer.SessionPersistenceConfigurationDetails, lbc *loadbalancer.LbCookieSessionPersistenceConfigurationDetails) string {
+		switch {
+		case app != nil:
+			return persistAppCookie
+		case lbc != nil:
+			return persistLBCookie
+		}
+		return "none"
+	}
+	have, need := kind(cur.SessionPersistenceConfiguration, cur.LbCookieSessionPersistenceConfiguration), kind(want.AppCookie, want.LBCookie)
+	if have != need {
+		return fmt.Sprintf("session persistence %s -> %s", have, need)
+	}
+	switch need {
+	case persistAppCookie:
+		c, w := cur.SessionPersistenceConfiguration, want.AppCookie
+		if derefS(c.CookieName) != derefS(w.CookieName) || derefB(c.DisableFallback) != derefB(w.DisableFallback) {
+			return "app-cookie settings"
+		}
+	case persistLBCookie:
+		c, w := cur.LbCookieSessionPersistenceConfiguration, want.LBCookie
+		changed := derefB(c.DisableFallback) != derefB(w.DisableFallback) ||
+			(w.CookieName != nil && derefS(c.CookieName) != *w.CookieName) ||
+			(w.Domain != nil && derefS(c.Domain) != *w.Domain) ||
+			(w.Path != nil && derefS(c.Path) != *w.Path) ||
+			(w.MaxAgeInSeconds != nil && deref(c.MaxAgeInSeconds) != *w.MaxAgeInSeconds) ||
+			(w.IsSecure != nil && derefB(c.IsSecure) != *w.IsSecure) ||
+			(w.IsHttpOnly != nil && derefB(c.IsHttpOnly) != *w.IsHttpOnly)
+		if changed {
+			return "lb-cookie settings"
+		}
+	}
+	return ""
+}
+
+// backendSetDrift lists how an existing backend set differs from the plan (empty: in sync).
+func backendSetDrift(cur loadbalancer.BackendSet, want backendSetPlan, ssl *loadbalancer.SslConfigurationDetails) []string {
+	var out []string
+	if derefS(cur.Policy) != want.Policy {
+		out = append(out, fmt.Sprintf("policy %s -> %s", derefS(cur.Policy), want.Policy))
+	}
+	out = append(out, healthDrift(cur.HealthChecker, want.Health)...)
+	if d := persistenceDrift(cur, want); d != "" {
+		out = append(out, d)
+	}
+	if d := backendSSLDrift(cur.SslConfiguration, ssl); d != "" {
+		out = append(out, d)
+	}
+	return out
+}
+
+// ensureBackendSet creates the planned backend set, or updates an existing one whose
+// policy, health checker, session persistence or backend TLS drifted.
+func (s *Service) ensureBackendSet(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.BackendSet, want backendSetPlan, ssl *loadbalancer.SslConfigurationDetails) error {
+	if cur, ok := have[want.Name]; ok {
+		return s.updateBackendSet(ctx, lbc, lbID, cur, want, ssl)
+	}
+	name, policy, hc := want.Name, want.Policy, want.Health
+	resp, err := lbc.CreateBackendSet(ctx, loadbalancer.CreateBackendSetRequest{
+		LoadBalancerId: &lbID,
+		CreateBackendSetDetails: loadbalancer.CreateBackendSetDetails{
+			Name:                                    &name,
+			Policy:                                  &policy,
+			HealthChecker:                           &hc,
+			SslConfiguration:                        ssl,
+			SessionPersistenceConfiguration:         want.AppCookie,
+			LbCookieSessionPersistenceConfiguration: want.LBCookie,
+		},
+	})
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "already exists") {
+			return nil
+		}
+		return fmt.Errorf("create backend set %s: %w", name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "create backend set")
+}
+
+// updateBackendSet applies the planned policy, health checker, session persistence and
+// backend TLS to an existing backend set when they drifted, keeping its backends. The
+// set is read again for its ETag, sent as If-Match so backends registered concurrently
+// are not dropped; on 412 it is read and compared again.
+func (s *Service) updateBackendSet(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, bs loadbalancer.BackendSet, want backendSetPlan, ssl *loadbalancer.SslConfigurationDetails) error {
+	if len(backendSetDrift(bs, want, ssl)) == 0 {
+		return nil
+	}
+	name, policy, hc := derefS(bs.Name), want.Policy, want.Health
+	for attempt := 1; ; attempt++ {
+		cur, err := lbc.GetBackendSet(ctx, loadbalancer.GetBackendSetRequest{LoadBalancerId: &lbID, BackendSetName: &name})
+		if err != nil {
+			return fmt.Errorf("get backend set %s: %w", name, err)
+		}
+		drift := backendSetDrift(cur.BackendSet, want, ssl)
+		if len(drift) == 0 {
+			return nil
+		}
+		log.Printf("LB: updating backend set %s: %s", name, strings.Join(drift, "; "))
+		resp, err := lbc.UpdateBackendSet(ctx, loadbalancer.UpdateBackendSetRequest{
+			LoadBalancerId: &lbID,
+			BackendSetName: &name,
+			IfMatch:        cur.ETag,
+			UpdateBackendSetDetails: loadbalancer.UpdateBackendSetDetails{
+				Policy:                                  &policy,
+				Backends:                                backendDetails(cur.Backends),
+				HealthChecker:                           &hc,
+				BackendMaxConnections:                   cur.BackendMaxConnections,
+				SslConfiguration:                        ssl,
+				SessionPersistenceConfiguration:         want.AppCookie,
+				LbCookieSessionPersistenceConfiguration: want.LBCookie,
+			},
+		})
+		if hasStatus(err, http.StatusPreconditionFailed) && attempt < staleRetries {
+			log.Printf("LB: backend set %s changed since it was read, reading it again", name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("update backend set %s: %w", name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "update backend set")
+	}
+}
+
+// backendDetails converts the backends of a backend set for an UpdateBackendSet call.
+func backendDetails(in []loadbalancer.Backend) []loadbalancer.BackendDetails {
+	out := make([]loadbalancer.BackendDetails, 0, len(in))
+	for _, b := range in {
+		out = append(out, loadbalancer.BackendDetails{
+			IpAddress:      b.IpAddress,
+			Port:           b.Port,
+			Weight:         b.Weight,
+			MaxConnections: b.MaxConnections,
+			Backup:         b.Backup,
+			Drain:          b.Drain,
+			Offline:        b.Offline,
+		})
+	}
+	return out
+}
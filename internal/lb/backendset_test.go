@@ -0,0 +1,147 @@
+// internal/lb/backendset_test.go
+package lb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+func healthConfig() config.FleetConfig {
+	cfg := config.FleetConfig{}
+	cfg.Spec.LoadBalancer = config.LoadBalancerSpec{
+		Enabled:     true,
+		BackendPort: 8080,
+		HealthPath:  "/healthz",
+		HealthCheck: &config.LBHealthCheckSpec{
+			Interval:          15 * time.Second,
+			Timeout:           2 * time.Second,
+			Retries:           5,
+			ReturnCode:        204,
+			ResponseBodyRegex: "^ok$",
+		},
+		SessionPersistence: &config.LBSessionPersistenceSpec{Type: "lb-cookie", MaxAge: time.Hour, Path: "/app"},
+	}
+	return cfg
+}
+
+// liveSet is what OCI reports for a backend set created from bs, with OCI defaults filled in.
+func liveSet(bs backendSetPlan) loadbalancer.BackendSet {
+	h := bs.Health
+	interval, timeout, retries, code, regex := 10000, 3000, 3, 200, ""
+	if h.IntervalInMillis == nil {
+		h.IntervalInMillis = &interval
+	}
+	if h.TimeoutInMillis == nil {
+		h.TimeoutInMillis = &timeout
+	}
+	if h.Retries == nil {
+		h.Retries = &retries
+	}
+	if h.ReturnCode == nil {
+		h.ReturnCode = &code
+	}
+	if h.ResponseBodyRegex == nil {
+		h.ResponseBodyRegex = &regex
+	}
+	return loadbalancer.BackendSet{
+		Name:   &bs.Name,
+		Policy: &bs.Policy,
+		HealthChecker: &loadbalancer.HealthChecker{
+			Protocol: h.Protocol, Port: h.Port, UrlPath: h.UrlPath, IntervalInMillis: h.IntervalInMillis,
+			TimeoutInMillis: h.TimeoutInMillis, Retries: h.Retries, ReturnCode: h.ReturnCode, ResponseBodyRegex: h.ResponseBodyRegex,
+		},
+		SessionPersistenceConfiguration:         bs.AppCookie,
+		LbCookieSessionPersistenceConfiguration: bs.LBCookie,
+	}
+}
+
+func TestPlanHealthAndPersistence(t *testing.T) {
+	p, err := planLB(healthConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := p.BackendSets[0].Health
+	if *h.Protocol != "HTTP" || *h.Port != 8080 || *h.UrlPath != "/healthz" || *h.IntervalInMillis != 15000 ||
+		*h.TimeoutInMillis != 2000 || *h.Retries != 5 || *h.ReturnCode != 204 || *h.ResponseBodyRegex != "^ok$" {
+		t.Fatalf("health checker = %v", h)
+	}
+	lc := p.BackendSets[0].LBCookie
+	if p.BackendSets[0].AppCookie != nil || lc == nil || *lc.MaxAgeInSeconds != 3600 || *lc.Path != "/app" || lc.CookieName != nil {
+		t.Fatalf("persistence = %v / %v", p.BackendSets[0].AppCookie, lc)
+	}
+
+	// A declared set inherits the fleet-wide settings unless it has its own.
+	cfg := healthConfig()
+	cfg.Spec.LoadBalancer.BackendSets = []config.LBBackendSetSpec{
+		{Name: "web", Groups: []string{"web"}},
+		{Name: "raw", HealthCheck: &config.LBHealthCheckSpec{Protocol: "tcp", Port: 9000}, SessionPersistence: &config.LBSessionPersistenceSpec{Type: "app-cookie", CookieName: "JSESSIONID"}},
+	}
+	if p, err = planLB(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if web := p.BackendSets[0]; *web.Health.Retries != 5 || web.LBCookie == nil {
+		t.Fatalf("web set did not inherit: %+v", web)
+	}
+	if raw := p.BackendSets[1]; *raw.Health.Protocol != "TCP" || *raw.Health.Port != 9000 || raw.Health.UrlPath != nil || raw.Health.Retries != nil || *raw.AppCookie.CookieName != "JSESSIONID" {
+		t.Fatalf("raw set = %+v", raw)
+	}
+}
+
+func TestBackendSetDrift(t *testing.T) {
+	p, err := planLB(healthConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := p.BackendSets[0]
+	cur := liveSet(want)
+	if d := backendSetDrift(cur, want, nil); len(d) != 0 {
+		t.Fatalf("in-sync drift = %v", d)
+	}
+
+	// Settings the spec leaves unset are OCI defaults, not drift.
+	var plain config.FleetConfig
+	plain.Spec.LoadBalancer = config.LoadBalancerSpec{BackendPort: 80, HealthPath: "/"}
+	bare, _ := planLB(plain)
+	if d := backendSetDrift(liveSet(bare.BackendSets[0]), bare.BackendSets[0], nil); len(d) != 0 {
+		t.Fatalf("defaults reported as drift: %v", d)
+	}
+
+	cfg := healthConfig()
+	cfg.Spec.LoadBalancer.Policy = "least_connections"
+	cfg.Spec.LoadBalancer.HealthCheck.Interval = 30 * time.Second
+	cfg.Spec.LoadBalancer.SessionPersistence = nil
+	changed, _ := planLB(cfg)
+	d := backendSetDrift(cur, changed.BackendSets[0], nil)
+	if strings.Join(d, "; ") != "policy ROUND_ROBIN -> LEAST_CONNECTIONS; health interval ms 15000 -> 30000; session persistence lb-cookie -> none" {
+		t.Fatalf("drift = %v", d)
+	}
+}
+
+func TestPlanRejectsInvalidHealthAndPersistence(t *testing.T) {
+	tests := []struct {
+		name string
+		hc   *config.LBHealthCheckSpec
+		sp   *config.LBSessionPersistenceSpec
+		want string
+	}{
+		{"unknown protocol", &config.LBHealthCheckSpec{Protocol: "UDP"}, nil, "use HTTP or TCP"},
+		{"regex on tcp", &config.LBHealthCheckSpec{Protocol: "TCP", ResponseBodyRegex: "ok"}, nil, "need an HTTP health check"},
+		{"timeout over interval", &config.LBHealthCheckSpec{Interval: time.Second, Timeout: 2 * time.Second}, nil, "must not exceed interval"},
+		{"sub-millisecond", &config.LBHealthCheckSpec{Timeout: time.Microsecond}, nil, "at least 1ms"},
+		{"unknown persistence", nil, &config.LBSessionPersistenceSpec{Type: "ip-hash"}, "use lb-cookie or app-cookie"},
+		{"app cookie without name", nil, &config.LBSessionPersistenceSpec{Type: "app-cookie"}, "requires cookieName"},
+		{"lb-cookie fields on app cookie", nil, &config.LBSessionPersistenceSpec{Type: "app-cookie", CookieName: "s", MaxAge: time.Hour}, "apply to lb-cookie only"},
+	}
+	for _, tt := range tests {
+		cfg := config.FleetConfig{}
+		cfg.Spec.LoadBalancer = config.LoadBalancerSpec{BackendPort: 80, HealthPath: "/", HealthCheck: tt.hc, SessionPersistence: tt.sp}
+		if _, err := planLB(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
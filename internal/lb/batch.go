@@ -0,0 +1,211 @@
+// internal/lb/batch.go
+package lb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+// Change adds a backend to, or removes one from, a backend set.
+type Change struct {
+	BackendSet string
+	Addr       Addr
+	Remove     bool
+}
+
+// BatchResult reports how ApplyChanges applied a set of changes.
+type BatchResult struct {
+	Applied   int // changes that took effect (excluding no-ops)
+	Requests  int // work requests used
+	Saved     int // work requests saved against one per change
+	Fallbacks int // backend sets that fell back to per-backend calls
+}
+
+// ApplyChanges applies changes with one UpdateBackendSet call per backend set: the full
+// backend list is read, edited and written back, keeping the set's policy, health
+// checker, persistence and TLS. When the batched update fails, the set falls back to
+// one CreateBackend/DeleteBackend call per change.
+func (s *Service) ApplyChanges(ctx context.Context, lbID string, changes []Change) (BatchResult, error) {
+	lbc, err := s.lbClient()
+	if err != nil {
+		return BatchResult{}, err
+	}
+	return applyBatched(changes,
+		func(set string, cs []Change) (int, error) { return s.updateBackends(ctx, lbc, lbID, set, cs) },
+		func(set string, c Change) error {
+			if c.Remove {
+				return s.RemoveBackend(ctx, lbID, set, c.Addr.IP, c.Addr.Port)
+			}
+			return s.AddBackend(ctx, lbID, set, c.Addr.IP, c.Addr.Port)
+		})
+}
+
+// applyBatched groups changes by backend set and hands each group to update, which
+// writes the set once and returns how many changes took effect. A set whose update
+// fails gets its changes one by one through apply.
+func applyBatched(changes []Change, update func(set string, cs []Change) (int, error), apply func(set string, c Change) error) (BatchResult, error) {
+	var res BatchResult
+	bySet := map[string][]Change{}
+	var order []string
+	for _, c := range changes {
+		if _, ok := bySet[c.BackendSet]; !ok {
+			order = append(order, c.BackendSet)
+		}
+		bySet[c.BackendSet] = append(bySet[c.BackendSet], c)
+	}
+	var errs []error
+	for _, name := range order {
+		cs := bySet[name]
+		n, err := update(name, cs)
+		if err == nil {
+			if n > 0 {
+				res.Applied += n
+				res.Requests++
+				res.Saved += n - 1
+			}
+			continue
+		}
+		log.Printf("LB: batched update of %s failed, applying %d change(s) one by one: %v", name, len(cs), err)
+		res.Fallbacks++
+		for _, c := range cs {
+			res.Requests++
+			if err := apply(name, c); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			res.Applied++
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// updateBackends writes the backend set with changes applied in one UpdateBackendSet and
+// returns how many changes were not already in place (0: nothing was written). The write
+// carries the ETag of the read as If-Match, so a concurrent change is not overwritten:
+// the update fails with 412 and the set is read and edited again.
+func (s *Service) updateBackends(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID, name string, changes []Change) (int, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := lbc.GetBackendSet(ctx, loadbalancer.GetBackendSetRequest{LoadBalancerId: &lbID, BackendSetName: &name})
+		if err != nil {
+			return 0, fmt.Errorf("get backend set %s: %w", name, err)
+		}
+		bs := resp.BackendSet
+		backends, n := editBackends(backendDetails(bs.Backends), changes)
+		if n == 0 {
+			return 0, nil
+		}
+		log.Printf("LB: updating backend set %s: %d backend change(s), %d backend(s) after", name, n, len(backends))
+		ur, err := lbc.UpdateBackendSet(ctx, loadbalancer.UpdateBackendSetRequest{
+			LoadBalancerId: &lbID,
+			BackendSetName: &name,
+			IfMatch:        resp.ETag,
+			UpdateBackendSetDetails: loadbalancer.UpdateBackendSetDetails{
+				Policy:                                  bs.Policy,
+				Backends:                                backends,
+				HealthChecker:                           healthCheckerDetails(bs.HealthChecker),
+				BackendMaxConnections:                   bs.BackendMaxConnections,
+				SslConfiguration:                        sslDetails(bs.SslConfiguration),
+				SessionPersistenceConfiguration:         bs.SessionPersistenceConfiguration,
+				LbCookieSessionPersistenceConfiguration: bs.LbCookieSessionPersistenceConfiguration,
+			},
+		})
+		if hasStatus(err, http.StatusPreconditionFailed) && attempt < staleRetries {
+			log.Printf("LB: backend set %s changed since it was read, reading it again", name)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("update backend set %s: %w", name, err)
+		}
+		return n, s.waitFor(ctx, ur.OpcWorkRequestId, "update backend set")
+	}
+}
+
+// editBackends applies changes to a backend list: removals drop the backend with that
+// IP and port, additions append one that is not there yet. Existing backends keep their
+// weight and flags. It returns the new list, sorted by IP and port, and how many changes
+// were not already in place.
+func editBackends(cur []loadbalancer.BackendDetails, changes []Change) ([]loadbalancer.BackendDetails, int) {
+	return editAddrs(cur, changes,
+		func(b loadbalancer.BackendDetails) Addr { return Addr{IP: derefS(b.IpAddress), Port: deref(b.Port)} },
+		func(a Addr) loadbalancer.BackendDetails {
+			return loadbalancer.BackendDetails{IpAddress: &a.IP, Port: &a.Port}
+		})
+}
+
+// editAddrs is editBackends for any backend type: addr reads a backend's address and
+// backend builds a new one.
+func editAddrs[B any](cur []B, changes []Change, addr func(B) Addr, backend func(Addr) B) ([]B, int) {
+	byAddr := make(map[Addr]B, len(cur))
+	for _, b := range cur {
+		byAddr[addr(b)] = b
+	}
+	n := 0
+	for _, c := range changes {
+		_, ok := byAddr[c.Addr]
+		switch {
+		case c.Remove && ok:
+			delete(byAddr, c.Addr)
+			n++
+		case !c.Remove && !ok:
+			byAddr[c.Addr] = backend(c.Addr)
+			n++
+		}
+	}
+	keys := make([]Addr, 0, len(byAddr))
+	for a := range byAddr {
+		keys = append(keys, a)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].IP != keys[j].IP {
+			return keys[i].IP < keys[j].IP
+		}
+		return keys[i].Port < keys[j].Port
+	})
+	out := make([]B, 0, len(keys))
+	for _, a := range keys {
+		out = append(out, byAddr[a])
+	}
+	return out, n
+}
+
+// healthCheckerDetails converts a backend set's health checker for an update call.
+func healthCheckerDetails(h *loadbalancer.HealthChecker) *loadbalancer.HealthCheckerDetails {
+	if h == nil {
+		return nil
+	}
+	return &loadbalancer.HealthCheckerDetails{
+		Protocol:          h.Protocol,
+		UrlPath:           h.UrlPath,
+		Port:              h.Port,
+		ReturnCode:        h.ReturnCode,
+		Retries:           h.Retries,
+		TimeoutInMillis:   h.TimeoutInMillis,
+		IntervalInMillis:  h.IntervalInMillis,
+		ResponseBodyRegex: h.ResponseBodyRegex,
+		IsForcePlainText:  h.IsForcePlainText,
+	}
+}
+
+// sslDetails converts a backend set's SSL configuration for an update call.
+func sslDetails(c *loadbalancer.SslConfiguration) *loadbalancer.SslConfigurationDetails {
+	if c == nil {
+		return nil
+	}
+	return &loadbalancer.SslConfigurationDetails{
+		VerifyDepth:                    c.VerifyDepth,
+		VerifyPeerCertificate:          c.VerifyPeerCertificate,
+		HasSessionResumption:           c.HasSessionResumption,
+		TrustedCertificateAuthorityIds: c.TrustedCertificateAuthorityIds,
+		CertificateIds:                 c.CertificateIds,
+		CertificateName:                c.CertificateName,
+		Protocols:                      c.Protocols,
+		CipherSuiteName:                c.CipherSuiteName,
+		ServerOrderPreference:          loadbalancer.SslConfigurationDetailsServerOrderPreferenceEnum(c.ServerOrderPreference),
+	}
+}
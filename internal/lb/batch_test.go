@@ -0,0 +1,78 @@
+// internal/lb/batch_test.go
+package lb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+func backendList(bs []loadbalancer.BackendDetails) string {
+	out := make([]string, 0, len(bs))
+	for _, b := range bs {
+		out = append(out, fmt.Sprintf("%s:%d/%d", derefS(b.IpAddress), deref(b.Port), deref(b.Weight)))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestEditBackends(t *testing.T) {
+	weight := 3
+	backend := func(ip string, port int) loadbalancer.BackendDetails {
+		return loadbalancer.BackendDetails{IpAddress: &ip, Port: &port, Weight: &weight}
+	}
+	cur := []loadbalancer.BackendDetails{backend("10.0.0.3", 8080), backend("10.0.0.1", 8080), backend("10.0.0.2", 9090)}
+	add := func(ip string, port int) Change { return Change{BackendSet: "web", Addr: Addr{IP: ip, Port: port}} }
+	rm := func(ip string, port int) Change { c := add(ip, port); c.Remove = true; return c }
+
+	cases := []struct {
+		name    string
+		changes []Change
+		want    string
+		n       int
+	}{
+		{"no changes", nil, "10.0.0.1:8080/3,10.0.0.2:9090/3,10.0.0.3:8080/3", 0},
+		{"replace one", []Change{rm("10.0.0.3", 8080), add("10.0.0.4", 8080)}, "10.0.0.1:8080/3,10.0.0.2:9090/3,10.0.0.4:8080/0", 2},
+		{"move to a new port", []Change{rm("10.0.0.2", 9090), add("10.0.0.2", 8080)}, "10.0.0.1:8080/3,10.0.0.2:8080/0,10.0.0.3:8080/3", 2},
+		{"already in place", []Change{add("10.0.0.1", 8080), rm("10.0.0.9", 8080), rm("10.0.0.2", 8080)}, "10.0.0.1:8080/3,10.0.0.2:9090/3,10.0.0.3:8080/3", 0},
+		{"duplicate add", []Change{add("10.0.0.5", 8080), add("10.0.0.5", 8080)}, "10.0.0.1:8080/3,10.0.0.2:9090/3,10.0.0.3:8080/3,10.0.0.5:8080/0", 1},
+		{"drain", []Change{rm("10.0.0.1", 8080), rm("10.0.0.2", 9090), rm("10.0.0.3", 8080)}, "", 3},
+	}
+	for _, tc := range cases {
+		got, n := editBackends(cur, tc.changes)
+		if s := backendList(got); s != tc.want || n != tc.n {
+			t.Fatalf("%s: editBackends = %s (%d changes), want %s (%d)", tc.name, s, n, tc.want, tc.n)
+		}
+	}
+}
+
+// serviceError is a common.ServiceError with a fixed HTTP status.
+type serviceError struct{ status int }
+
+func (e serviceError) Error() string {
+	return fmt.Sprintf("Error returned by Load Balancing Service. Http Status Code: %d", e.status)
+}
+func (e serviceError) GetHTTPStatusCode() int  { return e.status }
+func (e serviceError) GetMessage() string      { return "" }
+func (e serviceError) GetCode() string         { return "" }
+func (e serviceError) GetOpcRequestID() string { return "" }
+
+func TestHasStatus(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+		want bool
+	}{
+		{"nil", nil, 412, false},
+		{"precondition failed", serviceError{412}, 412, true},
+		{"other status", serviceError{409}, 412, false},
+		{"status only in the text", fmt.Errorf("backend 10.0.0.1:412 gone"), 412, false},
+	}
+	for _, c := range cases {
+		if got := hasStatus(c.err, c.code); got != c.want {
+			t.Fatalf("%s: hasStatus = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
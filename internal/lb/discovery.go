@@ -0,0 +1,226 @@
+// internal/lb/discovery.go
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"strings"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+	"github.com/oracle/oci-go-sdk/v65/networkloadbalancer"
+)
+
+// Freeform tags identifying the fleet a load balancer belongs to.
+const (
+	fleetTagKey    = "fleetctl-fleet"
+	fleetUIDTagKey = "fleetctl-fleet-uid"
+)
+
+// Identity is how Ensure recognises the fleet's load balancer: the OCID recorded in state
+// first, else a load balancer in the compartment tagged with the fleet name and UID.
+type Identity struct {
+	ID  string // LB OCID from state; "" when none was recorded
+	UID string // fleet UID (fleetctl-fleet-uid tag); "" matches on fleet tag and display name
+}
+
+// candidate is a load balancer listed in the fleet's compartment.
+type candidate struct {
+	ID   string
+	Name string
+	Tags map[string]string
+}
+
+// fleetTags are the freeform tags the fleet's load balancer is created with.
+func fleetTags(cfg config.FleetConfig, who Identity) map[string]string {
+	tags := map[string]string{fleetTagKey: cfg.Metadata.Name}
+	if who.UID != "" {
+		tags[fleetUIDTagKey] = who.UID
+	}
+	return tags
+}
+
+// pickLB selects the fleet's load balancer among cands: the one tagged with the fleet
+// name and UID, else one named displayName and tagged for the fleet that predates fleet
+// UIDs (adopt: the caller tags it with the UID). Without a UID the fleet tag and display
+// name decide. "" means there is none yet; several matches are an error.
+func pickLB(cands []candidate, fleet, displayName, uid string) (id string, adopt bool, err error) {
+	var tagged, legacy []string
+	for _, c := range cands {
+		if f, ok := c.Tags[fleetTagKey]; ok && f != fleet {
+			continue
+		}
+		have := c.Tags[fleetUIDTagKey]
+		switch {
+		case uid != "" && have == uid && c.Tags[fleetTagKey] == fleet:
+			tagged = append(tagged, c.ID)
+		case c.Name == displayName && (uid == "" || have == ""):
+			legacy = append(legacy, c.ID)
+		}
+	}
+	dup := func(ids []string) error {
+		return fmt.Errorf("found %d load balancers for fleet %s (uid %s): %s; delete the extra ones or record the right OCID as lb.id in state",
+			len(ids), fleet, orNone(uid), strings.Join(ids, ", "))
+	}
+	switch {
+	case len(tagged) == 1:
+		return tagged[0], false, nil
+	case len(tagged) > 1:
+		return "", false, dup(tagged)
+	case len(legacy) == 1:
+		return legacy[0], uid != "", nil
+	case len(legacy) > 1:
+		return "", false, dup(legacy)
+	}
+	return "", false, nil
+}
+
+// candidateTags returns the tags of the candidate with the given OCID.
+func candidateTags(cands []candidate, id string) map[string]string {
+	for _, c := range cands {
+		if c.ID == id {
+			return c.Tags
+		}
+	}
+	return nil
+}
+
+// withUID returns have plus the fleet UID tag; false when uid is empty or already there.
+func withUID(have map[string]string, uid string) (map[string]string, bool) {
+	if uid == "" || have[fleetUIDTagKey] == uid {
+		return nil, false
+	}
+	tags := maps.Clone(have)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	tags[fleetUIDTagKey] = uid
+	return tags, true
+}
+
+// isNotFound reports whether err is OCI's answer for a missing resource.
+func isNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// findLoadBalancer returns the OCID of the fleet's load balancer, "" when there is none.
+// The OCID recorded in state is used while that load balancer exists; otherwise every
+// page of the compartment's load balancers is searched by tag (see pickLB).
+func (s *Service) findLoadBalancer(ctx context.Context, lbc loadbalancer.LoadBalancerClient, cfg config.FleetConfig, who Identity) (string, error) {
+	if strings.HasPrefix(who.ID, "ocid1.loadbalancer.") {
+		resp, err := lbc.GetLoadBalancer(ctx, loadbalancer.GetLoadBalancerRequest{LoadBalancerId: &who.ID})
+		switch {
+		case err == nil && !lbGone(resp.LifecycleState):
+			return who.ID, s.tagUID(ctx, lbc, who.ID, resp.FreeformTags, who.UID)
+		case err != nil && !isNotFound(err):
+			return "", fmt.Errorf("get load balancer %s: %w", who.ID, err)
+		}
+		log.Printf("LB: %s recorded in state no longer exists; looking the fleet's load balancer up by tag", who.ID)
+	}
+	var cands []candidate
+	req := loadbalancer.ListLoadBalancersRequest{CompartmentId: &cfg.Spec.CompartmentID}
+	for {
+		resp, err := lbc.ListLoadBalancers(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("list load balancers: %w", err)
+		}
+		for _, item := range resp.Items {
+			if item.Id == nil || lbGone(item.LifecycleState) {
+				continue
+			}
+			cands = append(cands, candidate{ID: *item.Id, Name: derefS(item.DisplayName), Tags: item.FreeformTags})
+		}
+		if resp.OpcNextPage == nil {
+			break
+		}
+		req.Page = resp.OpcNextPage
+	}
+	id, adopt, err := pickLB(cands, cfg.Metadata.Name, lbDisplayName(cfg), who.UID)
+	if err != nil || !adopt {
+		return id, err
+	}
+	return id, s.tagUID(ctx, lbc, id, candidateTags(cands, id), who.UID)
+}
+
+// tagUID adds the fleet UID tag to a load balancer created before fleet UIDs.
+func (s *Service) tagUID(ctx context.Context, lbc loadbalancer.LoadBalancerClient, id string, have map[string]string, uid string) error {
+	tags, ok := withUID(have, uid)
+	if !ok {
+		return nil
+	}
+	log.Printf("LB: tagging %s with %s=%s", id, fleetUIDTagKey, uid)
+	resp, err := lbc.UpdateLoadBalancer(ctx, loadbalancer.UpdateLoadBalancerRequest{
+		LoadBalancerId:            &id,
+		UpdateLoadBalancerDetails: loadbalancer.UpdateLoadBalancerDetails{FreeformTags: tags},
+	})
+	if err != nil {
+		return fmt.Errorf("tag load balancer %s: %w", id, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "tag load balancer")
+}
+
+func lbGone(st loadbalancer.LoadBalancerLifecycleStateEnum) bool {
+	return st == loadbalancer.LoadBalancerLifecycleStateDeleted || st == loadbalancer.LoadBalancerLifecycleStateDeleting
+}
+
+// findLoadBalancer is Service.findLoadBalancer for network load balancers.
+func (s *NetworkService) findLoadBalancer(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, cfg config.FleetConfig, who Identity) (string, error) {
+	if strings.HasPrefix(who.ID, "ocid1.networkloadbalancer.") {
+		resp, err := c.GetNetworkLoadBalancer(ctx, networkloadbalancer.GetNetworkLoadBalancerRequest{NetworkLoadBalancerId: &who.ID})
+		switch {
+		case err == nil && !nlbGone(resp.LifecycleState):
+			return who.ID, s.tagUID(ctx, c, who.ID, resp.FreeformTags, who.UID)
+		case err != nil && !isNotFound(err):
+			return "", fmt.Errorf("get network load balancer %s: %w", who.ID, err)
+		}
+		log.Printf("NLB: %s recorded in state no longer exists; looking the fleet's load balancer up by tag", who.ID)
+	}
+	var cands []candidate
+	req := networkloadbalancer.ListNetworkLoadBalancersRequest{CompartmentId: &cfg.Spec.CompartmentID}
+	for {
+		resp, err := c.ListNetworkLoadBalancers(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("list network load balancers: %w", err)
+		}
+		for _, item := range resp.Items {
+			if item.Id == nil || nlbGone(item.LifecycleState) {
+				continue
+			}
+			cands = append(cands, candidate{ID: *item.Id, Name: derefS(item.DisplayName), Tags: item.FreeformTags})
+		}
+		if resp.OpcNextPage == nil {
+			break
+		}
+		req.Page = resp.OpcNextPage
+	}
+	id, adopt, err := pickLB(cands, cfg.Metadata.Name, lbDisplayName(cfg), who.UID)
+	if err != nil || !adopt {
+		return id, err
+	}
+	return id, s.tagUID(ctx, c, id, candidateTags(cands, id), who.UID)
+}
+
+// tagUID adds the fleet UID tag to a network load balancer created before fleet UIDs.
+func (s *NetworkService) tagUID(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id string, have map[string]string, uid string) error {
+	tags, ok := withUID(have, uid)
+	if !ok {
+		return nil
+	}
+	log.Printf("NLB: tagging %s with %s=%s", id, fleetUIDTagKey, uid)
+	resp, err := c.UpdateNetworkLoadBalancer(ctx, networkloadbalancer.UpdateNetworkLoadBalancerRequest{
+		NetworkLoadBalancerId:            &id,
+		UpdateNetworkLoadBalancerDetails: networkloadbalancer.UpdateNetworkLoadBalancerDetails{FreeformTags: tags},
+	})
+	if err != nil {
+		return fmt.Errorf("tag network load balancer %s: %w", id, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "tag network load balancer")
+}
+
+func nlbGone(st networkloadbalancer.LifecycleStateEnum) bool {
+	return st == networkloadbalancer.LifecycleStateDeleted || st == networkloadbalancer.LifecycleStateDeleting
+}
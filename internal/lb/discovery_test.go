@@ -0,0 +1,76 @@
+// internal/lb/discovery_test.go
+package lb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPickLB(t *testing.T) {
+	tagged := func(id, name, fleet, uid string) candidate {
+		tags := map[string]string{fleetTagKey: fleet}
+		if uid != "" {
+			tags[fleetUIDTagKey] = uid
+		}
+		return candidate{ID: id, Name: name, Tags: tags}
+	}
+	cases := []struct {
+		name      string
+		cands     []candidate
+		uid       string
+		want      string
+		wantAdopt bool
+		wantErr   string
+	}{
+		{"none", nil, "u1", "", false, ""},
+		{"tagged", []candidate{
+			tagged("a", "web-lb", "web", "u2"), // same name, another fleet UID
+			tagged("b", "renamed", "web", "u1"),
+			tagged("c", "web-lb", "api", "u1"),
+		}, "u1", "b", false, ""},
+		{"tag beats a legacy name match", []candidate{
+			{ID: "a", Name: "web-lb"},
+			tagged("b", "web-lb", "web", "u1"),
+		}, "u1", "b", false, ""},
+		{"legacy adopted", []candidate{
+			tagged("a", "web-lb", "web", ""),
+			{ID: "b", Name: "other"},
+		}, "u1", "a", true, ""},
+		{"legacy without a fleet UID", []candidate{tagged("a", "web-lb", "web", "u1")}, "", "a", false, ""},
+		{"another fleet's name", []candidate{tagged("a", "web-lb", "api", "")}, "u1", "", false, ""},
+		{"duplicates", []candidate{
+			tagged("a", "web-lb", "web", "u1"),
+			tagged("b", "web-lb-2", "web", "u1"),
+		}, "u1", "", false, "found 2 load balancers for fleet web (uid u1): a, b"},
+		{"legacy duplicates", []candidate{{ID: "a", Name: "web-lb"}, {ID: "b", Name: "web-lb"}}, "", "", false, "found 2 load balancers"},
+	}
+	for _, tc := range cases {
+		id, adopt, err := pickLB(tc.cands, "web", "web-lb", tc.uid)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("%s: err = %v, want %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || id != tc.want || adopt != tc.wantAdopt {
+			t.Fatalf("%s: pickLB = %q, adopt %v, %v; want %q, adopt %v", tc.name, id, adopt, err, tc.want, tc.wantAdopt)
+		}
+	}
+
+	if tags, ok := withUID(map[string]string{fleetTagKey: "web"}, "u1"); !ok || tags[fleetUIDTagKey] != "u1" || tags[fleetTagKey] != "web" {
+		t.Fatalf("withUID = %v, %v", tags, ok)
+	}
+	if _, ok := withUID(map[string]string{fleetUIDTagKey: "u1"}, "u1"); ok {
+		t.Fatal("withUID retags a load balancer that has the UID")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if !isNotFound(serviceError{404}) {
+		t.Fatalf("404 service error not reported as not found")
+	}
+	if isNotFound(serviceError{500}) || isNotFound(fmt.Errorf("backend set web-404 busy")) {
+		t.Fatalf("non-404 error reported as not found")
+	}
+}
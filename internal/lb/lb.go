@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,6 +15,40 @@ import (
 	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
 )
 
+// Load balancer types accepted by spec.loadBalancer.type.
+const (
+	TypeApplication = "application"
+	TypeNetwork     = "network"
+)
+
+// Balancer is a load balancer the fleet registers its instances in. Service (the Layer-7
+// load balancer) and NetworkService (the network load balancer) implement it, so scale,
+// rolling restart and reconcile treat both types the same way.
+type Balancer interface {
+	// Ensure creates or updates the load balancer from the spec and returns which backend
+	// set each instance group belongs in; who identifies the existing load balancer.
+	Ensure(ctx context.Context, cfg config.FleetConfig, who Identity) (Topology, error)
+	// ApplyChanges adds and removes backends, batching them per backend set.
+	ApplyChanges(ctx context.Context, lbID string, changes []Change) (BatchResult, error)
+	// BackendAddrs lists the backends registered in a backend set.
+	BackendAddrs(ctx context.Context, lbID, backendSet string) ([]Addr, error)
+	// CountAllBackends sums the backends of every backend set of the topology.
+	CountAllBackends(ctx context.Context, t Topology) (int, error)
+}
+
+// Open returns the Balancer for spec.type: the Layer-7 load balancer by default, the
+// network load balancer for "network".
+func Open(provider common.ConfigurationProvider, region string, spec config.LoadBalancerSpec) (Balancer, error) {
+	switch strings.ToLower(strings.TrimSpace(spec.Type)) {
+	case "", TypeApplication:
+		return New(provider, region), nil
+	case TypeNetwork:
+		return NewNetwork(provider, region), nil
+	default:
+		return nil, fmt.Errorf("loadBalancer.type %q: expected application or network", spec.Type)
+	}
+}
+
 // Service wraps OCI Load Balancer operations needed by fleetctl.
 type Service struct {
 	Provider common.ConfigurationProvider
@@ -36,6 +71,16 @@ func (s *Service) lbClient() (loadbalancer.LoadBalancerClient, error) {
 	return c, nil
 }
 
+// staleRetries bounds the read-modify-write attempts of a backend set update whose
+// If-Match ETag went stale because the set changed after it was read.
+const staleRetries = 3
+
+// hasStatus reports whether err is an OCI service error with the HTTP status code.
+func hasStatus(err error, code int) bool {
+	se, ok := common.IsServiceError(err)
+	return ok && se.GetHTTPStatusCode() == code
+}
+
 // Backoff/retry helpers for transient throttling or ephemeral LB failures.
 func isThrottleError(err error) bool {
 	if err == nil {
@@ -101,63 +146,51 @@ func (s *Service) waitWorkRequest(ctx context.Context, id string, label string)
 	}
 }
 
-// derived resource names
-func (s *Service) names(cfg config.FleetConfig) (displayName, backendSet, listener string) {
-	displayName = fmt.Sprintf("%s-lb", cfg.Metadata.Name)
-	backendSet = "fleet-backendset"
-	listener = "http-listener"
-	return
+// lbDisplayName is the display name of the fleet's load balancer.
+func lbDisplayName(cfg config.FleetConfig) string {
+	return fmt.Sprintf("%s-lb", cfg.Metadata.Name)
 }
 
-// Ensure creates or ensures existence of LB, backend set and listener.
-// Returns LB OCID, backend set name and listener name.
-func (s *Service) Ensure(ctx context.Context, cfg config.FleetConfig) (string, string, string, error) {
+// Ensure creates or ensures existence of the LB, its backend sets and listeners, and
+// brings the live configuration in line with the spec: shape and bandwidth, backend set
+// policy, health checks and persistence, listeners, hostname and path routing,
+// certificates and backend TLS (uploading a rotated certificate and switching the
+// listener to it). Each change is logged. Settings OCI cannot change in place are
+// reported in Topology.NeedsReplacement. The returned Topology also says which backend
+// set each instance group belongs in. The load balancer is found by who (see
+// findLoadBalancer) and created with the fleet's tags when there is none.
+func (s *Service) Ensure(ctx context.Context, cfg config.FleetConfig, who Identity) (Topology, error) {
 	if s == nil || s.Provider == nil {
-		return "", "", "", fmt.Errorf("lb service not initialized")
+		return Topology{}, fmt.Errorf("lb service not initialized")
 	}
 	spec := cfg.Spec.LoadBalancer
 	if !spec.Enabled {
-		return "", "", "", fmt.Errorf("load balancer is disabled in config")
+		return Topology{}, fmt.Errorf("load balancer is disabled in config")
 	}
 
 	lbc, err := s.lbClient()
 	if err != nil {
-		return "", "", "", err
+		return Topology{}, err
 	}
 
-	displayName, backendSet, listener := s.names(cfg)
-
-	// 1) Find or create Load Balancer
-	var lbID string
-	{
-		req := loadbalancer.ListLoadBalancersRequest{
-			CompartmentId: &cfg.Spec.CompartmentID,
-		}
-		resp, err := lbc.ListLoadBalancers(ctx, req)
-		if err != nil {
-			return "", "", "", fmt.Errorf("list load balancers: %w", err)
-		}
-		for _, item := range resp.Items {
-			if item.DisplayName != nil && *item.DisplayName == displayName {
-				if item.Id != nil {
-					lbID = *item.Id
-					break
-				}
-			}
-		}
+	displayName := lbDisplayName(cfg)
+	plan, err := planLB(cfg)
+	if err != nil {
+		return Topology{}, err
 	}
 
+	// 1) Find (see findLoadBalancer) or create the load balancer
+	lbID, err := s.findLoadBalancer(ctx, lbc, cfg, who)
+	if err != nil {
+		return Topology{}, err
+	}
 	if lbID == "" {
-		shapeName := "flexible"
+		shapeName := lbShape
 		minBw := spec.MinBandwidthMbps
 		maxBw := spec.MaxBandwidthMbps
 		subnetIds := []string{strings.TrimSpace(spec.SubnetID)}
 		if subnetIds[0] == "" {
-			return "", "", "", fmt.Errorf("loadBalancer.subnetId must be set")
-		}
-		// Freeform tags: tag LB with the fleet name for traceability
-		ftags := map[string]string{
-			"fleetctl-fleet": cfg.Metadata.Name,
+			return Topology{}, fmt.Errorf("loadBalancer.subnetId must be set")
 		}
 		details := loadbalancer.CreateLoadBalancerDetails{
 			CompartmentId: &cfg.Spec.CompartmentID,
@@ -168,130 +201,98 @@ func (s *Service) Ensure(ctx context.Context, cfg config.FleetConfig) (string, s
 				MinimumBandwidthInMbps: &minBw,
 				MaximumBandwidthInMbps: &maxBw,
 			},
-			SubnetIds:    subnetIds,
-			FreeformTags: ftags,
+			SubnetIds: subnetIds,
+			// Freeform tags: fleet name and UID, used to find the LB again
+			FreeformTags: fleetTags(cfg, who),
 		}
 		resp, err := lbc.CreateLoadBalancer(ctx, loadbalancer.CreateLoadBalancerRequest{
 			CreateLoadBalancerDetails: details,
 		})
 		if err != nil {
-			return "", "", "", fmt.Errorf("create load balancer: %w", err)
+			return Topology{}, fmt.Errorf("create load balancer: %w", err)
 		}
 		if resp.OpcWorkRequestId != nil {
 			if err := s.waitWorkRequest(ctx, *resp.OpcWorkRequestId, "create load balancer"); err != nil {
-				return "", "", "", err
+				return Topology{}, err
 			}
 		}
-		// Fetch again to obtain the ID by name
-		listResp, err := lbc.ListLoadBalancers(ctx, loadbalancer.ListLoadBalancersRequest{
-			CompartmentId: &cfg.Spec.CompartmentID,
-		})
-		if err != nil {
-			return "", "", "", fmt.Errorf("list after create: %w", err)
-		}
-		for _, item := range listResp.Items {
-			if item.DisplayName != nil && *item.DisplayName == displayName && item.Id != nil {
-				lbID = *item.Id
-				break
-			}
+		// The create call returns no OCID: look the new LB up by its tags
+		if lbID, err = s.findLoadBalancer(ctx, lbc, cfg, Identity{UID: who.UID}); err != nil {
+			return Topology{}, fmt.Errorf("find created load balancer: %w", err)
 		}
 		if lbID == "" {
-			return "", "", "", fmt.Errorf("created load balancer but could not resolve its ID")
+			return Topology{}, fmt.Errorf("created load balancer but could not resolve its ID")
 		}
 	}
 
-	// 2) Ensure Backend Set
-	{
-		_, err := lbc.GetBackendSet(ctx, loadbalancer.GetBackendSetRequest{
-			LoadBalancerId: &lbID,
-			BackendSetName: &backendSet,
-		})
-		if err != nil {
-			// If not found, create
-			if !strings.Contains(strings.ToLower(err.Error()), "notfound") &&
-				!strings.Contains(strings.ToLower(err.Error()), "404") {
-				return "", "", "", fmt.Errorf("get backend set: %w", err)
-			}
-			policy := spec.Policy
-			if strings.TrimSpace(policy) == "" {
-				policy = "ROUND_ROBIN"
-			}
-			proto := "HTTP"
-			hp := strings.TrimSpace(spec.HealthPath)
-			port := spec.BackendPort
-			hc := loadbalancer.HealthCheckerDetails{
-				Protocol: &proto,
-				UrlPath:  &hp,
-				Port:     &port,
-			}
-			cbs := loadbalancer.CreateBackendSetDetails{
-				Name:                                    &backendSet,
-				Policy:                                  &policy,
-				HealthChecker:                           &hc,
-				SessionPersistenceConfiguration:         nil,
-				LbCookieSessionPersistenceConfiguration: nil,
-				SslConfiguration:                        nil,
-				Backends:                                nil,
-			}
-			resp, err := lbc.CreateBackendSet(ctx, loadbalancer.CreateBackendSetRequest{
-				LoadBalancerId:          &lbID,
-				CreateBackendSetDetails: cbs,
-			})
-			if err != nil {
-				return "", "", "", fmt.Errorf("create backend set: %w", err)
-			}
-			if resp.OpcWorkRequestId != nil {
-				if err := s.waitWorkRequest(ctx, *resp.OpcWorkRequestId, "create backend set"); err != nil {
-					return "", "", "", err
-				}
-			}
+	// 2) Shape and bandwidth (in place), settings that need a new LB (reported), then
+	// certificates: the backend sets and listeners refer to them by name
+	lbResp, err := lbc.GetLoadBalancer(ctx, loadbalancer.GetLoadBalancerRequest{LoadBalancerId: &lbID})
+	if err != nil {
+		return Topology{}, fmt.Errorf("get load balancer: %w", err)
+	}
+	cur := lbResp.LoadBalancer
+	if err := s.ensureShape(ctx, lbc, lbID, cur, spec); err != nil {
+		return Topology{}, err
+	}
+	replace := replacementDrift(cur, spec)
+	if len(replace) > 0 {
+		log.Printf("LB: %s needs replacement to apply: %s (delete it to have it recreated)", displayName, strings.Join(replace, "; "))
+	}
+	if err := s.ensureCertificates(ctx, lbc, lbID, cur.Certificates, plan.Certs); err != nil {
+		return Topology{}, err
+	}
+
+	// 3) Backend sets
+	for _, bs := range plan.BackendSets {
+		if err := s.ensureBackendSet(ctx, lbc, lbID, cur.BackendSets, bs, plan.BackendSSL); err != nil {
+			return Topology{}, err
 		}
 	}
 
-	// 3) Ensure Listener
-	{
-		// Some SDKs do not expose GetListener; fetch LB and inspect Listeners map instead.
-		lbResp, err := lbc.GetLoadBalancer(ctx, loadbalancer.GetLoadBalancerRequest{
-			LoadBalancerId: &lbID,
-		})
-		if err != nil {
-			return "", "", "", fmt.Errorf("get load balancer for listeners: %w", err)
+	// 4) Routing the listeners refer to: hostnames, path routes, the HTTP->HTTPS redirect
+	if err := s.ensureHostnames(ctx, lbc, lbID, cur.Hostnames, plan.Hostnames); err != nil {
+		return Topology{}, err
+	}
+	for _, prs := range plan.PathRouteSets {
+		if err := s.ensurePathRouteSet(ctx, lbc, lbID, cur.PathRouteSets, prs); err != nil {
+			return Topology{}, err
 		}
-		has := false
-		if lbResp.LoadBalancer.Listeners != nil {
-			if _, ok := lbResp.LoadBalancer.Listeners[listener]; ok {
-				has = true
-			}
+	}
+	if slices.ContainsFunc(plan.Listeners, func(l listenerPlan) bool { return l.Name == redirectListener }) {
+		if err := s.ensureRedirectRuleSet(ctx, lbc, lbID, cur.RuleSets, spec.ListenerPort); err != nil {
+			return Topology{}, err
 		}
-		if !has {
-			proto := "HTTP"
-			lport := spec.ListenerPort
-			cld := loadbalancer.CreateListenerDetails{
-				Name:                  &listener,
-				DefaultBackendSetName: &backendSet,
-				Port:                  &lport,
-				Protocol:              &proto,
-			}
-			resp, err := lbc.CreateListener(ctx, loadbalancer.CreateListenerRequest{
-				LoadBalancerId:        &lbID,
-				CreateListenerDetails: cld,
-			})
-			if err != nil {
-				// If already exists, treat as success
-				if strings.Contains(strings.ToLower(err.Error()), "already exists") {
-					// noop
-				} else {
-					return "", "", "", fmt.Errorf("create listener: %w", err)
-				}
-			} else if resp.OpcWorkRequestId != nil {
-				if err := s.waitWorkRequest(ctx, *resp.OpcWorkRequestId, "create listener"); err != nil {
-					return "", "", "", err
-				}
-			}
+	}
+
+	// 5) Listeners, after removing those an earlier spec created (freeing their ports)
+	if err := s.pruneListeners(ctx, lbc, lbID, cur.Listeners, plan); err != nil {
+		return Topology{}, err
+	}
+	for _, l := range plan.Listeners {
+		if err := s.ensureListener(ctx, lbc, lbID, cur.Listeners, l); err != nil {
+			return Topology{}, err
 		}
 	}
+	s.pruneDefaultBackendSet(ctx, lbc, lbID, cur.BackendSets, plan)
+	if len(plan.Certs) > 0 || len(cur.Certificates) > 0 {
+		s.pruneCertificates(ctx, lbc, lbID, plan.CertPrefix, plan.Certs)
+	}
 
-	return lbID, backendSet, listener, nil
+	return Topology{ID: lbID, Listener: plan.Listeners[0].Name, NeedsReplacement: replace, sets: plan.BackendSets}, nil
+}
+
+// CountAllBackends returns the number of backends across the topology's backend sets.
+func (s *Service) CountAllBackends(ctx context.Context, t Topology) (int, error) {
+	n := 0
+	for _, tg := range t.Targets() {
+		c, err := s.CountBackends(ctx, t.ID, tg.BackendSet)
+		if err != nil {
+			return 0, err
+		}
+		n += c
+	}
+	return n, nil
 }
 
 // ListBackends returns the current backends in the named backend set.
@@ -312,6 +313,27 @@ func (s *Service) ListBackends(ctx context.Context, lbID, backendSet string) ([]
 	return items, nil
 }
 
+// Addr is the IP and port of a registered backend.
+type Addr struct {
+	IP   string
+	Port int
+}
+
+// BackendAddrs returns the address of each backend in the named backend set.
+func (s *Service) BackendAddrs(ctx context.Context, lbID, backendSet string) ([]Addr, error) {
+	items, err := s.ListBackends(ctx, lbID, backendSet)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Addr, 0, len(items))
+	for _, b := range items {
+		if b.IpAddress != nil {
+			out = append(out, Addr{IP: *b.IpAddress, Port: deref(b.Port)})
+		}
+	}
+	return out, nil
+}
+
 // CountBackends returns the number of backends in the named backend set.
 func (s *Service) CountBackends(ctx context.Context, lbID, backendSet string) (int, error) {
 	items, err := s.ListBackends(ctx, lbID, backendSet)
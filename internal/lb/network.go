@@ -0,0 +1,725 @@
+// internal/lb/network.go
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/networkloadbalancer"
+)
+
+// NetworkService wraps the OCI Network Load Balancer operations needed by fleetctl: a
+// pass-through Layer-4 load balancer for TCP/UDP services that keeps the client's IP.
+type NetworkService struct {
+	Provider common.ConfigurationProvider
+	Region   string
+}
+
+// NewNetwork constructs a network load balancer Service.
+func NewNetwork(provider common.ConfigurationProvider, region string) *NetworkService {
+	return &NetworkService{Provider: provider, Region: region}
+}
+
+func (s *NetworkService) nlbClient() (networkloadbalancer.NetworkLoadBalancerClient, error) {
+	c, err := networkloadbalancer.NewNetworkLoadBalancerClientWithConfigurationProvider(s.Provider)
+	if err != nil {
+		return networkloadbalancer.NetworkLoadBalancerClient{}, fmt.Errorf("nlb client init: %w", err)
+	}
+	if s.Region != "" {
+		c.SetRegion(s.Region)
+	}
+	return c, nil
+}
+
+// nlbSetPlan is the desired state of one network load balancer backend set.
+type nlbSetPlan struct {
+	Set            backendSetPlan // name, groups, port and policy
+	Health         networkloadbalancer.HealthCheckerDetails
+	PreserveSource bool
+}
+
+// nlbListenerPlan is the desired state of one network load balancer listener.
+type nlbListenerPlan struct {
+	Name       string
+	Port       int // 0 with protocol ANY: every port
+	Protocol   string
+	BackendSet string
+}
+
+// nlbPlan is the desired backend sets and listeners of a network load balancer.
+type nlbPlan struct {
+	Sets      []nlbSetPlan
+	Listeners []nlbListenerPlan
+}
+
+// planNetwork derives the network load balancer's backend sets and listeners from
+// spec.loadBalancer. Settings only the Layer-7 load balancer has are rejected.
+func planNetwork(cfg config.FleetConfig) (nlbPlan, error) {
+	spec := cfg.Spec.LoadBalancer
+	var p nlbPlan
+	switch {
+	case spec.TLS != nil || spec.BackendTLS != nil || spec.RedirectHTTPPort != 0:
+		return p, fmt.Errorf("loadBalancer: tls, backendTls and redirectHttpPort need type application (a network load balancer passes TLS through)")
+	case spec.SessionPersistence != nil:
+		return p, fmt.Errorf("loadBalancer.sessionPersistence needs type application (a network load balancer keeps clients on a backend through its policy, e.g. TWO_TUPLE)")
+	}
+	sets, err := layoutBackendSets(cfg)
+	if err != nil {
+		return p, err
+	}
+	policy, err := nlbPolicy("loadBalancer", spec.Policy, "FIVE_TUPLE")
+	if err != nil {
+		return p, err
+	}
+	preserve := spec.PreserveSourceIP == nil || *spec.PreserveSourceIP
+	for i, bs := range sets {
+		field, path, hc := "loadBalancer", strings.TrimSpace(spec.HealthPath), spec.HealthCheck
+		bs.Policy = policy
+		if len(spec.BackendSets) > 0 {
+			s := spec.BackendSets[i]
+			field = fmt.Sprintf("loadBalancer.backendSets[%s]", bs.Name)
+			if s.SessionPersistence != nil {
+				return p, fmt.Errorf("%s.sessionPersistence needs type application", field)
+			}
+			path = setHealthPath(spec, s)
+			if s.HealthCheck != nil {
+				hc = s.HealthCheck
+			}
+			if bs.Policy, err = nlbPolicy(field, s.Policy, policy); err != nil {
+				return p, err
+			}
+		}
+		health, err := planNetworkHealth(field, hc, path, bs.Port)
+		if err != nil {
+			return p, err
+		}
+		p.Sets = append(p.Sets, nlbSetPlan{Set: bs, Health: health, PreserveSource: preserve})
+	}
+
+	if len(spec.Listeners) == 0 {
+		l, err := planNetworkListener("loadBalancer", "", spec.ListenerPort, spec.Protocol, sets[0].Name)
+		if err != nil {
+			return p, err
+		}
+		p.Listeners = []nlbListenerPlan{l}
+		return p, nil
+	}
+	seen := map[string]bool{}
+	for i, ls := range spec.Listeners {
+		name := strings.TrimSpace(ls.Name)
+		field := fmt.Sprintf("loadBalancer.listeners[%d]", i)
+		switch {
+		case name == "":
+			return p, fmt.Errorf("%s: name is required", field)
+		case seen[name]:
+			return p, fmt.Errorf("%s: duplicate listener %q", field, name)
+		}
+		seen[name] = true
+		field = fmt.Sprintf("loadBalancer.listeners[%s]", name)
+		if ls.TLS != nil || len(ls.Hostnames) > 0 || len(ls.Routes) > 0 {
+			return p, fmt.Errorf("%s: tls, hostnames and routes need type application", field)
+		}
+		set := strings.TrimSpace(ls.BackendSet)
+		if set == "" {
+			set = sets[0].Name
+		} else if !slices.ContainsFunc(sets, func(bs backendSetPlan) bool { return bs.Name == set }) {
+			return p, fmt.Errorf("%s: unknown backend set %q", field, set)
+		}
+		l, err := planNetworkListener(field, name, ls.Port, ls.Protocol, set)
+		if err != nil {
+			return p, err
+		}
+		p.Listeners = append(p.Listeners, l)
+	}
+	return p, nil
+}
+
+// planNetworkListener validates a listener's protocol and port; an empty name is the
+// single listener, named after its protocol.
+func planNetworkListener(field, name string, port int, protocol, set string) (nlbListenerPlan, error) {
+	proto := strings.ToUpper(strings.TrimSpace(protocol))
+	switch proto {
+	case "":
+		proto = "TCP"
+	case "TCP", "UDP", "TCP_AND_UDP", "ANY":
+	default:
+		return nlbListenerPlan{}, fmt.Errorf("%s.protocol %q: a network load balancer listens on TCP, UDP, TCP_AND_UDP or ANY", field, protocol)
+	}
+	switch {
+	case port < 0 || port > 65535:
+		return nlbListenerPlan{}, fmt.Errorf("%s: port %d out of range", field, port)
+	case port == 0 && proto != "ANY":
+		return nlbListenerPlan{}, fmt.Errorf("%s: port is required (only protocol ANY listens on every port)", field)
+	}
+	if name == "" {
+		name = listenerName(proto)
+	}
+	return nlbListenerPlan{Name: name, Port: port, Protocol: proto, BackendSet: set}, nil
+}
+
+// nlbPolicy validates a network load balancing policy; "" yields def.
+func nlbPolicy(field, policy, def string) (string, error) {
+	p := strings.ToUpper(strings.TrimSpace(policy))
+	switch p {
+	case "":
+		return def, nil
+	case "FIVE_TUPLE", "THREE_TUPLE", "TWO_TUPLE":
+		return p, nil
+	}
+	return "", fmt.Errorf("%s.policy %q: a network load balancer uses FIVE_TUPLE, THREE_TUPLE or TWO_TUPLE", field, policy)
+}
+
+// planNetworkHealth builds a network load balancer health checker: HTTP with a health
+// path, else TCP, unless healthCheck.protocol picks HTTP, HTTPS, TCP or UDP. Timings,
+// retries and the HTTP checks are validated like the Layer-7 ones.
+func planNetworkHealth(field string, hc *config.LBHealthCheckSpec, path string, port int) (networkloadbalancer.HealthCheckerDetails, error) {
+	if hc == nil {
+		hc = &config.LBHealthCheckSpec{}
+	}
+	proto := "TCP"
+	if path != "" {
+		proto = "HTTP"
+	}
+	if p := strings.ToUpper(strings.TrimSpace(hc.Protocol)); p != "" {
+		switch p {
+		case "HTTP", "HTTPS":
+			if path == "" {
+				return networkloadbalancer.HealthCheckerDetails{}, fmt.Errorf("%s.healthCheck.protocol %s requires healthPath", field, p)
+			}
+		case "TCP", "UDP":
+		default:
+			return networkloadbalancer.HealthCheckerDetails{}, fmt.Errorf("%s.healthCheck.protocol %q: use HTTP, HTTPS, TCP or UDP", field, hc.Protocol)
+		}
+		proto = p
+	}
+	l7, l7Path, l7Proto := *hc, "", "TCP"
+	l7.Protocol = ""
+	if proto == "HTTP" || proto == "HTTPS" {
+		l7Path, l7Proto = path, "HTTP"
+	}
+	d, err := planHealth(field, &l7, l7Path, port, l7Proto)
+	if err != nil {
+		return networkloadbalancer.HealthCheckerDetails{}, err
+	}
+	return networkloadbalancer.HealthCheckerDetails{
+		Protocol:          networkloadbalancer.HealthCheckProtocolsEnum(proto),
+		Port:              d.Port,
+		UrlPath:           d.UrlPath,
+		Retries:           d.Retries,
+		TimeoutInMillis:   d.TimeoutInMillis,
+		IntervalInMillis:  d.IntervalInMillis,
+		ReturnCode:        d.ReturnCode,
+		ResponseBodyRegex: d.ResponseBodyRegex,
+	}, nil
+}
+
+// Ensure creates or ensures existence of the network load balancer, its backend sets
+// and listeners, and brings their policy, source IP preservation, health checks, ports
+// and protocols in line with the spec. Each change is logged; isPrivate and subnetId
+// changes are reported in Topology.NeedsReplacement. The network load balancer is found
+// by who, as for Service.Ensure.
+func (s *NetworkService) Ensure(ctx context.Context, cfg config.FleetConfig, who Identity) (Topology, error) {
+	if s == nil || s.Provider == nil {
+		return Topology{}, fmt.Errorf("nlb service not initialized")
+	}
+	spec := cfg.Spec.LoadBalancer
+	if !spec.Enabled {
+		return Topology{}, fmt.Errorf("load balancer is disabled in config")
+	}
+	plan, err := planNetwork(cfg)
+	if err != nil {
+		return Topology{}, err
+	}
+	c, err := s.nlbClient()
+	if err != nil {
+		return Topology{}, err
+	}
+
+	// 1) Find (see findLoadBalancer) or create the network load balancer
+	displayName := lbDisplayName(cfg)
+	id, err := s.findLoadBalancer(ctx, c, cfg, who)
+	if err != nil {
+		return Topology{}, err
+	}
+	if id == "" {
+		if id, err = s.create(ctx, c, cfg, who, displayName); err != nil {
+			return Topology{}, err
+		}
+	}
+	resp, err := c.GetNetworkLoadBalancer(ctx, networkloadbalancer.GetNetworkLoadBalancerRequest{NetworkLoadBalancerId: &id})
+	if err != nil {
+		return Topology{}, fmt.Errorf("get network load balancer: %w", err)
+	}
+	cur := resp.NetworkLoadBalancer
+	replace := networkReplacementDrift(cur, spec)
+	if len(replace) > 0 {
+		log.Printf("NLB: %s needs replacement to apply: %s (delete it to have it recreated)", displayName, strings.Join(replace, "; "))
+	}
+
+	// 2) Backend sets, then listeners (after removing those an earlier spec created)
+	for _, bs := range plan.Sets {
+		if err := s.ensureBackendSet(ctx, c, id, cur.BackendSets, bs); err != nil {
+			return Topology{}, err
+		}
+	}
+	for name := range cur.Listeners {
+		if !isManagedNetworkListener(name) || slices.ContainsFunc(plan.Listeners, func(l nlbListenerPlan) bool { return l.Name == name }) {
+			continue
+		}
+		if err := s.deleteListener(ctx, c, id, name); err != nil {
+			return Topology{}, err
+		}
+	}
+	for _, l := range plan.Listeners {
+		if err := s.ensureListener(ctx, c, id, cur.Listeners, l); err != nil {
+			return Topology{}, err
+		}
+	}
+	sets := make([]backendSetPlan, 0, len(plan.Sets))
+	for _, bs := range plan.Sets {
+		sets = append(sets, bs.Set)
+	}
+	if _, ok := cur.BackendSets[defaultBackendSet]; ok && !slices.ContainsFunc(sets, func(bs backendSetPlan) bool { return bs.Name == defaultBackendSet }) {
+		s.deleteBackendSet(ctx, c, id, defaultBackendSet)
+	}
+	return Topology{ID: id, Listener: plan.Listeners[0].Name, NeedsReplacement: replace, sets: sets}, nil
+}
+
+// create creates the network load balancer and returns its OCID once it is active.
+func (s *NetworkService) create(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, cfg config.FleetConfig, who Identity, displayName string) (string, error) {
+	spec := cfg.Spec.LoadBalancer
+	subnet := strings.TrimSpace(spec.SubnetID)
+	if subnet == "" {
+		return "", fmt.Errorf("loadBalancer.subnetId must be set")
+	}
+	resp, err := c.CreateNetworkLoadBalancer(ctx, networkloadbalancer.CreateNetworkLoadBalancerRequest{
+		CreateNetworkLoadBalancerDetails: networkloadbalancer.CreateNetworkLoadBalancerDetails{
+			CompartmentId: &cfg.Spec.CompartmentID,
+			DisplayName:   &displayName,
+			SubnetId:      &subnet,
+			IsPrivate:     &spec.IsPrivate,
+			FreeformTags:  fleetTags(cfg, who),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create network load balancer: %w", err)
+	}
+	if err := s.waitFor(ctx, resp.OpcWorkRequestId, "create network load balancer"); err != nil {
+		return "", err
+	}
+	if resp.Id == nil {
+		return "", fmt.Errorf("created network load balancer but could not resolve its ID")
+	}
+	log.Printf("NLB: created %s (%s)", displayName, *resp.Id)
+	return *resp.Id, nil
+}
+
+// networkReplacementDrift lists spec changes OCI cannot apply to an existing network
+// load balancer.
+func networkReplacementDrift(cur networkloadbalancer.NetworkLoadBalancer, spec config.LoadBalancerSpec) []string {
+	var out []string
+	if derefB(cur.IsPrivate) != spec.IsPrivate {
+		out = append(out, fmt.Sprintf("isPrivate %t -> %t", derefB(cur.IsPrivate), spec.IsPrivate))
+	}
+	if want := strings.TrimSpace(spec.SubnetID); want != "" && derefS(cur.SubnetId) != want {
+		out = append(out, fmt.Sprintf("subnetId %s -> %s", derefS(cur.SubnetId), want))
+	}
+	return out
+}
+
+// networkSetDrift lists how an existing backend set differs from the plan (empty: in
+// sync). Health settings the spec leaves unset are not compared.
+func networkSetDrift(cur networkloadbalancer.BackendSet, want nlbSetPlan) []string {
+	var out []string
+	if string(cur.Policy) != want.Set.Policy {
+		out = append(out, fmt.Sprintf("policy %s -> %s", cur.Policy, want.Set.Policy))
+	}
+	if derefB(cur.IsPreserveSource) != want.PreserveSource {
+		out = append(out, fmt.Sprintf("preserve source IP %t -> %t", derefB(cur.IsPreserveSource), want.PreserveSource))
+	}
+	h, w := cur.HealthChecker, want.Health
+	if h == nil {
+		return append(out, "health checker missing")
+	}
+	if h.Protocol != w.Protocol {
+		out = append(out, fmt.Sprintf("health protocol %s -> %s", h.Protocol, w.Protocol))
+	}
+	cmpI := func(name string, have, need *int) {
+		if need != nil && deref(have) != *need {
+			out = append(out, fmt.Sprintf("health %s %d -> %d", name, deref(have), *need))
+		}
+	}
+	cmpS := func(name string, have, need *string) {
+		if need != nil && derefS(have) != *need {
+			out = append(out, fmt.Sprintf("health %s %q -> %q", name, derefS(have), *need))
+		}
+	}
+	cmpI("port", h.Port, w.Port)
+	cmpS("path", h.UrlPath, w.UrlPath)
+	cmpI("interval ms", h.IntervalInMillis, w.IntervalInMillis)
+	cmpI("timeout ms", h.TimeoutInMillis, w.TimeoutInMillis)
+	cmpI("retries", h.Retries, w.Retries)
+	cmpI("return code", h.ReturnCode, w.ReturnCode)
+	cmpS("body regex", h.ResponseBodyRegex, w.ResponseBodyRegex)
+	return out
+}
+
+// networkListenerDrift lists how an existing listener differs from the plan.
+func networkListenerDrift(cur networkloadbalancer.Listener, want nlbListenerPlan) []string {
+	var out []string
+	if deref(cur.Port) != want.Port {
+		out = append(out, fmt.Sprintf("port %d -> %d", deref(cur.Port), want.Port))
+	}
+	if string(cur.Protocol) != want.Protocol {
+		out = append(out, fmt.Sprintf("protocol %s -> %s", cur.Protocol, want.Protocol))
+	}
+	if derefS(cur.DefaultBackendSetName) != want.BackendSet {
+		out = append(out, fmt.Sprintf("backend set %s -> %s", derefS(cur.DefaultBackendSetName), want.BackendSet))
+	}
+	return out
+}
+
+// isManagedNetworkListener reports whether name is a single listener fleetctl created.
+func isManagedNetworkListener(name string) bool {
+	switch name {
+	case listenerName("TCP"), listenerName("UDP"), listenerName("TCP_AND_UDP"), listenerName("ANY"):
+		return true
+	}
+	return false
+}
+
+// ensureBackendSet creates the planned backend set, or updates an existing one whose
+// policy, source IP preservation or health checker drifted, keeping its backends.
+func (s *NetworkService) ensureBackendSet(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id string, have map[string]networkloadbalancer.BackendSet, want nlbSetPlan) error {
+	name, preserve, hc := want.Set.Name, want.PreserveSource, want.Health
+	cur, ok := have[name]
+	if !ok {
+		resp, err := c.CreateBackendSet(ctx, networkloadbalancer.CreateBackendSetRequest{
+			NetworkLoadBalancerId: &id,
+			CreateBackendSetDetails: networkloadbalancer.CreateBackendSetDetails{
+				Name:             &name,
+				Policy:           networkloadbalancer.NetworkLoadBalancingPolicyEnum(want.Set.Policy),
+				HealthChecker:    &hc,
+				IsPreserveSource: &preserve,
+			},
+		})
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "already exists") {
+				return nil
+			}
+			return fmt.Errorf("create backend set %s: %w", name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "create backend set")
+	}
+	if len(networkSetDrift(cur, want)) == 0 {
+		return nil
+	}
+	// Read the set again for its ETag so backends registered meanwhile are kept (412).
+	policy := want.Set.Policy
+	for attempt := 1; ; attempt++ {
+		got, err := c.GetBackendSet(ctx, networkloadbalancer.GetBackendSetRequest{NetworkLoadBalancerId: &id, BackendSetName: &name})
+		if err != nil {
+			return fmt.Errorf("get backend set %s: %w", name, err)
+		}
+		drift := networkSetDrift(got.BackendSet, want)
+		if len(drift) == 0 {
+			return nil
+		}
+		log.Printf("NLB: updating backend set %s: %s", name, strings.Join(drift, "; "))
+		resp, err := c.UpdateBackendSet(ctx, networkloadbalancer.UpdateBackendSetRequest{
+			NetworkLoadBalancerId: &id,
+			BackendSetName:        &name,
+			IfMatch:               got.Etag,
+			UpdateBackendSetDetails: networkloadbalancer.UpdateBackendSetDetails{
+				Policy:           &policy,
+				IsPreserveSource: &preserve,
+				HealthChecker:    &hc,
+				Backends:         networkBackendDetails(got.Backends),
+			},
+		})
+		if hasStatus(err, http.StatusPreconditionFailed) && attempt < staleRetries {
+			log.Printf("NLB: backend set %s changed since it was read, reading it again", name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("update backend set %s: %w", name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "update backend set")
+	}
+}
+
+// ensureListener creates the planned listener, or updates an existing one whose port,
+// protocol or backend set drifted.
+func (s *NetworkService) ensureListener(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id string, have map[string]networkloadbalancer.Listener, want nlbListenerPlan) error {
+	name, port, set := want.Name, want.Port, want.BackendSet
+	proto := networkloadbalancer.ListenerProtocolsEnum(want.Protocol)
+	cur, ok := have[name]
+	if !ok {
+		resp, err := c.CreateListener(ctx, networkloadbalancer.CreateListenerRequest{
+			NetworkLoadBalancerId: &id,
+			CreateListenerDetails: networkloadbalancer.CreateListenerDetails{
+				Name:                  &name,
+				DefaultBackendSetName: &set,
+				Port:                  &port,
+				Protocol:              proto,
+			},
+		})
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "already exists") {
+				return nil
+			}
+			return fmt.Errorf("create listener %s: %w", name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "create listener")
+	}
+	drift := networkListenerDrift(cur, want)
+	if len(drift) == 0 {
+		return nil
+	}
+	log.Printf("NLB: updating listener %s: %s", name, strings.Join(drift, "; "))
+	resp, err := c.UpdateListener(ctx, networkloadbalancer.UpdateListenerRequest{
+		NetworkLoadBalancerId: &id,
+		ListenerName:          &name,
+		UpdateListenerDetails: networkloadbalancer.UpdateListenerDetails{
+			DefaultBackendSetName: &set,
+			Port:                  &port,
+			Protocol:              proto,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update listener %s: %w", name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "update listener")
+}
+
+// deleteListener removes a listener an earlier spec created.
+func (s *NetworkService) deleteListener(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id, name string) error {
+	log.Printf("NLB: deleting listener %s (no longer in the spec)", name)
+	resp, err := c.DeleteListener(ctx, networkloadbalancer.DeleteListenerRequest{NetworkLoadBalancerId: &id, ListenerName: &name})
+	if err != nil {
+		return fmt.Errorf("delete listener %s: %w", name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "delete listener")
+}
+
+// deleteBackendSet removes the single fleet-wide backend set once the spec declares its
+// own; failures are logged and retried on the next Ensure.
+func (s *NetworkService) deleteBackendSet(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id, name string) {
+	resp, err := c.DeleteBackendSet(ctx, networkloadbalancer.DeleteBackendSetRequest{NetworkLoadBalancerId: &id, BackendSetName: &name})
+	if err != nil {
+		log.Printf("NLB: delete backend set %s: %v", name, err)
+		return
+	}
+	log.Printf("NLB: deleted backend set %s (replaced by loadBalancer.backendSets)", name)
+	if err := s.waitFor(ctx, resp.OpcWorkRequestId, "delete backend set"); err != nil {
+		log.Printf("NLB: delete backend set %s: %v", name, err)
+	}
+}
+
+// CountAllBackends returns the number of backends across the topology's backend sets.
+func (s *NetworkService) CountAllBackends(ctx context.Context, t Topology) (int, error) {
+	n := 0
+	for _, tg := range t.Targets() {
+		addrs, err := s.BackendAddrs(ctx, t.ID, tg.BackendSet)
+		if err != nil {
+			return 0, err
+		}
+		n += len(addrs)
+	}
+	return n, nil
+}
+
+// BackendAddrs returns the address of each backend in the named backend set.
+func (s *NetworkService) BackendAddrs(ctx context.Context, id, backendSet string) ([]Addr, error) {
+	c, err := s.nlbClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.GetBackendSet(ctx, networkloadbalancer.GetBackendSetRequest{NetworkLoadBalancerId: &id, BackendSetName: &backendSet})
+	if err != nil {
+		return nil, fmt.Errorf("get backend set %s: %w", backendSet, err)
+	}
+	out := make([]Addr, 0, len(resp.Backends))
+	for _, b := range resp.Backends {
+		if b.IpAddress != nil {
+			out = append(out, Addr{IP: *b.IpAddress, Port: deref(b.Port)})
+		}
+	}
+	return out, nil
+}
+
+// ApplyChanges applies changes with one UpdateBackendSet call per backend set, keeping
+// the set's policy, source IP preservation and health checker, and falls back to one
+// CreateBackend/DeleteBackend call per change when that fails.
+func (s *NetworkService) ApplyChanges(ctx context.Context, id string, changes []Change) (BatchResult, error) {
+	c, err := s.nlbClient()
+	if err != nil {
+		return BatchResult{}, err
+	}
+	return applyBatched(changes,
+		func(set string, cs []Change) (int, error) { return s.updateBackends(ctx, c, id, set, cs) },
+		func(set string, ch Change) error {
+			if ch.Remove {
+				return s.removeBackend(ctx, c, id, set, ch.Addr)
+			}
+			return s.addBackend(ctx, c, id, set, ch.Addr)
+		})
+}
+
+// updateBackends writes the backend set with changes applied in one UpdateBackendSet and
+// returns how many changes were not already in place (0: nothing was written). Like the
+// LB variant it writes with the read's ETag as If-Match and starts over on 412.
+func (s *NetworkService) updateBackends(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id, name string, changes []Change) (int, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.GetBackendSet(ctx, networkloadbalancer.GetBackendSetRequest{NetworkLoadBalancerId: &id, BackendSetName: &name})
+		if err != nil {
+			return 0, fmt.Errorf("get backend set %s: %w", name, err)
+		}
+		bs := resp.BackendSet
+		backends, n := editAddrs(networkBackendDetails(bs.Backends), changes,
+			func(b networkloadbalancer.BackendDetails) Addr {
+				return Addr{IP: derefS(b.IpAddress), Port: deref(b.Port)}
+			},
+			func(a Addr) networkloadbalancer.BackendDetails {
+				return networkloadbalancer.BackendDetails{IpAddress: &a.IP, Port: &a.Port}
+			})
+		if n == 0 {
+			return 0, nil
+		}
+		log.Printf("NLB: updating backend set %s: %d backend change(s), %d backend(s) after", name, n, len(backends))
+		policy := string(bs.Policy)
+		ur, err := c.UpdateBackendSet(ctx, networkloadbalancer.UpdateBackendSetRequest{
+			NetworkLoadBalancerId: &id,
+			BackendSetName:        &name,
+			IfMatch:               resp.Etag,
+			UpdateBackendSetDetails: networkloadbalancer.UpdateBackendSetDetails{
+				Policy:                   &policy,
+				IsPreserveSource:         bs.IsPreserveSource,
+				IsFailOpen:               bs.IsFailOpen,
+				IsInstantFailoverEnabled: bs.IsInstantFailoverEnabled,
+				Backends:                 backends,
+				HealthChecker:            networkHealthDetails(bs.HealthChecker),
+			},
+		})
+		if hasStatus(err, http.StatusPreconditionFailed) && attempt < staleRetries {
+			log.Printf("NLB: backend set %s changed since it was read, reading it again", name)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("update backend set %s: %w", name, err)
+		}
+		return n, s.waitFor(ctx, ur.OpcWorkRequestId, "update backend set")
+	}
+}
+
+// addBackend registers one backend; an existing one is success.
+func (s *NetworkService) addBackend(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id, set string, a Addr) error {
+	resp, err := c.CreateBackend(ctx, networkloadbalancer.CreateBackendRequest{
+		NetworkLoadBalancerId: &id,
+		BackendSetName:        &set,
+		CreateBackendDetails:  networkloadbalancer.CreateBackendDetails{IpAddress: &a.IP, Port: &a.Port},
+	})
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "already exists") {
+			return nil
+		}
+		return fmt.Errorf("create backend %s:%d: %w", a.IP, a.Port, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "create backend")
+}
+
+// removeBackend deregisters one backend; a missing one is success.
+func (s *NetworkService) removeBackend(ctx context.Context, c networkloadbalancer.NetworkLoadBalancerClient, id, set string, a Addr) error {
+	// BackendName format is "IP:port"
+	name := fmt.Sprintf("%s:%d", a.IP, a.Port)
+	resp, err := c.DeleteBackend(ctx, networkloadbalancer.DeleteBackendRequest{NetworkLoadBalancerId: &id, BackendSetName: &set, BackendName: &name})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("delete backend %s: %w", name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "delete backend")
+}
+
+// networkBackendDetails converts the backends of a backend set for an UpdateBackendSet call.
+func networkBackendDetails(in []networkloadbalancer.Backend) []networkloadbalancer.BackendDetails {
+	out := make([]networkloadbalancer.BackendDetails, 0, len(in))
+	for _, b := range in {
+		out = append(out, networkloadbalancer.BackendDetails{
+			Name:      b.Name,
+			IpAddress: b.IpAddress,
+			TargetId:  b.TargetId,
+			Port:      b.Port,
+			Weight:    b.Weight,
+			IsBackup:  b.IsBackup,
+			IsDrain:   b.IsDrain,
+			IsOffline: b.IsOffline,
+		})
+	}
+	return out
+}
+
+// networkHealthDetails converts a backend set's health checker for an update call.
+func networkHealthDetails(h *networkloadbalancer.HealthChecker) *networkloadbalancer.HealthCheckerDetails {
+	if h == nil {
+		return nil
+	}
+	return &networkloadbalancer.HealthCheckerDetails{
+		Protocol:          h.Protocol,
+		Port:              h.Port,
+		Retries:           h.Retries,
+		TimeoutInMillis:   h.TimeoutInMillis,
+		IntervalInMillis:  h.IntervalInMillis,
+		UrlPath:           h.UrlPath,
+		ResponseBodyRegex: h.ResponseBodyRegex,
+		ReturnCode:        h.ReturnCode,
+		RequestData:       h.RequestData,
+		ResponseData:      h.ResponseData,
+		Dns:               h.Dns,
+	}
+}
+
+// waitFor waits for the work request of a mutating call, if it returned one.
+func (s *NetworkService) waitFor(ctx context.Context, wr *string, label string) error {
+	if wr == nil {
+		return nil
+	}
+	c, err := s.nlbClient()
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := c.GetWorkRequest(ctx, networkloadbalancer.GetWorkRequestRequest{WorkRequestId: wr})
+		if err != nil {
+			// As for the Layer-7 LB: an unreadable work request is treated as complete and
+			// the next Ensure/reconcile verifies the result.
+			if isNotFound(err) {
+				log.Printf("%s: work request %s not accessible via API (treat as complete): %v", label, *wr, err)
+				return nil
+			}
+			return fmt.Errorf("%s work request %s: %w", label, *wr, err)
+		}
+		status := resp.WorkRequest.Status
+		log.Printf("%s: work request %s status=%s", label, *wr, status)
+		switch status {
+		case networkloadbalancer.OperationStatusSucceeded:
+			return nil
+		case networkloadbalancer.OperationStatusFailed, networkloadbalancer.OperationStatusCanceled:
+			return fmt.Errorf("%s failed (state=%s)", label, status)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
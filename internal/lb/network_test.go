@@ -0,0 +1,154 @@
+// internal/lb/network_test.go
+package lb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/networkloadbalancer"
+)
+
+func networkConfig() config.FleetConfig {
+	cfg := config.FleetConfig{}
+	cfg.Metadata.Name = "dns"
+	cfg.Spec.Instances = []config.InstanceSpec{{Name: "resolver"}, {Name: "relay"}}
+	cfg.Spec.LoadBalancer = config.LoadBalancerSpec{
+		Enabled:     true,
+		Type:        TypeNetwork,
+		BackendPort: 53,
+		BackendSets: []config.LBBackendSetSpec{
+			{Name: "resolvers", Groups: []string{"resolver"}},
+			{Name: "relays", Groups: []string{"relay"}, BackendPort: 8080, HealthPath: "/health", Policy: "two_tuple"},
+		},
+		Listeners: []config.LBListenerSpec{
+			{Name: "dns", Port: 53, Protocol: "tcp_and_udp"},
+			{Name: "relay", Port: 443, BackendSet: "relays"},
+		},
+	}
+	return cfg
+}
+
+func TestPlanNetwork(t *testing.T) {
+	p, err := planNetwork(networkConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Sets) != 2 {
+		t.Fatalf("backend sets = %+v", p.Sets)
+	}
+	res, rel := p.Sets[0], p.Sets[1]
+	if res.Set.Port != 53 || res.Set.Policy != "FIVE_TUPLE" || res.Health.Protocol != "TCP" || res.Health.UrlPath != nil || !res.PreserveSource {
+		t.Fatalf("resolvers = %+v, want TCP health, FIVE_TUPLE and source IP preserved", res)
+	}
+	if rel.Set.Port != 8080 || rel.Set.Policy != "TWO_TUPLE" || rel.Health.Protocol != "HTTP" || *rel.Health.UrlPath != "/health" {
+		t.Fatalf("relays = %+v", rel)
+	}
+	dns, relay := p.Listeners[0], p.Listeners[1]
+	if dns.Protocol != "TCP_AND_UDP" || dns.BackendSet != "resolvers" || relay.Protocol != "TCP" || relay.BackendSet != "relays" {
+		t.Fatalf("listeners = %+v", p.Listeners)
+	}
+
+	// The single listener and backend set, without source IP preservation
+	var single config.FleetConfig
+	off := false
+	single.Spec.LoadBalancer = config.LoadBalancerSpec{Enabled: true, Type: TypeNetwork, ListenerPort: 5000, BackendPort: 5000, Protocol: "udp", PreserveSourceIP: &off,
+		HealthCheck: &config.LBHealthCheckSpec{Protocol: "udp", Retries: 2}}
+	if p, err = planNetwork(single); err != nil {
+		t.Fatal(err)
+	}
+	if l := p.Listeners[0]; l.Name != "udp-listener" || l.Port != 5000 || l.BackendSet != defaultBackendSet {
+		t.Fatalf("single listener = %+v", l)
+	}
+	if bs := p.Sets[0]; bs.PreserveSource || bs.Health.Protocol != "UDP" || *bs.Health.Retries != 2 {
+		t.Fatalf("single backend set = %+v", bs)
+	}
+}
+
+func TestPlanNetworkRejectsLayer7Settings(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*config.LoadBalancerSpec)
+		want   string
+	}{
+		{"tls", func(l *config.LoadBalancerSpec) { l.TLS = &config.LBTLSSpec{CertificateID: "ocid1.certificate"} }, "need type application"},
+		{"persistence", func(l *config.LoadBalancerSpec) {
+			l.BackendSets[0].SessionPersistence = &config.LBSessionPersistenceSpec{Type: "lb-cookie"}
+		}, "backendSets[resolvers].sessionPersistence needs type application"},
+		{"layer-7 policy", func(l *config.LoadBalancerSpec) { l.Policy = "ROUND_ROBIN" }, "uses FIVE_TUPLE, THREE_TUPLE or TWO_TUPLE"},
+		{"http listener", func(l *config.LoadBalancerSpec) { l.Listeners[1].Protocol = "HTTP" }, "listens on TCP, UDP, TCP_AND_UDP or ANY"},
+		{"routes", func(l *config.LoadBalancerSpec) { l.Listeners[0].Routes = []config.LBRouteSpec{{Path: "/"}} }, "routes need type application"},
+		{"no port", func(l *config.LoadBalancerSpec) { l.Listeners[0].Port = 0 }, "only protocol ANY listens on every port"},
+		{"unknown set", func(l *config.LoadBalancerSpec) { l.Listeners[1].BackendSet = "x" }, `unknown backend set "x"`},
+		{"https health without path", func(l *config.LoadBalancerSpec) {
+			l.HealthCheck = &config.LBHealthCheckSpec{Protocol: "https"}
+		}, "backendSets[resolvers].healthCheck.protocol HTTPS requires healthPath"},
+		{"return code on tcp", func(l *config.LoadBalancerSpec) { l.HealthCheck = &config.LBHealthCheckSpec{ReturnCode: 200} }, "need an HTTP health check"},
+	}
+	for _, tt := range tests {
+		cfg := networkConfig()
+		tt.mutate(&cfg.Spec.LoadBalancer)
+		if _, err := planNetwork(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// The Layer-7 planner rejects network-only settings
+	cfg := multiConfig()
+	on := true
+	cfg.Spec.LoadBalancer.PreserveSourceIP = &on
+	if _, err := planLB(cfg); err == nil || !strings.Contains(err.Error(), "preserveSourceIp needs type network") {
+		t.Fatalf("planLB with preserveSourceIp: err = %v", err)
+	}
+}
+
+func TestNetworkDrift(t *testing.T) {
+	p, err := planNetwork(networkConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := p.Sets[1]
+	interval, retries, port := 10000, 3, 8080
+	h := want.Health
+	live := networkloadbalancer.BackendSet{
+		Policy:           networkloadbalancer.NetworkLoadBalancingPolicyTwoTuple,
+		IsPreserveSource: &want.PreserveSource,
+		HealthChecker: &networkloadbalancer.HealthChecker{
+			Protocol: h.Protocol, Port: &port, UrlPath: h.UrlPath, IntervalInMillis: &interval, Retries: &retries,
+		},
+	}
+	if d := networkSetDrift(live, want); len(d) != 0 {
+		t.Fatalf("drift of an up-to-date set = %v (OCI defaults are not drift)", d)
+	}
+	off := false
+	live.Policy = networkloadbalancer.NetworkLoadBalancingPolicyFiveTuple
+	live.IsPreserveSource = &off
+	live.HealthChecker.Protocol = networkloadbalancer.HealthCheckProtocolsTcp
+	if d := strings.Join(networkSetDrift(live, want), "; "); d != "policy FIVE_TUPLE -> TWO_TUPLE; preserve source IP false -> true; health protocol TCP -> HTTP" {
+		t.Fatalf("drift = %s", d)
+	}
+
+	l := p.Listeners[0]
+	lport, set := 5353, "relays"
+	cur := networkloadbalancer.Listener{Port: &lport, Protocol: networkloadbalancer.ListenerProtocolsUdp, DefaultBackendSetName: &set}
+	if d := strings.Join(networkListenerDrift(cur, l), "; "); d != "port 5353 -> 53; protocol UDP -> TCP_AND_UDP; backend set relays -> resolvers" {
+		t.Fatalf("listener drift = %s", d)
+	}
+}
+
+func TestOpenPicksTheLoadBalancerType(t *testing.T) {
+	for typ, want := range map[string]string{"": "*lb.Service", "application": "*lb.Service", "Network": "*lb.NetworkService"} {
+		b, err := Open(nil, "", config.LoadBalancerSpec{Type: typ})
+		if err != nil {
+			t.Fatalf("Open(%q): %v", typ, err)
+		}
+		if got := fmt.Sprintf("%T", b); got != want {
+			t.Fatalf("Open(%q) = %s, want %s", typ, got, want)
+		}
+	}
+	if _, err := Open(nil, "", config.LoadBalancerSpec{Type: "gateway"}); err == nil {
+		t.Fatal("Open accepted an unknown type")
+	}
+}
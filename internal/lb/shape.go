@@ -0,0 +1,69 @@
+// internal/lb/shape.go
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+// lbShape is the shape every fleet load balancer is created with.
+const lbShape = "flexible"
+
+// shapeDrift lists how the load balancer's shape and bandwidth differ from the spec.
+func shapeDrift(cur loadbalancer.LoadBalancer, spec config.LoadBalancerSpec) []string {
+	var out []string
+	if !strings.EqualFold(derefS(cur.ShapeName), lbShape) {
+		out = append(out, fmt.Sprintf("shape %s -> %s", derefS(cur.ShapeName), lbShape))
+	}
+	var minBw, maxBw int
+	if cur.ShapeDetails != nil {
+		minBw, maxBw = deref(cur.ShapeDetails.MinimumBandwidthInMbps), deref(cur.ShapeDetails.MaximumBandwidthInMbps)
+	}
+	if minBw != spec.MinBandwidthMbps {
+		out = append(out, fmt.Sprintf("minimum bandwidth %d -> %d Mbps", minBw, spec.MinBandwidthMbps))
+	}
+	if maxBw != spec.MaxBandwidthMbps {
+		out = append(out, fmt.Sprintf("maximum bandwidth %d -> %d Mbps", maxBw, spec.MaxBandwidthMbps))
+	}
+	return out
+}
+
+// replacementDrift lists spec changes OCI cannot apply to an existing load balancer;
+// they take effect only when the load balancer is deleted and created again.
+func replacementDrift(cur loadbalancer.LoadBalancer, spec config.LoadBalancerSpec) []string {
+	var out []string
+	if derefB(cur.IsPrivate) != spec.IsPrivate {
+		out = append(out, fmt.Sprintf("isPrivate %t -> %t", derefB(cur.IsPrivate), spec.IsPrivate))
+	}
+	if want := strings.TrimSpace(spec.SubnetID); want != "" && (len(cur.SubnetIds) != 1 || cur.SubnetIds[0] != want) {
+		out = append(out, fmt.Sprintf("subnetId %s -> %s", strings.Join(cur.SubnetIds, ","), want))
+	}
+	return out
+}
+
+// ensureShape resizes the load balancer in place when its shape or bandwidth drifted.
+func (s *Service) ensureShape(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, cur loadbalancer.LoadBalancer, spec config.LoadBalancerSpec) error {
+	drift := shapeDrift(cur, spec)
+	if len(drift) == 0 {
+		return nil
+	}
+	log.Printf("LB: updating shape of %s: %s", derefS(cur.DisplayName), strings.Join(drift, "; "))
+	shape, minBw, maxBw := lbShape, spec.MinBandwidthMbps, spec.MaxBandwidthMbps
+	resp, err := lbc.UpdateLoadBalancerShape(ctx, loadbalancer.UpdateLoadBalancerShapeRequest{
+		LoadBalancerId: &lbID,
+		UpdateLoadBalancerShapeDetails: loadbalancer.UpdateLoadBalancerShapeDetails{
+			ShapeName:    &shape,
+			ShapeDetails: &loadbalancer.ShapeDetails{MinimumBandwidthInMbps: &minBw, MaximumBandwidthInMbps: &maxBw},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update load balancer shape: %w", err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "update load balancer shape")
+}
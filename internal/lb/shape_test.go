@@ -0,0 +1,44 @@
+// internal/lb/shape_test.go
+package lb
+
+import (
+	"strings"
+	"testing"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+func TestShapeAndReplacementDrift(t *testing.T) {
+	shape, minBw, maxBw, private := "flexible", 10, 100, false
+	cur := loadbalancer.LoadBalancer{
+		ShapeName:    &shape,
+		ShapeDetails: &loadbalancer.ShapeDetails{MinimumBandwidthInMbps: &minBw, MaximumBandwidthInMbps: &maxBw},
+		IsPrivate:    &private,
+		SubnetIds:    []string{"ocid1.subnet.a"},
+	}
+	spec := config.LoadBalancerSpec{SubnetID: "ocid1.subnet.a", MinBandwidthMbps: 10, MaxBandwidthMbps: 100}
+	if d := shapeDrift(cur, spec); len(d) != 0 {
+		t.Fatalf("in-sync shape drift = %v", d)
+	}
+	if d := replacementDrift(cur, spec); len(d) != 0 {
+		t.Fatalf("in-sync replacement drift = %v", d)
+	}
+
+	spec.MaxBandwidthMbps = 400
+	spec.IsPrivate = true
+	spec.SubnetID = "ocid1.subnet.b"
+	if d := shapeDrift(cur, spec); strings.Join(d, "; ") != "maximum bandwidth 100 -> 400 Mbps" {
+		t.Fatalf("shape drift = %v", d)
+	}
+	if d := replacementDrift(cur, spec); strings.Join(d, "; ") != "isPrivate false -> true; subnetId ocid1.subnet.a -> ocid1.subnet.b" {
+		t.Fatalf("replacement drift = %v", d)
+	}
+
+	fixed := "100Mbps"
+	cur.ShapeName, cur.ShapeDetails = &fixed, nil
+	if d := shapeDrift(cur, spec); len(d) != 3 || d[0] != "shape 100Mbps -> flexible" {
+		t.Fatalf("fixed shape drift = %v", d)
+	}
+}
@@ -0,0 +1,560 @@
+// internal/lb/tls.go
+package lb
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+// Names of the HTTP->HTTPS redirect listener and its rule set (rule set names allow
+// only letters, digits and underscores).
+const (
+	redirectListener = "http-redirect"
+	redirectRuleSet  = "fleetctl_https_redirect"
+)
+
+// Uploaded certificates are named <fleet>-<kind>-<fingerprint[:16]>; only these are pruned.
+const (
+	certKindTLS       = "tls-"
+	certKindBackendCA = "backend-ca-"
+)
+
+// certBundle is a PEM certificate read from disk for upload to the load balancer.
+type certBundle struct {
+	Name        string // <prefix><fingerprint[:16]>: a new certificate gets a new name
+	Fingerprint string
+	Public      string
+	Private     string
+	CA          string
+}
+
+// listenerPlan is the desired state of one listener.
+type listenerPlan struct {
+	Name       string
+	Port       int
+	Protocol   string // OCI listener protocol: HTTP or TCP (HTTPS is HTTP with SSL)
+	SSL        *loadbalancer.SslConfigurationDetails
+	RuleSets   []string
+	BackendSet string // default backend set
+
+	// Hostnames and path routes are only managed for loadBalancer.listeners; the single
+	// listener keeps whatever was set on it by hand.
+	ManageRouting bool
+	Hostnames     []string // hostname resource names
+	PathRouteSet  string
+}
+
+// lbPlan is what Ensure makes the load balancer match, derived from the LB spec.
+type lbPlan struct {
+	Listeners     []listenerPlan // the main listener first
+	BackendSets   []backendSetPlan
+	Hostnames     []hostnamePlan
+	PathRouteSets []pathRouteSetPlan
+	Certs         []certBundle // uploaded certificates the plan refers to
+	BackendSSL    *loadbalancer.SslConfigurationDetails
+	CertPrefix    string // <fleet>-; see certKindTLS and certKindBackendCA
+}
+
+// CertFingerprint returns the hex SHA-256 fingerprint of the first certificate in pemData.
+func CertFingerprint(pemData []byte) (string, error) {
+	for rest := pemData; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return "", fmt.Errorf("no PEM certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return "", fmt.Errorf("parse certificate: %w", err)
+		}
+		sum := sha256.Sum256(block.Bytes)
+		return hex.EncodeToString(sum[:]), nil
+	}
+}
+
+// loadCertBundle reads a certificate (and optionally key and CA chain) from PEM files.
+func loadCertBundle(prefix, certFile, keyFile, caFile string) (certBundle, error) {
+	var b certBundle
+	read := func(path string) (string, error) {
+		if path == "" {
+			return "", nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", path, err)
+		}
+		return string(data), nil
+	}
+	var err error
+	if b.Public, err = read(certFile); err != nil {
+		return b, err
+	}
+	if b.Private, err = read(keyFile); err != nil {
+		return b, err
+	}
+	if b.CA, err = read(caFile); err != nil {
+		return b, err
+	}
+	fpSource := b.Public
+	if fpSource == "" {
+		fpSource = b.CA // CA-only bundle (backend verification)
+	}
+	if b.Fingerprint, err = CertFingerprint([]byte(fpSource)); err != nil {
+		return b, fmt.Errorf("%s: %w", firstNonEmpty(certFile, caFile), err)
+	}
+	b.Name = prefix + b.Fingerprint[:16]
+	return b, nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// planLB derives backend sets, listeners, routing, certificates and backend SSL from the
+// LB spec.
+func planLB(cfg config.FleetConfig) (lbPlan, error) {
+	spec := cfg.Spec.LoadBalancer
+	p := lbPlan{CertPrefix: cfg.Metadata.Name + "-"}
+	if spec.PreserveSourceIP != nil {
+		return p, fmt.Errorf("loadBalancer.preserveSourceIp needs type network")
+	}
+	var err error
+	if p.BackendSets, err = planBackendSets(cfg); err != nil {
+		return p, err
+	}
+
+	if len(spec.Listeners) == 0 {
+		proto := spec.ListenerProtocol()
+		main, err := p.planListener("loadBalancer", listenerName(proto), spec.ListenerPort, proto, spec.Protocol, spec.TLS)
+		if err != nil {
+			return p, err
+		}
+		main.BackendSet = p.BackendSets[0].Name
+		p.Listeners = append(p.Listeners, main)
+
+		if spec.RedirectHTTPPort > 0 {
+			if proto != "HTTPS" {
+				return p, fmt.Errorf("loadBalancer.redirectHttpPort requires protocol HTTPS")
+			}
+			if spec.RedirectHTTPPort == spec.ListenerPort {
+				return p, fmt.Errorf("loadBalancer.redirectHttpPort must differ from listenerPort")
+			}
+			p.Listeners = append(p.Listeners, listenerPlan{
+				Name:       redirectListener,
+				Port:       spec.RedirectHTTPPort,
+				Protocol:   "HTTP",
+				RuleSets:   []string{redirectRuleSet},
+				BackendSet: main.BackendSet,
+			})
+		}
+	} else {
+		if spec.RedirectHTTPPort > 0 {
+			return p, fmt.Errorf("loadBalancer.redirectHttpPort applies to the single listener, not loadBalancer.listeners")
+		}
+		seen := map[string]bool{}
+		for i, l := range spec.Listeners {
+			name := strings.TrimSpace(l.Name)
+			field := fmt.Sprintf("loadBalancer.listeners[%d]", i)
+			switch {
+			case name == "":
+				return p, fmt.Errorf("%s: name is required", field)
+			case seen[name]:
+				return p, fmt.Errorf("%s: duplicate listener %q", field, name)
+			case l.Port <= 0:
+				return p, fmt.Errorf("%s: port is required", field)
+			}
+			seen[name] = true
+			field = fmt.Sprintf("loadBalancer.listeners[%s]", name)
+			proto := config.NormalizeProtocol(l.Protocol)
+			lp, hosts, routes, err := planRouting(field, l, p.BackendSets)
+			if err != nil {
+				return p, err
+			}
+			if proto == "TCP" && (len(hosts) > 0 || routes != nil) {
+				return p, fmt.Errorf("%s: hostnames and routes need protocol HTTP or HTTPS", field)
+			}
+			base, err := p.planListener(field, name, l.Port, proto, l.Protocol, l.TLS)
+			if err != nil {
+				return p, err
+			}
+			lp.Name, lp.Port, lp.Protocol, lp.SSL = base.Name, base.Port, base.Protocol, base.SSL
+			p.Listeners = append(p.Listeners, lp)
+			p.Hostnames = append(p.Hostnames, hosts...)
+			if routes != nil {
+				p.PathRouteSets = append(p.PathRouteSets, *routes)
+			}
+		}
+	}
+
+	if bt := spec.BackendTLS; bt != nil {
+		ssl := &loadbalancer.SslConfigurationDetails{VerifyPeerCertificate: &bt.VerifyPeer}
+		depth := bt.VerifyDepth
+		if depth <= 0 {
+			depth = 1
+		}
+		ssl.VerifyDepth = &depth
+		switch {
+		case bt.CAFile != "" && len(bt.TrustedCAIDs) > 0:
+			return p, fmt.Errorf("loadBalancer.backendTls: set caFile or trustedCaIds, not both")
+		case bt.CAFile != "":
+			ca, err := loadCertBundle(p.CertPrefix+certKindBackendCA, "", "", bt.CAFile)
+			if err != nil {
+				return p, fmt.Errorf("loadBalancer.backendTls: %w", err)
+			}
+			ssl.CertificateName = &ca.Name
+			p.Certs = append(p.Certs, ca)
+		case len(bt.TrustedCAIDs) > 0:
+			ssl.TrustedCertificateAuthorityIds = bt.TrustedCAIDs
+		case bt.VerifyPeer:
+			return p, fmt.Errorf("loadBalancer.backendTls.verifyPeer requires caFile or trustedCaIds")
+		}
+		p.BackendSSL = ssl
+	}
+	return p, nil
+}
+
+// planListener resolves a listener's OCI protocol and certificate; proto is the normalized
+// protocol, raw the spec value and field the spec path used in errors.
+func (p *lbPlan) planListener(field, name string, port int, proto, raw string, tls *config.LBTLSSpec) (listenerPlan, error) {
+	l := listenerPlan{Name: name, Port: port, Protocol: proto}
+	switch proto {
+	case "HTTP":
+		if tls != nil {
+			return l, fmt.Errorf("%s.tls requires protocol HTTPS or TCP", field)
+		}
+	case "HTTPS", "TCP":
+		if proto == "HTTPS" {
+			l.Protocol = "HTTP"
+			if tls == nil {
+				return l, fmt.Errorf("%s.protocol HTTPS requires %s.tls", field, field)
+			}
+		}
+		if tls != nil {
+			ssl, cert, err := listenerSSL(field, p.CertPrefix+certKindTLS, tls)
+			if err != nil {
+				return l, err
+			}
+			l.SSL = ssl
+			if cert != nil && !slices.ContainsFunc(p.Certs, func(c certBundle) bool { return c.Name == cert.Name }) {
+				p.Certs = append(p.Certs, *cert)
+			}
+		}
+	default:
+		return l, fmt.Errorf("%s.protocol %q: use HTTP, HTTPS or TCP", field, raw)
+	}
+	return l, nil
+}
+
+// listenerSSL builds the listener SSL configuration from the TLS spec; cert is the bundle
+// to upload when the certificate comes from PEM files.
+func listenerSSL(field, prefix string, t *config.LBTLSSpec) (*loadbalancer.SslConfigurationDetails, *certBundle, error) {
+	hasFiles := t.CertFile != "" || t.KeyFile != ""
+	switch {
+	case t.CertificateID != "" && hasFiles:
+		return nil, nil, fmt.Errorf("%s.tls: set certificateId or certFile/keyFile, not both", field)
+	case t.CertificateID != "":
+		return &loadbalancer.SslConfigurationDetails{CertificateIds: []string{t.CertificateID}}, nil, nil
+	case t.CertFile == "" || t.KeyFile == "":
+		return nil, nil, fmt.Errorf("%s.tls: certFile and keyFile (or certificateId) are required", field)
+	}
+	b, err := loadCertBundle(prefix, t.CertFile, t.KeyFile, t.CAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s.tls: %w", field, err)
+	}
+	return &loadbalancer.SslConfigurationDetails{CertificateName: &b.Name}, &b, nil
+}
+
+// listenerName is the main listener's name for an LB spec protocol.
+func listenerName(proto string) string {
+	return strings.ToLower(proto) + "-listener"
+}
+
+// sslName returns the certificate a listener or backend set uses ("" without SSL).
+func sslName(certName *string, ids []string) string {
+	if certName != nil && *certName != "" {
+		return *certName
+	}
+	return strings.Join(ids, ",")
+}
+
+// listenerDrift lists how an existing listener differs from the plan (empty: in sync).
+func listenerDrift(cur loadbalancer.Listener, want listenerPlan) []string {
+	backendSet := want.BackendSet
+	var out []string
+	if cur.Port == nil || *cur.Port != want.Port {
+		out = append(out, fmt.Sprintf("port %d -> %d", deref(cur.Port), want.Port))
+	}
+	if cur.Protocol == nil || !strings.EqualFold(*cur.Protocol, want.Protocol) {
+		out = append(out, fmt.Sprintf("protocol %s -> %s", derefS(cur.Protocol), want.Protocol))
+	}
+	if cur.DefaultBackendSetName == nil || *cur.DefaultBackendSetName != backendSet {
+		out = append(out, fmt.Sprintf("backend set %s -> %s", derefS(cur.DefaultBackendSetName), backendSet))
+	}
+	var have, need string
+	if cur.SslConfiguration != nil {
+		have = sslName(cur.SslConfiguration.CertificateName, cur.SslConfiguration.CertificateIds)
+	}
+	if want.SSL != nil {
+		need = sslName(want.SSL.CertificateName, want.SSL.CertificateIds)
+	}
+	if have != need {
+		out = append(out, fmt.Sprintf("certificate %s -> %s", orNone(have), orNone(need)))
+	}
+	if !slices.Equal(cur.RuleSetNames, want.RuleSets) && (len(cur.RuleSetNames) > 0 || len(want.RuleSets) > 0) {
+		out = append(out, fmt.Sprintf("rule sets %v -> %v", cur.RuleSetNames, want.RuleSets))
+	}
+	if want.ManageRouting {
+		if !slices.Equal(cur.HostnameNames, want.Hostnames) && (len(cur.HostnameNames) > 0 || len(want.Hostnames) > 0) {
+			out = append(out, fmt.Sprintf("hostnames %v -> %v", cur.HostnameNames, want.Hostnames))
+		}
+		if derefS(cur.PathRouteSetName) != want.PathRouteSet {
+			out = append(out, fmt.Sprintf("path routes %s -> %s", orNone(derefS(cur.PathRouteSetName)), orNone(want.PathRouteSet)))
+		}
+	}
+	return out
+}
+
+// backendSSLDrift reports how a backend set's SSL configuration differs from want ("" if none).
+func backendSSLDrift(cur *loadbalancer.SslConfiguration, want *loadbalancer.SslConfigurationDetails) string {
+	switch {
+	case cur == nil && want == nil:
+		return ""
+	case cur == nil:
+		return "backend TLS off -> on"
+	case want == nil:
+		return "backend TLS on -> off"
+	}
+	have := sslName(cur.CertificateName, cur.TrustedCertificateAuthorityIds)
+	need := sslName(want.CertificateName, want.TrustedCertificateAuthorityIds)
+	if have != need {
+		return fmt.Sprintf("backend CA %s -> %s", orNone(have), orNone(need))
+	}
+	if deref(cur.VerifyDepth) != deref(want.VerifyDepth) || derefB(cur.VerifyPeerCertificate) != derefB(want.VerifyPeerCertificate) {
+		return "backend verification settings"
+	}
+	return ""
+}
+
+func deref(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func derefS(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+func derefB(p *bool) bool {
+	return p != nil && *p
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// waitFor waits for the work request of a mutating call, if it returned one.
+func (s *Service) waitFor(ctx context.Context, wr *string, label string) error {
+	if wr == nil {
+		return nil
+	}
+	return s.waitWorkRequest(ctx, *wr, label)
+}
+
+// ensureCertificates uploads the plan's certificates that the LB does not have yet.
+func (s *Service) ensureCertificates(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.Certificate, certs []certBundle) error {
+	for _, c := range certs {
+		if _, ok := have[c.Name]; ok {
+			continue
+		}
+		d := loadbalancer.CreateCertificateDetails{CertificateName: &c.Name}
+		if c.Public != "" {
+			d.PublicCertificate = &c.Public
+		}
+		if c.Private != "" {
+			d.PrivateKey = &c.Private
+		}
+		if c.CA != "" {
+			d.CaCertificate = &c.CA
+		}
+		log.Printf("LB: uploading certificate %s (sha256 %s)", c.Name, c.Fingerprint)
+		resp, err := lbc.CreateCertificate(ctx, loadbalancer.CreateCertificateRequest{LoadBalancerId: &lbID, CreateCertificateDetails: d})
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "already exists") {
+			return fmt.Errorf("create certificate %s: %w", c.Name, err)
+		}
+		if err == nil {
+			if err := s.waitFor(ctx, resp.OpcWorkRequestId, "create certificate"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pruneCertificates deletes fleetctl-managed certificates that nothing uses any more
+// (the previous certificate after a rotation).
+func (s *Service) pruneCertificates(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID, prefix string, keep []certBundle) {
+	resp, err := lbc.GetLoadBalancer(ctx, loadbalancer.GetLoadBalancerRequest{LoadBalancerId: &lbID})
+	if err != nil {
+		log.Printf("LB: list certificates for pruning: %v", err)
+		return
+	}
+	inUse := map[string]bool{}
+	for _, c := range keep {
+		inUse[c.Name] = true
+	}
+	for _, l := range resp.LoadBalancer.Listeners {
+		if l.SslConfiguration != nil && l.SslConfiguration.CertificateName != nil {
+			inUse[*l.SslConfiguration.CertificateName] = true
+		}
+	}
+	for _, bs := range resp.LoadBalancer.BackendSets {
+		if bs.SslConfiguration != nil && bs.SslConfiguration.CertificateName != nil {
+			inUse[*bs.SslConfiguration.CertificateName] = true
+		}
+	}
+	for name := range resp.LoadBalancer.Certificates {
+		managed := strings.HasPrefix(name, prefix+certKindTLS) || strings.HasPrefix(name, prefix+certKindBackendCA)
+		if inUse[name] || !managed {
+			continue
+		}
+		name := name
+		log.Printf("LB: deleting unused certificate %s", name)
+		dr, err := lbc.DeleteCertificate(ctx, loadbalancer.DeleteCertificateRequest{LoadBalancerId: &lbID, CertificateName: &name})
+		if err == nil {
+			err = s.waitFor(ctx, dr.OpcWorkRequestId, "delete certificate")
+		}
+		if err != nil {
+			log.Printf("LB: delete certificate %s: %v", name, err)
+		}
+	}
+}
+
+// ensureRedirectRuleSet creates the rule set that redirects every request to HTTPS on
+// httpsPort, keeping host, path and query.
+func (s *Service) ensureRedirectRuleSet(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.RuleSet, httpsPort int) error {
+	proto, host, path, query := "HTTPS", "{host}", "{path}", "{query}"
+	code := 301
+	root := "/"
+	rules := []loadbalancer.Rule{loadbalancer.RedirectRule{
+		Conditions: []loadbalancer.RuleCondition{loadbalancer.PathMatchCondition{
+			AttributeValue: &root,
+			Operator:       loadbalancer.PathMatchConditionOperatorForceLongestPrefixMatch,
+		}},
+		ResponseCode: &code,
+		RedirectUri:  &loadbalancer.RedirectUri{Protocol: &proto, Host: &host, Port: &httpsPort, Path: &path, Query: &query},
+	}}
+	name := redirectRuleSet
+	if rs, ok := have[name]; ok {
+		if redirectPort(rs) == httpsPort {
+			return nil
+		}
+		resp, err := lbc.UpdateRuleSet(ctx, loadbalancer.UpdateRuleSetRequest{LoadBalancerId: &lbID, RuleSetName: &name, UpdateRuleSetDetails: loadbalancer.UpdateRuleSetDetails{Items: rules}})
+		if err != nil {
+			return fmt.Errorf("update rule set %s: %w", name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "update rule set")
+	}
+	resp, err := lbc.CreateRuleSet(ctx, loadbalancer.CreateRuleSetRequest{LoadBalancerId: &lbID, CreateRuleSetDetails: loadbalancer.CreateRuleSetDetails{Name: &name, Items: rules}})
+	if err != nil {
+		return fmt.Errorf("create rule set %s: %w", name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "create rule set")
+}
+
+// redirectPort returns the HTTPS port the redirect rule set points at (0 if none).
+func redirectPort(rs loadbalancer.RuleSet) int {
+	for _, it := range rs.Items {
+		if r, ok := it.(loadbalancer.RedirectRule); ok && r.RedirectUri != nil {
+			return deref(r.RedirectUri.Port)
+		}
+	}
+	return 0
+}
+
+// ensureListener creates the planned listener or updates it when it drifted (port,
+// protocol, certificate after a rotation, rule sets, routing).
+func (s *Service) ensureListener(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.Listener, want listenerPlan) error {
+	backendSet := want.BackendSet
+	var pathRouteSet *string
+	if want.PathRouteSet != "" {
+		pathRouteSet = &want.PathRouteSet
+	}
+	cur, ok := have[want.Name]
+	if !ok {
+		resp, err := lbc.CreateListener(ctx, loadbalancer.CreateListenerRequest{
+			LoadBalancerId: &lbID,
+			CreateListenerDetails: loadbalancer.CreateListenerDetails{
+				Name:                  &want.Name,
+				DefaultBackendSetName: &backendSet,
+				Port:                  &want.Port,
+				Protocol:              &want.Protocol,
+				SslConfiguration:      want.SSL,
+				RuleSetNames:          want.RuleSets,
+				HostnameNames:         want.Hostnames,
+				PathRouteSetName:      pathRouteSet,
+			},
+		})
+		if err != nil {
+			// If already exists, treat as success
+			if strings.Contains(strings.ToLower(err.Error()), "already exists") {
+				return nil
+			}
+			return fmt.Errorf("create listener %s: %w", want.Name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "create listener")
+	}
+	drift := listenerDrift(cur, want)
+	if len(drift) == 0 {
+		return nil
+	}
+	if !want.ManageRouting {
+		want.Hostnames, pathRouteSet = cur.HostnameNames, cur.PathRouteSetName
+	}
+	log.Printf("LB: updating listener %s: %s", want.Name, strings.Join(drift, "; "))
+	resp, err := lbc.UpdateListener(ctx, loadbalancer.UpdateListenerRequest{
+		LoadBalancerId: &lbID,
+		ListenerName:   &want.Name,
+		UpdateListenerDetails: loadbalancer.UpdateListenerDetails{
+			DefaultBackendSetName:   &backendSet,
+			Port:                    &want.Port,
+			Protocol:                &want.Protocol,
+			SslConfiguration:        want.SSL,
+			RuleSetNames:            want.RuleSets,
+			HostnameNames:           want.Hostnames,
+			PathRouteSetName:        pathRouteSet,
+			RoutingPolicyName:       cur.RoutingPolicyName,
+			ConnectionConfiguration: cur.ConnectionConfiguration,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update listener %s: %w", want.Name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "update listener")
+}
@@ -0,0 +1,134 @@
+// internal/lb/tls_test.go
+package lb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+// writeCert writes a fresh self-signed certificate and key and returns their paths.
+func writeCert(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certPath, keyPath
+}
+
+func httpsConfig(certFile, keyFile string) config.FleetConfig {
+	cfg := config.FleetConfig{}
+	cfg.Metadata.Name = "web"
+	cfg.Spec.LoadBalancer = config.LoadBalancerSpec{
+		Enabled:          true,
+		ListenerPort:     443,
+		BackendPort:      8080,
+		Protocol:         "https",
+		TLS:              &config.LBTLSSpec{CertFile: certFile, KeyFile: keyFile},
+		RedirectHTTPPort: 80,
+	}
+	return cfg
+}
+
+func TestPlanHTTPSWithRedirect(t *testing.T) {
+	dir := t.TempDir()
+	crt, key := writeCert(t, dir, "a")
+	p, err := planLB(httpsConfig(crt, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Listeners) != 2 || len(p.Certs) != 1 {
+		t.Fatalf("plan = %+v", p)
+	}
+	main, redirect := p.Listeners[0], p.Listeners[1]
+	if main.Name != "https-listener" || main.Protocol != "HTTP" || main.SSL == nil || *main.SSL.CertificateName != p.Certs[0].Name {
+		t.Fatalf("main listener = %+v", main)
+	}
+	if !strings.HasPrefix(p.Certs[0].Name, "web-tls-") || len(p.Certs[0].Name) != len("web-tls-")+16 {
+		t.Fatalf("certificate name = %s", p.Certs[0].Name)
+	}
+	if redirect.Port != 80 || len(redirect.RuleSets) != 1 || redirect.SSL != nil {
+		t.Fatalf("redirect listener = %+v", redirect)
+	}
+}
+
+func TestPlanRejectsInvalidSpecs(t *testing.T) {
+	tests := []struct {
+		name string
+		lb   config.LoadBalancerSpec
+		want string
+	}{
+		{"https without tls", config.LoadBalancerSpec{Protocol: "HTTPS"}, "requires loadBalancer.tls"},
+		{"tls on http", config.LoadBalancerSpec{TLS: &config.LBTLSSpec{CertificateID: "ocid1.certificate"}}, "requires protocol HTTPS or TCP"},
+		{"redirect without https", config.LoadBalancerSpec{Protocol: "TCP", RedirectHTTPPort: 80}, "requires protocol HTTPS"},
+		{"both certificate sources", config.LoadBalancerSpec{Protocol: "HTTPS", TLS: &config.LBTLSSpec{CertificateID: "x", CertFile: "c", KeyFile: "k"}}, "not both"},
+		{"unknown protocol", config.LoadBalancerSpec{Protocol: "UDP"}, "use HTTP, HTTPS or TCP"},
+		{"verify without CA", config.LoadBalancerSpec{BackendTLS: &config.LBBackendTLSSpec{VerifyPeer: true}}, "requires caFile or trustedCaIds"},
+	}
+	for _, tt := range tests {
+		cfg := config.FleetConfig{}
+		cfg.Spec.LoadBalancer = tt.lb
+		if _, err := planLB(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestListenerDriftDetectsRotation(t *testing.T) {
+	dir := t.TempDir()
+	crt, key := writeCert(t, dir, "a")
+	before, err := planLB(httpsConfig(crt, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := before.Listeners[0]
+	port, proto, bs := 443, "HTTP", "fleet-backendset"
+	cur := loadbalancer.Listener{
+		Port:                  &port,
+		Protocol:              &proto,
+		DefaultBackendSetName: &bs,
+		SslConfiguration:      &loadbalancer.SslConfiguration{CertificateName: want.SSL.CertificateName},
+	}
+	if d := listenerDrift(cur, want); len(d) != 0 {
+		t.Fatalf("in-sync listener drift = %v", d)
+	}
+
+	// Same paths, new certificate: the fingerprint (and so the name) changes.
+	writeCert(t, dir, "a")
+	after, err := planLB(httpsConfig(crt, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := listenerDrift(cur, after.Listeners[0])
+	if len(d) != 1 || !strings.HasPrefix(d[0], "certificate "+before.Certs[0].Name+" -> web-tls-") {
+		t.Fatalf("drift after rotation = %v", d)
+	}
+}
@@ -0,0 +1,399 @@
+// internal/lb/topology.go
+package lb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/loadbalancer"
+)
+
+// defaultBackendSet is the single backend set of a spec without backendSets.
+const defaultBackendSet = "fleet-backendset"
+
+// backendSetPlan is the desired state of one backend set.
+type backendSetPlan struct {
+	Name      string
+	Groups    []string // empty: every group no other set lists
+	Port      int
+	Policy    string
+	Health    loadbalancer.HealthCheckerDetails
+	AppCookie *loadbalancer.SessionPersistenceConfigurationDetails         // app-cookie persistence, or
+	LBCookie  *loadbalancer.LbCookieSessionPersistenceConfigurationDetails // lb-cookie persistence
+}
+
+// hostnamePlan is a virtual hostname resource a listener answers for.
+type hostnamePlan struct {
+	Name     string
+	Hostname string
+}
+
+// pathRouteSetPlan is the path route set of one listener.
+type pathRouteSetPlan struct {
+	Name   string
+	Routes []loadbalancer.PathRoute
+}
+
+// Target is where the backends of an instance group are registered.
+type Target struct {
+	BackendSet string
+	Port       int
+}
+
+// Topology is the load balancer as Ensure left it: its OCID, the main listener and which
+// backend set each instance group belongs in.
+type Topology struct {
+	ID       string
+	Listener string // the first listener
+
+	// Spec changes that need the load balancer recreated (e.g. isPrivate); empty when none
+	NeedsReplacement []string
+
+	sets []backendSetPlan
+}
+
+// Target returns the backend set for instances of group; false when no set takes the group.
+func (t Topology) Target(group string) (Target, bool) {
+	var catchAll *backendSetPlan
+	for i, bs := range t.sets {
+		if len(bs.Groups) == 0 && catchAll == nil {
+			catchAll = &t.sets[i]
+		}
+		if slices.Contains(bs.Groups, group) {
+			return Target{BackendSet: bs.Name, Port: bs.Port}, true
+		}
+	}
+	if catchAll == nil {
+		return Target{}, false
+	}
+	return Target{BackendSet: catchAll.Name, Port: catchAll.Port}, true
+}
+
+// Targets returns every backend set of the topology in spec order.
+func (t Topology) Targets() []Target {
+	out := make([]Target, 0, len(t.sets))
+	for _, bs := range t.sets {
+		out = append(out, Target{BackendSet: bs.Name, Port: bs.Port})
+	}
+	return out
+}
+
+// BackendSetNames returns the backend set names, comma-separated, for status and state.
+func (t Topology) BackendSetNames() string {
+	names := make([]string, 0, len(t.sets))
+	for _, bs := range t.sets {
+		names = append(names, bs.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// planBackendSets derives the backend sets from the spec: the declared ones, or the
+// single fleet-wide set on loadBalancer.backendPort.
+func planBackendSets(cfg config.FleetConfig) ([]backendSetPlan, error) {
+	spec := cfg.Spec.LoadBalancer
+	sets, err := layoutBackendSets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	policy := strings.ToUpper(strings.TrimSpace(spec.Policy))
+	if policy == "" {
+		policy = "ROUND_ROBIN"
+	}
+	if len(spec.BackendSets) == 0 {
+		bs := &sets[0]
+		bs.Policy = policy
+		path, proto := strings.TrimSpace(spec.HealthPath), "HTTP"
+		if spec.ListenerProtocol() == "TCP" && path == "" {
+			proto = "TCP"
+		}
+		if bs.Health, err = planHealth("loadBalancer", spec.HealthCheck, path, bs.Port, proto); err != nil {
+			return nil, err
+		}
+		if bs.AppCookie, bs.LBCookie, err = planPersistence("loadBalancer", spec.SessionPersistence); err != nil {
+			return nil, err
+		}
+		return sets, nil
+	}
+
+	for i, s := range spec.BackendSets {
+		bs := &sets[i]
+		field := fmt.Sprintf("loadBalancer.backendSets[%s]", bs.Name)
+		bs.Policy = strings.ToUpper(strings.TrimSpace(s.Policy))
+		if bs.Policy == "" {
+			bs.Policy = policy
+		}
+		path := setHealthPath(spec, s)
+		proto := "HTTP"
+		if path == "" {
+			proto = "TCP"
+		}
+		hc, sp := s.HealthCheck, s.SessionPersistence
+		if hc == nil {
+			hc = spec.HealthCheck
+		}
+		if sp == nil {
+			sp = spec.SessionPersistence
+		}
+		if bs.Health, err = planHealth(field, hc, path, bs.Port, proto); err != nil {
+			return nil, err
+		}
+		if bs.AppCookie, bs.LBCookie, err = planPersistence(field, sp); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// layoutBackendSets returns the backend sets' names, instance groups and ports, one per
+// declared set in spec order (or the single fleet-backendset), and rejects a group in
+// two sets or unknown groups. Both load balancer types share it.
+func layoutBackendSets(cfg config.FleetConfig) ([]backendSetPlan, error) {
+	spec := cfg.Spec.LoadBalancer
+	if len(spec.BackendSets) == 0 {
+		return []backendSetPlan{{Name: defaultBackendSet, Port: spec.BackendPort}}, nil
+	}
+
+	known := map[string]bool{}
+	for _, g := range cfg.Spec.Instances {
+		known[g.Name] = true
+	}
+	claimed := map[string]string{}
+	seen := map[string]bool{}
+	catchAll := ""
+	out := make([]backendSetPlan, 0, len(spec.BackendSets))
+	for i, s := range spec.BackendSets {
+		name := strings.TrimSpace(s.Name)
+		field := fmt.Sprintf("loadBalancer.backendSets[%d]", i)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("%s: name is required", field)
+		case seen[name]:
+			return nil, fmt.Errorf("%s: duplicate backend set %q", field, name)
+		}
+		seen[name] = true
+		field = fmt.Sprintf("loadBalancer.backendSets[%s]", name)
+		bs := backendSetPlan{Name: name, Groups: s.Groups, Port: s.BackendPort}
+		if bs.Port == 0 {
+			bs.Port = spec.BackendPort
+		}
+		if bs.Port <= 0 {
+			return nil, fmt.Errorf("%s: backendPort (or loadBalancer.backendPort) is required", field)
+		}
+		if len(s.Groups) == 0 {
+			if catchAll != "" {
+				return nil, fmt.Errorf("%s: only one backend set may omit groups (%s does too)", field, catchAll)
+			}
+			catchAll = name
+		}
+		for _, g := range s.Groups {
+			if len(known) > 0 && !known[g] {
+				return nil, fmt.Errorf("%s: unknown instance group %q", field, g)
+			}
+			if other, ok := claimed[g]; ok {
+				return nil, fmt.Errorf("%s: group %q is already in backend set %s", field, g, other)
+			}
+			claimed[g] = name
+		}
+		out = append(out, bs)
+	}
+	return out, nil
+}
+
+// setHealthPath is a declared backend set's health path, defaulting to loadBalancer.healthPath.
+func setHealthPath(spec config.LoadBalancerSpec, s config.LBBackendSetSpec) string {
+	if path := strings.TrimSpace(s.HealthPath); path != "" {
+		return path
+	}
+	return strings.TrimSpace(spec.HealthPath)
+}
+
+// planRouting resolves a declared listener's default backend set, hostnames and path routes.
+func planRouting(field string, l config.LBListenerSpec, sets []backendSetPlan) (listenerPlan, []hostnamePlan, *pathRouteSetPlan, error) {
+	lp := listenerPlan{ManageRouting: true}
+	exists := func(name string) bool {
+		return slices.ContainsFunc(sets, func(bs backendSetPlan) bool { return bs.Name == name })
+	}
+	lp.BackendSet = strings.TrimSpace(l.BackendSet)
+	if lp.BackendSet == "" {
+		lp.BackendSet = sets[0].Name
+	} else if !exists(lp.BackendSet) {
+		return lp, nil, nil, fmt.Errorf("%s.backendSet: unknown backend set %q", field, lp.BackendSet)
+	}
+
+	var hosts []hostnamePlan
+	for _, h := range l.Hostnames {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			return lp, nil, nil, fmt.Errorf("%s.hostnames: empty hostname", field)
+		}
+		hp := hostnamePlan{Name: resourceName(h), Hostname: h}
+		hosts = append(hosts, hp)
+		lp.Hostnames = append(lp.Hostnames, hp.Name)
+	}
+
+	var prs *pathRouteSetPlan
+	if len(l.Routes) > 0 {
+		prs = &pathRouteSetPlan{Name: resourceName(l.Name + "_routes")}
+		for i, r := range l.Routes {
+			path := strings.TrimSpace(r.Path)
+			if path == "" {
+				return lp, nil, nil, fmt.Errorf("%s.routes[%d]: path is required", field, i)
+			}
+			if !exists(r.BackendSet) {
+				return lp, nil, nil, fmt.Errorf("%s.routes[%d]: unknown backend set %q", field, i, r.BackendSet)
+			}
+			mt, err := routeMatch(r.Match)
+			if err != nil {
+				return lp, nil, nil, fmt.Errorf("%s.routes[%d]: %w", field, i, err)
+			}
+			bs := r.BackendSet
+			prs.Routes = append(prs.Routes, loadbalancer.PathRoute{
+				Path:           &path,
+				PathMatchType:  &loadbalancer.PathMatchType{MatchType: mt},
+				BackendSetName: &bs,
+			})
+		}
+		lp.PathRouteSet = prs.Name
+	}
+	return lp, hosts, prs, nil
+}
+
+// routeMatch maps a route's match keyword to the OCI path match type.
+func routeMatch(m string) (loadbalancer.PathMatchTypeMatchTypeEnum, error) {
+	switch strings.ToLower(strings.TrimSpace(m)) {
+	case "", "prefix":
+		return loadbalancer.PathMatchTypeMatchTypeForceLongestPrefixMatch, nil
+	case "exact":
+		return loadbalancer.PathMatchTypeMatchTypeExactMatch, nil
+	case "suffix":
+		return loadbalancer.PathMatchTypeMatchTypeSuffixMatch, nil
+	}
+	return "", fmt.Errorf("match %q: use prefix, exact or suffix", m)
+}
+
+var nonResourceChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// resourceName turns s into a hostname or path route set name (letters, digits, underscores).
+func resourceName(s string) string {
+	return nonResourceChars.ReplaceAllString(strings.ReplaceAll(s, "*", "wildcard"), "_")
+}
+
+// pathRoutesEqual reports whether two path route lists route the same way.
+func pathRoutesEqual(a, b []loadbalancer.PathRoute) bool {
+	return slices.EqualFunc(a, b, func(x, y loadbalancer.PathRoute) bool {
+		var mx, my loadbalancer.PathMatchTypeMatchTypeEnum
+		if x.PathMatchType != nil {
+			mx = x.PathMatchType.MatchType
+		}
+		if y.PathMatchType != nil {
+			my = y.PathMatchType.MatchType
+		}
+		return derefS(x.Path) == derefS(y.Path) && mx == my && derefS(x.BackendSetName) == derefS(y.BackendSetName)
+	})
+}
+
+// isManagedListener reports whether name is a listener fleetctl creates for the single
+// listener spec, so one left over after the spec changed can be removed.
+func isManagedListener(name string) bool {
+	switch name {
+	case redirectListener, listenerName("HTTP"), listenerName("HTTPS"), listenerName("TCP"):
+		return true
+	}
+	return false
+}
+
+// ensureHostnames creates the planned virtual hostnames the load balancer lacks.
+func (s *Service) ensureHostnames(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.Hostname, want []hostnamePlan) error {
+	for _, h := range want {
+		if _, ok := have[h.Name]; ok {
+			continue
+		}
+		name, host := h.Name, h.Hostname
+		resp, err := lbc.CreateHostname(ctx, loadbalancer.CreateHostnameRequest{
+			LoadBalancerId:        &lbID,
+			CreateHostnameDetails: loadbalancer.CreateHostnameDetails{Name: &name, Hostname: &host},
+		})
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "already exists") {
+				continue
+			}
+			return fmt.Errorf("create hostname %s: %w", host, err)
+		}
+		if err := s.waitFor(ctx, resp.OpcWorkRequestId, "create hostname"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensurePathRouteSet creates the planned path route set or updates its routes.
+func (s *Service) ensurePathRouteSet(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.PathRouteSet, want pathRouteSetPlan) error {
+	name := want.Name
+	if cur, ok := have[name]; ok {
+		if pathRoutesEqual(cur.PathRoutes, want.Routes) {
+			return nil
+		}
+		log.Printf("LB: updating path routes %s", name)
+		resp, err := lbc.UpdatePathRouteSet(ctx, loadbalancer.UpdatePathRouteSetRequest{
+			LoadBalancerId:            &lbID,
+			PathRouteSetName:          &name,
+			UpdatePathRouteSetDetails: loadbalancer.UpdatePathRouteSetDetails{PathRoutes: want.Routes},
+		})
+		if err != nil {
+			return fmt.Errorf("update path route set %s: %w", name, err)
+		}
+		return s.waitFor(ctx, resp.OpcWorkRequestId, "update path route set")
+	}
+	resp, err := lbc.CreatePathRouteSet(ctx, loadbalancer.CreatePathRouteSetRequest{
+		LoadBalancerId:            &lbID,
+		CreatePathRouteSetDetails: loadbalancer.CreatePathRouteSetDetails{Name: &name, PathRoutes: want.Routes},
+	})
+	if err != nil {
+		return fmt.Errorf("create path route set %s: %w", name, err)
+	}
+	return s.waitFor(ctx, resp.OpcWorkRequestId, "create path route set")
+}
+
+// pruneListeners deletes listeners fleetctl created for an earlier spec (e.g. the single
+// http-listener after switching to loadBalancer.listeners), freeing their ports.
+func (s *Service) pruneListeners(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.Listener, plan lbPlan) error {
+	for name := range have {
+		if !isManagedListener(name) || slices.ContainsFunc(plan.Listeners, func(l listenerPlan) bool { return l.Name == name }) {
+			continue
+		}
+		name := name
+		log.Printf("LB: deleting listener %s (no longer in the spec)", name)
+		resp, err := lbc.DeleteListener(ctx, loadbalancer.DeleteListenerRequest{LoadBalancerId: &lbID, ListenerName: &name})
+		if err != nil {
+			return fmt.Errorf("delete listener %s: %w", name, err)
+		}
+		if err := s.waitFor(ctx, resp.OpcWorkRequestId, "delete listener"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneDefaultBackendSet deletes the single fleet-wide backend set once the spec declares
+// its own backend sets and no listener uses it any more.
+func (s *Service) pruneDefaultBackendSet(ctx context.Context, lbc loadbalancer.LoadBalancerClient, lbID string, have map[string]loadbalancer.BackendSet, plan lbPlan) {
+	if _, ok := have[defaultBackendSet]; !ok || slices.ContainsFunc(plan.BackendSets, func(bs backendSetPlan) bool { return bs.Name == defaultBackendSet }) {
+		return
+	}
+	name := defaultBackendSet
+	resp, err := lbc.DeleteBackendSet(ctx, loadbalancer.DeleteBackendSetRequest{LoadBalancerId: &lbID, BackendSetName: &name})
+	if err != nil {
+		log.Printf("LB: delete backend set %s: %v", name, err)
+		return
+	}
+	log.Printf("LB: deleted backend set %s (replaced by loadBalancer.backendSets)", name)
+	if err := s.waitFor(ctx, resp.OpcWorkRequestId, "delete backend set"); err != nil {
+		log.Printf("LB: delete backend set %s: %v", name, err)
+	}
+}
@@ -0,0 +1,131 @@
+// internal/lb/topology_test.go
+package lb
+
+import (
+	"strings"
+	"testing"
+
+	"fleetctl/internal/config"
+)
+
+func multiConfig() config.FleetConfig {
+	cfg := config.FleetConfig{}
+	cfg.Metadata.Name = "shop"
+	cfg.Spec.Instances = []config.InstanceSpec{{Name: "web"}, {Name: "api"}, {Name: "admin"}, {Name: "batch"}}
+	cfg.Spec.LoadBalancer = config.LoadBalancerSpec{
+		Enabled:     true,
+		BackendPort: 8080,
+		HealthPath:  "/healthz",
+		BackendSets: []config.LBBackendSetSpec{
+			{Name: "web", Groups: []string{"web"}},
+			{Name: "api", Groups: []string{"api", "admin"}, BackendPort: 9090, HealthPath: "/ready", Policy: "LEAST_CONNECTIONS"},
+		},
+		Listeners: []config.LBListenerSpec{
+			{Name: "public", Port: 80, Hostnames: []string{"shop.example.com", "*.shop.example.com"}, Routes: []config.LBRouteSpec{
+				{Path: "/api", BackendSet: "api"},
+				{Path: "/status", Match: "exact", BackendSet: "api"},
+			}},
+			{Name: "internal", Port: 8443, Protocol: "tcp", BackendSet: "api"},
+		},
+	}
+	return cfg
+}
+
+func TestPlanBackendSetsAndRouting(t *testing.T) {
+	p, err := planLB(multiConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.BackendSets) != 2 {
+		t.Fatalf("backend sets = %+v", p.BackendSets)
+	}
+	web, api := p.BackendSets[0], p.BackendSets[1]
+	if web.Port != 8080 || *web.Health.UrlPath != "/healthz" || web.Policy != "ROUND_ROBIN" {
+		t.Fatalf("web set = %+v, want spec defaults", web)
+	}
+	if api.Port != 9090 || *api.Health.UrlPath != "/ready" || api.Policy != "LEAST_CONNECTIONS" {
+		t.Fatalf("api set = %+v", api)
+	}
+
+	if len(p.Listeners) != 2 {
+		t.Fatalf("listeners = %+v", p.Listeners)
+	}
+	pub, in := p.Listeners[0], p.Listeners[1]
+	if pub.BackendSet != "web" || pub.PathRouteSet != "public_routes" || strings.Join(pub.Hostnames, ",") != "shop_example_com,wildcard_shop_example_com" {
+		t.Fatalf("public listener = %+v", pub)
+	}
+	if in.BackendSet != "api" || in.Protocol != "TCP" || in.PathRouteSet != "" {
+		t.Fatalf("internal listener = %+v", in)
+	}
+	if len(p.PathRouteSets) != 1 || len(p.PathRouteSets[0].Routes) != 2 {
+		t.Fatalf("path route sets = %+v", p.PathRouteSets)
+	}
+	if mt := p.PathRouteSets[0].Routes[1].PathMatchType.MatchType; mt != "EXACT_MATCH" {
+		t.Fatalf("exact route match type = %s", mt)
+	}
+
+	topo := Topology{ID: "lb1", Listener: pub.Name, sets: p.BackendSets}
+	tests := []struct {
+		group string
+		want  Target
+		ok    bool
+	}{
+		{"web", Target{"web", 8080}, true},
+		{"admin", Target{"api", 9090}, true},
+		{"batch", Target{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := topo.Target(tt.group); got != tt.want || ok != tt.ok {
+			t.Errorf("Target(%s) = %+v, %v; want %+v, %v", tt.group, got, ok, tt.want, tt.ok)
+		}
+	}
+	if topo.BackendSetNames() != "web,api" {
+		t.Fatalf("BackendSetNames = %s", topo.BackendSetNames())
+	}
+}
+
+func TestSingleBackendSetTakesEveryGroup(t *testing.T) {
+	cfg := config.FleetConfig{}
+	cfg.Spec.LoadBalancer = config.LoadBalancerSpec{Enabled: true, ListenerPort: 80, BackendPort: 8080}
+	p, err := planLB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topo := Topology{sets: p.BackendSets}
+	if got, ok := topo.Target("anything"); !ok || got != (Target{defaultBackendSet, 8080}) {
+		t.Fatalf("Target = %+v, %v", got, ok)
+	}
+	if l := p.Listeners[0]; l.Name != "http-listener" || l.BackendSet != defaultBackendSet || l.ManageRouting {
+		t.Fatalf("listener = %+v", l)
+	}
+}
+
+func TestPlanRejectsInvalidTopology(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*config.LoadBalancerSpec)
+		want   string
+	}{
+		{"group in two sets", func(l *config.LoadBalancerSpec) { l.BackendSets[1].Groups = []string{"web"} }, `group "web" is already in backend set web`},
+		{"unknown group", func(l *config.LoadBalancerSpec) { l.BackendSets[0].Groups = []string{"nope"} }, `unknown instance group "nope"`},
+		{"two catch-all sets", func(l *config.LoadBalancerSpec) {
+			l.BackendSets[0].Groups = nil
+			l.BackendSets[1].Groups = nil
+		}, "only one backend set may omit groups"},
+		{"duplicate set", func(l *config.LoadBalancerSpec) { l.BackendSets[1].Name = "web" }, "duplicate backend set"},
+		{"no port", func(l *config.LoadBalancerSpec) { l.BackendPort = 0 }, "backendPort (or loadBalancer.backendPort) is required"},
+		{"route to unknown set", func(l *config.LoadBalancerSpec) { l.Listeners[0].Routes[0].BackendSet = "x" }, `routes[0]: unknown backend set "x"`},
+		{"bad match", func(l *config.LoadBalancerSpec) { l.Listeners[0].Routes[0].Match = "regex" }, "use prefix, exact or suffix"},
+		{"routes on tcp", func(l *config.LoadBalancerSpec) { l.Listeners[0].Protocol = "TCP" }, "need protocol HTTP or HTTPS"},
+		{"listener without port", func(l *config.LoadBalancerSpec) { l.Listeners[1].Port = 0 }, "listeners[1]: port is required"},
+		{"redirect with listeners", func(l *config.LoadBalancerSpec) { l.RedirectHTTPPort = 80 }, "redirectHttpPort applies to the single listener"},
+		{"https listener without tls", func(l *config.LoadBalancerSpec) { l.Listeners[1].Protocol = "https" }, "loadBalancer.listeners[internal].protocol HTTPS requires loadBalancer.listeners[internal].tls"},
+	}
+	for _, tt := range tests {
+		cfg := multiConfig()
+		tt.mutate(&cfg.Spec.LoadBalancer)
+		if _, err := planLB(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
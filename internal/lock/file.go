@@ -0,0 +1,157 @@
+// internal/lock/file.go
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// File is a flock(2)-based lock next to the state file. The kernel drops the
+// lock when the holding process exits, so stale holders cannot wedge the fleet;
+// holder details are written into the lock file for diagnostics.
+type File struct {
+	path  string
+	fleet string
+	ttl   time.Duration
+
+	mu   sync.Mutex
+	fl   *flock.Flock
+	info *Info
+}
+
+// NewFile creates a file lock at path for the named fleet.
+func NewFile(path, fleet string, ttl time.Duration) *File {
+	return &File{path: path, fleet: fleet, ttl: ttl}
+}
+
+func (f *File) ensureDir() error {
+	dir := filepath.Dir(f.path)
+	if dir == "." || dir == "" {
+		return nil
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// readInfo returns the holder details last written to the lock file, if any.
+func (f *File) readInfo() (*Info, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading lock file %q: %w", f.path, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, nil // foreign or truncated content; holder unknown
+	}
+	return &info, nil
+}
+
+func (f *File) Acquire(ctx context.Context, op string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.info != nil {
+		return fmt.Errorf("lock %s already held by this process for %s", f.path, f.info.Operation)
+	}
+	if err := f.ensureDir(); err != nil {
+		return err
+	}
+	fl := flock.New(f.path)
+	ok, err := fl.TryLock()
+	if err != nil {
+		return fmt.Errorf("lock %s: %w", f.path, err)
+	}
+	if !ok {
+		info, _ := f.readInfo()
+		if info == nil {
+			info = &Info{Fleet: f.fleet, Holder: "unknown"}
+		}
+		return &LockedError{Backend: "flock", Info: *info}
+	}
+	now := time.Now()
+	info := &Info{
+		Fleet:      f.fleet,
+		Holder:     holderID(),
+		Operation:  op,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(f.ttl),
+	}
+	data, _ := json.Marshal(info)
+	if err := os.WriteFile(f.path, data, 0o600); err != nil {
+		_ = fl.Unlock()
+		return fmt.Errorf("write lock holder %s: %w", f.path, err)
+	}
+	f.fl = fl
+	f.info = info
+	return nil
+}
+
+func (f *File) Release(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fl == nil {
+		return nil
+	}
+	// Clear holder details before dropping the lock so readers never see a stale holder.
+	_ = os.Truncate(f.path, 0)
+	err := f.fl.Unlock()
+	f.fl = nil
+	f.info = nil
+	if err != nil {
+		return fmt.Errorf("unlock %s: %w", f.path, err)
+	}
+	return nil
+}
+
+func (f *File) Status(ctx context.Context) (Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.info != nil {
+		info := *f.info
+		return Status{Backend: "flock", Held: true, Info: &info}, nil
+	}
+	if _, err := os.Stat(f.path); os.IsNotExist(err) {
+		return Status{Backend: "flock"}, nil
+	}
+	probe := flock.New(f.path)
+	ok, err := probe.TryLock()
+	if err != nil {
+		return Status{}, fmt.Errorf("probe lock %s: %w", f.path, err)
+	}
+	if ok {
+		_ = probe.Unlock()
+		return Status{Backend: "flock"}, nil
+	}
+	info, err := f.readInfo()
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Backend: "flock", Held: true, Info: info}, nil
+}
+
+// ForceUnlock removes the lock file. A process still holding the old file keeps
+// its (now orphaned) lock, so only use this when the holder is known to be gone.
+func (f *File) ForceUnlock(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fl != nil {
+		_ = f.fl.Unlock()
+		f.fl = nil
+		f.info = nil
+	}
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove lock file %s: %w", f.path, err)
+	}
+	return nil
+}
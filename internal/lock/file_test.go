@@ -0,0 +1,114 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLockExcludesSecondHolder(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), ".dev.lock")
+	a := NewFile(path, "dev", time.Minute)
+	b := NewFile(path, "dev", time.Minute)
+
+	if err := a.Acquire(ctx, "scale"); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	err := b.Acquire(ctx, "rolling-restart")
+	var le *LockedError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected LockedError, got %v", err)
+	}
+	if le.Info.Operation != "scale" || le.Info.Holder != holderID() {
+		t.Fatalf("unexpected holder info: %+v", le.Info)
+	}
+	if !strings.Contains(err.Error(), "--force-unlock") {
+		t.Fatalf("error should mention --force-unlock: %v", err)
+	}
+
+	st, err := b.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !st.Held || st.Info == nil || st.Info.Operation != "scale" {
+		t.Fatalf("expected held status with holder info, got %+v", st)
+	}
+
+	if err := a.Release(ctx); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := b.Acquire(ctx, "rolling-restart"); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	if err := b.Release(ctx); err != nil {
+		t.Fatalf("release b: %v", err)
+	}
+}
+
+func TestFileLockForceUnlock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), ".dev.lock")
+	a := NewFile(path, "dev", time.Minute)
+	b := NewFile(path, "dev", time.Minute)
+
+	if err := a.Acquire(ctx, "scale"); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := b.ForceUnlock(ctx); err != nil {
+		t.Fatalf("force unlock: %v", err)
+	}
+	if err := b.Acquire(ctx, "scale"); err != nil {
+		t.Fatalf("acquire after force unlock: %v", err)
+	}
+}
+
+func TestLeaseEncoding(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	in := Info{Fleet: "dev", Holder: "host:42", Operation: "scale", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+	out, ok := decodeLease("dev", encodeLease(in))
+	if !ok {
+		t.Fatalf("decode failed")
+	}
+	if out != in {
+		t.Fatalf("round trip mismatch: %+v != %+v", out, in)
+	}
+	if !out.Expired(now.Add(2 * time.Minute)) {
+		t.Fatalf("expected lease to be expired")
+	}
+	if _, ok := decodeLease("dev", "garbage"); ok {
+		t.Fatalf("expected garbage to fail decoding")
+	}
+}
+
+// serviceError is a common.ServiceError with a fixed HTTP status.
+type serviceError struct{ status int }
+
+func (e serviceError) Error() string {
+	return fmt.Sprintf("Error returned by Identity Service. Http Status Code: %d", e.status)
+}
+func (e serviceError) GetHTTPStatusCode() int  { return e.status }
+func (e serviceError) GetMessage() string      { return "" }
+func (e serviceError) GetCode() string         { return "" }
+func (e serviceError) GetOpcRequestID() string { return "" }
+
+func TestLeasePreconditionFailed(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"412", serviceError{412}, true},
+		{"409", serviceError{409}, false},
+		{"412 only in the text", errors.New("tag value fleetctl-412 precondition"), false},
+	}
+	for _, c := range cases {
+		if got := isPreconditionFailed(c.err); got != c.want {
+			t.Fatalf("%s: isPreconditionFailed = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
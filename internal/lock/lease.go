@@ -0,0 +1,307 @@
+// internal/lock/lease.go
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+)
+
+// LeaseTagPrefix prefixes the compartment freeform tag that stores a fleet's lease.
+const LeaseTagPrefix = "fleetctl-lock-"
+
+// Lease is a distributed lock stored as a freeform tag on an OCI compartment.
+// Updates use the compartment ETag (If-Match) so two hosts cannot both win the
+// same lease; the holder renews it in the background until Release. When a renewal
+// finds the lease taken over by another host, renewing stops and Lost is closed.
+type Lease struct {
+	Provider    common.ConfigurationProvider
+	Region      string
+	Compartment string
+	fleet       string
+	ttl         time.Duration
+
+	mu     sync.Mutex
+	info   *Info
+	stopCh chan struct{}
+	lost   chan struct{}
+}
+
+// NewLease creates a compartment-tag lease for the named fleet.
+func NewLease(provider common.ConfigurationProvider, region, compartmentID, fleet string, ttl time.Duration) *Lease {
+	return &Lease{Provider: provider, Region: region, Compartment: compartmentID, fleet: fleet, ttl: ttl}
+}
+
+func (l *Lease) tagKey() string {
+	return LeaseTagPrefix + l.fleet
+}
+
+func (l *Lease) identityClient() (identity.IdentityClient, error) {
+	idc, err := identity.NewIdentityClientWithConfigurationProvider(l.Provider)
+	if err != nil {
+		return identity.IdentityClient{}, fmt.Errorf("identity client init: %w", err)
+	}
+	if l.Region != "" {
+		idc.SetRegion(l.Region)
+	}
+	return idc, nil
+}
+
+// encodeLease renders holder|operation|acquiredUnix|expiresUnix (tag values are limited to 256 chars).
+func encodeLease(i Info) string {
+	return fmt.Sprintf("%s|%s|%d|%d", i.Holder, i.Operation, i.AcquiredAt.Unix(), i.ExpiresAt.Unix())
+}
+
+func decodeLease(fleet, v string) (Info, bool) {
+	parts := strings.Split(v, "|")
+	if len(parts) != 4 {
+		return Info{}, false
+	}
+	acq, err1 := strconv.ParseInt(parts[2], 10, 64)
+	exp, err2 := strconv.ParseInt(parts[3], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Info{}, false
+	}
+	return Info{
+		Fleet:      fleet,
+		Holder:     parts[0],
+		Operation:  parts[1],
+		AcquiredAt: time.Unix(acq, 0),
+		ExpiresAt:  time.Unix(exp, 0),
+	}, true
+}
+
+// read returns the compartment's freeform tags, its ETag and the current lease (if any).
+func (l *Lease) read(ctx context.Context, idc identity.IdentityClient) (map[string]string, string, *Info, error) {
+	resp, err := idc.GetCompartment(ctx, identity.GetCompartmentRequest{CompartmentId: &l.Compartment})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("get compartment %s: %w", l.Compartment, err)
+	}
+	tags := map[string]string{}
+	for k, v := range resp.Compartment.FreeformTags {
+		tags[k] = v
+	}
+	etag := ""
+	if resp.Etag != nil {
+		etag = *resp.Etag
+	}
+	if v, ok := tags[l.tagKey()]; ok {
+		if info, ok := decodeLease(l.fleet, v); ok {
+			return tags, etag, &info, nil
+		}
+	}
+	return tags, etag, nil, nil
+}
+
+// write replaces the compartment's freeform tags, conditional on etag.
+func (l *Lease) write(ctx context.Context, idc identity.IdentityClient, tags map[string]string, etag string) error {
+	req := identity.UpdateCompartmentRequest{
+		CompartmentId:            &l.Compartment,
+		UpdateCompartmentDetails: identity.UpdateCompartmentDetails{FreeformTags: tags},
+	}
+	if etag != "" {
+		req.IfMatch = &etag
+	}
+	if _, err := idc.UpdateCompartment(ctx, req); err != nil {
+		if isPreconditionFailed(err) {
+			err = fmt.Errorf("%w: %w", errStaleETag, err)
+		}
+		return fmt.Errorf("update compartment %s lease tag: %w", l.Compartment, err)
+	}
+	return nil
+}
+
+// errStaleETag marks a write rejected because the compartment changed after it was read.
+var errStaleETag = errors.New("compartment changed since it was read")
+
+// isPreconditionFailed reports whether err is OCI's 412 answer to a stale If-Match.
+func isPreconditionFailed(err error) bool {
+	se, ok := common.IsServiceError(err)
+	return ok && se.GetHTTPStatusCode() == http.StatusPreconditionFailed
+}
+
+func (l *Lease) Acquire(ctx context.Context, op string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.info != nil {
+		return fmt.Errorf("lease %s already held by this process for %s", l.tagKey(), l.info.Operation)
+	}
+	idc, err := l.identityClient()
+	if err != nil {
+		return err
+	}
+	tags, etag, cur, err := l.read(ctx, idc)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	me := holderID()
+	if cur != nil && !cur.Expired(now) && cur.Holder != me {
+		return &LockedError{Backend: "oci-lease", Info: *cur}
+	}
+	info := Info{Fleet: l.fleet, Holder: me, Operation: op, AcquiredAt: now, ExpiresAt: now.Add(l.ttl)}
+	tags[l.tagKey()] = encodeLease(info)
+	if err := l.write(ctx, idc, tags, etag); err != nil {
+		// A concurrent writer changed the compartment between read and write (412).
+		if errors.Is(err, errStaleETag) {
+			return &LockedError{Backend: "oci-lease", Info: Info{Fleet: l.fleet, Holder: "concurrent writer"}}
+		}
+		return err
+	}
+	l.info = &info
+	l.stopCh = make(chan struct{})
+	l.lost = make(chan struct{})
+	go l.renew(l.stopCh)
+	return nil
+}
+
+// Lost returns a channel closed when the lease taken by the last Acquire was lost to
+// another holder; nil before the first Acquire.
+func (l *Lease) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// errLeaseLost is returned by extend when the tag no longer names this holder.
+var errLeaseLost = errors.New("lease taken over by another holder")
+
+// renew extends the lease every ttl/3 until stopped or lost. l.mu is only held to read
+// and update the in-memory lease, never across OCI calls, so Status and Release do not
+// wait for a slow renewal.
+func (l *Lease) renew(stop chan struct{}) {
+	t := time.NewTicker(l.ttl / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		l.mu.Lock()
+		if l.info == nil || l.stopCh != stop {
+			l.mu.Unlock()
+			return
+		}
+		info := *l.info
+		l.mu.Unlock()
+
+		next, err := l.extend(context.Background(), info)
+
+		l.mu.Lock()
+		switch {
+		case l.info == nil || l.stopCh != stop:
+			// Released while renewing; Release cleared the tag.
+			l.mu.Unlock()
+			return
+		case errors.Is(err, errLeaseLost):
+			log.Printf("lock: lease for fleet %s lost: %v", l.fleet, err)
+			l.info = nil
+			close(l.lost)
+			l.mu.Unlock()
+			return
+		case err != nil:
+			log.Printf("lock: renew lease for fleet %s: %v", l.fleet, err)
+		default:
+			l.info = &next
+		}
+		l.mu.Unlock()
+	}
+}
+
+// extend rewrites the lease tag with a new expiry, provided it still names info's holder.
+func (l *Lease) extend(ctx context.Context, info Info) (Info, error) {
+	idc, err := l.identityClient()
+	if err != nil {
+		return Info{}, err
+	}
+	tags, etag, cur, err := l.read(ctx, idc)
+	if err != nil {
+		return Info{}, err
+	}
+	if cur == nil {
+		return Info{}, fmt.Errorf("%w: tag %s was removed", errLeaseLost, l.tagKey())
+	}
+	if cur.Holder != info.Holder {
+		return Info{}, fmt.Errorf("%w: now held by %s for %s", errLeaseLost, cur.Holder, cur.Operation)
+	}
+	info.ExpiresAt = time.Now().Add(l.ttl)
+	tags[l.tagKey()] = encodeLease(info)
+	if err := l.write(ctx, idc, tags, etag); err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
+
+func (l *Lease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	if l.info == nil {
+		l.mu.Unlock()
+		return nil
+	}
+	close(l.stopCh)
+	l.info = nil
+	l.mu.Unlock()
+	return l.clear(ctx, true)
+}
+
+// clear removes the lease tag; when onlyMine is set, a tag owned by another holder is left alone.
+func (l *Lease) clear(ctx context.Context, onlyMine bool) error {
+	idc, err := l.identityClient()
+	if err != nil {
+		return err
+	}
+	tags, etag, cur, err := l.read(ctx, idc)
+	if err != nil {
+		return err
+	}
+	if _, ok := tags[l.tagKey()]; !ok {
+		return nil
+	}
+	if onlyMine && cur != nil && cur.Holder != holderID() {
+		return nil
+	}
+	delete(tags, l.tagKey())
+	return l.write(ctx, idc, tags, etag)
+}
+
+func (l *Lease) Status(ctx context.Context) (Status, error) {
+	l.mu.Lock()
+	if l.info != nil {
+		info := *l.info
+		l.mu.Unlock()
+		return Status{Backend: "oci-lease", Held: true, Info: &info}, nil
+	}
+	l.mu.Unlock()
+	idc, err := l.identityClient()
+	if err != nil {
+		return Status{}, err
+	}
+	_, _, cur, err := l.read(ctx, idc)
+	if err != nil {
+		return Status{}, err
+	}
+	if cur == nil || cur.Expired(time.Now()) {
+		return Status{Backend: "oci-lease", Info: cur}, nil
+	}
+	return Status{Backend: "oci-lease", Held: true, Info: cur}, nil
+}
+
+func (l *Lease) ForceUnlock(ctx context.Context) error {
+	l.mu.Lock()
+	if l.info != nil {
+		close(l.stopCh)
+		l.info = nil
+	}
+	l.mu.Unlock()
+	return l.clear(ctx, false)
+}
@@ -0,0 +1,177 @@
+// internal/lock/lock.go
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+)
+
+// DefaultTTL is the lease duration used when spec.lock.ttl is unset.
+const DefaultTTL = 10 * time.Minute
+
+// Info describes the current holder of a fleet lock.
+type Info struct {
+	Fleet      string    `json:"fleet"`
+	Holder     string    `json:"holder"` // host:pid of the owning process
+	Operation  string    `json:"operation"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the recorded lease has run out at now.
+func (i Info) Expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt)
+}
+
+// Status is a point-in-time view of a lock for diagnostics (/control, --status).
+type Status struct {
+	Backend string `json:"backend"`
+	Held    bool   `json:"held"`
+	Info    *Info  `json:"info,omitempty"`
+}
+
+// Locker is an advisory, cross-process lock guarding mutating operations on one fleet.
+type Locker interface {
+	// Acquire takes the lock for operation op, failing fast with *LockedError if another holder has it.
+	Acquire(ctx context.Context, op string) error
+	// Release gives up the lock if held by this process.
+	Release(ctx context.Context) error
+	// Status reports whether the lock is held and by whom.
+	Status(ctx context.Context) (Status, error)
+	// ForceUnlock clears the lock regardless of holder (escape hatch for crashed processes).
+	ForceUnlock(ctx context.Context) error
+}
+
+// ErrLost is the cause of an operation's context cancelled because its lock was lost.
+var ErrLost = errors.New("fleet lock lost")
+
+// LossWatcher is implemented by lockers that can lose a held lock to another process,
+// such as an OCI lease that expired before it was renewed and was taken over. Lost
+// returns a channel closed when the lock of the last Acquire is lost.
+type LossWatcher interface {
+	Lost() <-chan struct{}
+}
+
+// LockedError is returned by Acquire when another process holds the lock.
+type LockedError struct {
+	Backend string
+	Info    Info
+}
+
+func (e *LockedError) Error() string {
+	msg := fmt.Sprintf("fleet %q is locked (%s) by %s", e.Info.Fleet, e.Backend, e.Info.Holder)
+	if e.Info.Operation != "" {
+		msg += fmt.Sprintf(" for %s", e.Info.Operation)
+	}
+	if !e.Info.ExpiresAt.IsZero() {
+		msg += fmt.Sprintf(" until %s", e.Info.ExpiresAt.Format(time.RFC3339))
+	}
+	return msg + "; use --force-unlock if the holder is gone"
+}
+
+// holderID identifies this process as host:pid.
+func holderID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// ForFleet builds the locker configured by spec.lock: always a flock on lockPath,
+// plus an OCI compartment tag lease when spec.lock.lease is "oci".
+func ForFleet(cfg config.FleetConfig, lockPath string, provider common.ConfigurationProvider, region string) (Locker, error) {
+	ttl := cfg.Spec.Lock.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	fl := NewFile(lockPath, cfg.Metadata.Name, ttl)
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Spec.Lock.Lease)) {
+	case "", "none":
+		return fl, nil
+	case "oci":
+		if provider == nil {
+			return nil, fmt.Errorf("lock lease %q requires an OCI client", cfg.Spec.Lock.Lease)
+		}
+		compartment := strings.TrimSpace(cfg.Spec.Lock.LeaseCompartmentID)
+		if compartment == "" {
+			compartment = cfg.Spec.CompartmentID
+		}
+		return Multi{fl, NewLease(provider, region, compartment, cfg.Metadata.Name, ttl)}, nil
+	default:
+		return nil, fmt.Errorf("unknown spec.lock.lease %q (expected 'oci' or empty)", cfg.Spec.Lock.Lease)
+	}
+}
+
+// Multi acquires several lockers in order and releases them in reverse.
+type Multi []Locker
+
+func (m Multi) Acquire(ctx context.Context, op string) error {
+	for i, l := range m {
+		if err := l.Acquire(ctx, op); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				_ = m[j].Release(ctx)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Multi) Release(ctx context.Context) error {
+	var first error
+	for i := len(m) - 1; i >= 0; i-- {
+		if err := m[i].Release(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Status reports the first held lock, or the last (outermost) backend when none is held.
+func (m Multi) Status(ctx context.Context) (Status, error) {
+	var out Status
+	var names []string
+	for _, l := range m {
+		st, err := l.Status(ctx)
+		if err != nil {
+			return Status{}, err
+		}
+		names = append(names, st.Backend)
+		if st.Held && !out.Held {
+			out = st
+		}
+	}
+	out.Backend = strings.Join(names, "+")
+	return out, nil
+}
+
+// Lost reports the loss of the first member that can lose its lock; ForFleet builds at
+// most one (the lease). It returns nil when no member can.
+func (m Multi) Lost() <-chan struct{} {
+	for _, l := range m {
+		if w, ok := l.(LossWatcher); ok {
+			return w.Lost()
+		}
+	}
+	return nil
+}
+
+func (m Multi) ForceUnlock(ctx context.Context) error {
+	var first error
+	for _, l := range m {
+		if err := l.ForceUnlock(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,128 @@
+// internal/maintenance/window.go
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+// maxLookahead bounds the search for the next window opening.
+const maxLookahead = 8 * 24 * time.Hour
+
+// Window is open for Duration after each trigger time of its cron schedule.
+type Window struct {
+	Expr     string
+	Schedule *Schedule
+	Duration time.Duration
+}
+
+// Policy decides when disruptive operations (scale-down, rolling restart, replacements) may run.
+// A nil Policy means no maintenance windows are configured: always open.
+type Policy struct {
+	Windows  []Window
+	Location *time.Location
+	Queue    bool // outsideWindow: queue (API requests are queued instead of rejected)
+}
+
+// NewPolicy builds a Policy from spec.maintenanceWindows; nil or empty specs yield a nil Policy.
+func NewPolicy(spec *config.MaintenanceSpec) (*Policy, error) {
+	if spec == nil || len(spec.Windows) == 0 {
+		return nil, nil
+	}
+	loc := time.UTC
+	if tz := strings.TrimSpace(spec.TimeZone); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("maintenanceWindows.timeZone %q: %w", tz, err)
+		}
+		loc = l
+	}
+	p := &Policy{Location: loc}
+	switch strings.ToLower(strings.TrimSpace(spec.OutsideWindow)) {
+	case "", "reject":
+	case "queue":
+		p.Queue = true
+	default:
+		return nil, fmt.Errorf("maintenanceWindows.outsideWindow %q: expected reject or queue", spec.OutsideWindow)
+	}
+	for i, w := range spec.Windows {
+		sched, err := ParseCron(w.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("maintenanceWindows.windows[%d]: %w", i, err)
+		}
+		if w.Duration <= 0 || w.Duration > 7*24*time.Hour {
+			return nil, fmt.Errorf("maintenanceWindows.windows[%d]: duration must be between 1m and 168h", i)
+		}
+		p.Windows = append(p.Windows, Window{Expr: w.Cron, Schedule: sched, Duration: w.Duration})
+	}
+	return p, nil
+}
+
+// Open reports whether t falls inside any window.
+func (p *Policy) Open(t time.Time) bool {
+	if p == nil {
+		return true
+	}
+	t = t.In(p.Location).Truncate(time.Minute)
+	for _, w := range p.Windows {
+		// A window is open if it was triggered within the last Duration.
+		for m := t; t.Sub(m) < w.Duration; m = m.Add(-time.Minute) {
+			if w.Schedule.Matches(m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NextOpen returns the next time at or after t when a window opens.
+func (p *Policy) NextOpen(t time.Time) (time.Time, bool) {
+	if p == nil || p.Open(t) {
+		return t, true
+	}
+	t = t.In(p.Location).Truncate(time.Minute).Add(time.Minute)
+	for end := t.Add(maxLookahead); t.Before(end); t = t.Add(time.Minute) {
+		for _, w := range p.Windows {
+			if w.Schedule.Matches(t) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Describe summarizes the configured windows, e.g. "0 2 * * 6 for 4h0m0s (Europe/Berlin)".
+func (p *Policy) Describe() string {
+	if p == nil {
+		return "none (always open)"
+	}
+	parts := make([]string, 0, len(p.Windows))
+	for _, w := range p.Windows {
+		parts = append(parts, fmt.Sprintf("%q for %s", w.Expr, w.Duration))
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", "), p.Location)
+}
+
+// BlockedError explains why a disruptive operation was not run.
+type BlockedError struct {
+	Operation string
+	Reason    string
+	NextOpen  time.Time // zero when unknown or not window-related
+	Queued    bool      // the operation was queued to run when the window opens
+}
+
+func (e *BlockedError) Error() string {
+	msg := fmt.Sprintf("%s blocked: %s", e.Operation, e.Reason)
+	if !e.NextOpen.IsZero() {
+		msg += fmt.Sprintf("; next window opens %s", e.NextOpen.Format(time.RFC3339))
+	}
+	if e.Queued {
+		msg += "; queued until then"
+	} else {
+		msg += "; use --override-window \"<reason>\" to run anyway"
+	}
+	return msg
+}
@@ -0,0 +1,97 @@
+// internal/maintenance/window_test.go
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+func TestParseCron(t *testing.T) {
+	bad := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"}
+	for _, expr := range bad {
+		if _, err := ParseCron(expr); err == nil {
+			t.Fatalf("ParseCron(%q): expected error", expr)
+		}
+	}
+
+	s, err := ParseCron("30 2-4/2 * * 6,7")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	tests := []struct {
+		at   string
+		want bool
+	}{
+		{"2026-10-17T02:30:00Z", true},  // Saturday
+		{"2026-10-18T04:30:00Z", true},  // Sunday written as 7
+		{"2026-10-18T03:30:00Z", false}, // hour not in 2-4/2
+		{"2026-10-19T02:30:00Z", false}, // Monday
+		{"2026-10-17T02:31:00Z", false},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := s.Matches(at); got != tt.want {
+			t.Fatalf("Matches(%s) = %t, want %t", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestCronDayOfMonthOrDayOfWeek(t *testing.T) {
+	s, err := ParseCron("0 0 1 * 1")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	for _, at := range []string{"2026-10-01T00:00:00Z", "2026-10-19T00:00:00Z"} { // 1st (Thursday), a Monday
+		ts, _ := time.Parse(time.RFC3339, at)
+		if !s.Matches(ts) {
+			t.Fatalf("expected %s to match either day-of-month or day-of-week", at)
+		}
+	}
+}
+
+func TestPolicyOpenAndNextOpen(t *testing.T) {
+	p, err := NewPolicy(&config.MaintenanceSpec{
+		TimeZone: "America/New_York",
+		Windows:  []config.MaintenanceWindow{{Cron: "0 22 * * *", Duration: 3 * time.Hour}},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+
+	inside := time.Date(2026, 10, 18, 0, 30, 0, 0, ny) // window opened 22:00 the previous day
+	if !p.Open(inside) {
+		t.Fatalf("expected window open at %s", inside)
+	}
+	outside := time.Date(2026, 10, 18, 1, 0, 0, 0, ny) // 3h window closes at 01:00
+	if p.Open(outside) {
+		t.Fatalf("expected window closed at %s", outside)
+	}
+	next, ok := p.NextOpen(outside.UTC())
+	if !ok || !next.Equal(time.Date(2026, 10, 18, 22, 0, 0, 0, ny)) {
+		t.Fatalf("NextOpen = %s (%t), want 22:00 local", next, ok)
+	}
+
+	var none *Policy
+	if !none.Open(outside) {
+		t.Fatalf("nil policy must always be open")
+	}
+}
+
+func TestNewPolicyRejectsBadSpec(t *testing.T) {
+	specs := []*config.MaintenanceSpec{
+		{TimeZone: "Nowhere/City", Windows: []config.MaintenanceWindow{{Cron: "* * * * *", Duration: time.Hour}}},
+		{Windows: []config.MaintenanceWindow{{Cron: "* * * * *"}}},
+		{Windows: []config.MaintenanceWindow{{Cron: "* * * * *", Duration: time.Hour}}, OutsideWindow: "defer"},
+	}
+	for i, s := range specs {
+		if _, err := NewPolicy(s); err == nil {
+			t.Fatalf("spec %d: expected error", i)
+		}
+	}
+	if p, err := NewPolicy(nil); p != nil || err != nil {
+		t.Fatalf("nil spec: got %v, %v", p, err)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"time"
 )
 
-// ActionsMetrics tracks live operation metrics for user-visible progress via /metrics.
-type ActionsMetrics struct {
+// Metrics tracks one fleet's live operation metrics for user-visible progress via
+// /metrics, plus totals since it was created for /metrics/prometheus. Each Fleet owns
+// one (see fleet.New); operations of a fleet are serialized, so they never interleave.
+type Metrics struct {
 	mu sync.RWMutex
 
 	// High-level operation context
@@ -26,6 +28,11 @@ type ActionsMetrics struct {
 	TerminateSucceeded int
 	TerminateFailed    int
 
+	// Warm pool: instances started from the pool in this operation, and pool gauges
+	WarmStarted   int
+	WarmPoolSize  int // configured pool size
+	WarmPoolReady int // STOPPED instances ready to start
+
 	// Rolling restart book-keeping
 	RollingRestartIndex int // 1-based index of current item
 	RollingRestartTotal int
@@ -34,6 +41,13 @@ type ActionsMetrics struct {
 	LbEnabled  bool
 	LbId       string
 	LbBackends int
+	// Spec changes the running LB cannot take in place
+	LbNeedsReplacement []string
+	// Backend changes of the current operation: work requests used, work requests
+	// saved by batching, and backend sets that fell back to per-backend calls
+	LbWorkRequests      int
+	LbWorkRequestsSaved int
+	LbBatchFallbacks    int
 
 	// Scale target context
 	TargetTotal int
@@ -44,260 +58,347 @@ type ActionsMetrics struct {
 
 	// Last error encountered (if any)
 	LastError string
+
+	// Totals since New, for the Prometheus endpoint; Reset keeps them
+	launches     map[string]int // by result: succeeded, failed
+	terminations map[string]int
+	warmStarts   int
+	durations    map[[2]string]*Histogram // by operation and result
 }
 
-var global = &ActionsMetrics{}
+// New returns empty metrics.
+func New() *Metrics {
+	return &Metrics{
+		launches:     map[string]int{},
+		terminations: map[string]int{},
+		durations:    map[[2]string]*Histogram{},
+	}
+}
 
-// Reset initializes/overwrites the current operation and clears counters.
-func Reset(operation string) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.Operation = operation
-	global.Phase = "planning"
+// Reset initializes/overwrites the current operation and clears its counters; the totals
+// since New are kept.
+func (m *Metrics) Reset(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Operation = operation
+	m.Phase = "planning"
 	now := time.Now()
-	global.StartedAt = now
-	global.LastUpdate = now
-	global.TargetTotal = 0
-	global.StartTotal = 0
+	m.StartedAt = now
+	m.LastUpdate = now
+	m.TargetTotal = 0
+	m.StartTotal = 0
+
+	m.LaunchRequested = 0
+	m.LaunchSucceeded = 0
+	m.LaunchFailed = 0
+
+	m.TerminateRequested = 0
+	m.TerminateSucceeded = 0
+	m.TerminateFailed = 0
 
-	global.LaunchRequested = 0
-	global.LaunchSucceeded = 0
-	global.LaunchFailed = 0
+	m.WarmStarted = 0
 
-	global.TerminateRequested = 0
-	global.TerminateSucceeded = 0
-	global.TerminateFailed = 0
+	m.LbWorkRequests = 0
+	m.LbWorkRequestsSaved = 0
+	m.LbBatchFallbacks = 0
 
-	global.RollingRestartIndex = 0
-	global.RollingRestartTotal = 0
+	m.RollingRestartIndex = 0
+	m.RollingRestartTotal = 0
 
-	global.LastError = ""
+	m.LastError = ""
 }
 
-// Done marks the current operation as completed.
-func Done() {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.Phase = "done"
+// Done marks the current operation as completed and records its duration, with result
+// error when the operation recorded an error.
+func (m *Metrics) Done() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Operation != "" {
+		result := "success"
+		if m.LastError != "" {
+			result = "error"
+		}
+		k := [2]string{m.Operation, result}
+		if m.durations[k] == nil {
+			m.durations[k] = NewHistogram(DurationBuckets)
+		}
+		m.durations[k].Observe(time.Since(m.StartedAt).Seconds())
+	}
+	m.Phase = "done"
 	// Clear operation so UI badge shows "Scaling idle" until next Reset()
-	global.Operation = ""
-	global.LastUpdate = time.Now()
+	m.Operation = ""
+	m.LastUpdate = time.Now()
 }
 
 // SetPhase updates the current phase.
-func SetPhase(phase string) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.Phase = phase
-	global.LastUpdate = time.Now()
+func (m *Metrics) SetPhase(phase string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Phase = phase
+	m.LastUpdate = time.Now()
 }
 
 // SetError records the last error string.
-func SetError(err string) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.LastError = err
-	global.LastUpdate = time.Now()
+func (m *Metrics) SetError(err string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LastError = err
+	m.LastUpdate = time.Now()
 }
 
 // SetScaleTargets sets the starting and target totals for a scale operation.
-func SetScaleTargets(start, target int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
+func (m *Metrics) SetScaleTargets(start, target int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if start < 0 {
 		start = 0
 	}
 	if target < 0 {
 		target = 0
 	}
-	global.StartTotal = start
-	global.TargetTotal = target
-	global.LastUpdate = time.Now()
+	m.StartTotal = start
+	m.TargetTotal = target
+	m.LastUpdate = time.Now()
 }
 
 // IncLaunchRequested increments the number of launches requested by n (can be negative to correct).
-func IncLaunchRequested(n int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.LaunchRequested += n
-	if global.LaunchRequested < 0 {
-		global.LaunchRequested = 0
+func (m *Metrics) IncLaunchRequested(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LaunchRequested += n
+	if m.LaunchRequested < 0 {
+		m.LaunchRequested = 0
 	}
-	global.LastUpdate = time.Now()
+	m.LastUpdate = time.Now()
 }
 
 // IncLaunchSucceeded increments the number of successful launches by 1.
-func IncLaunchSucceeded() {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.LaunchSucceeded++
-	global.LastUpdate = time.Now()
+func (m *Metrics) IncLaunchSucceeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LaunchSucceeded++
+	m.launches["succeeded"]++
+	m.LastUpdate = time.Now()
 }
 
 // IncLaunchFailed increments the number of failed launches by 1 and records err (optional).
-func IncLaunchFailed(err string) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.LaunchFailed++
+func (m *Metrics) IncLaunchFailed(err string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LaunchFailed++
+	m.launches["failed"]++
 	if err != "" {
-		global.LastError = err
+		m.LastError = err
 	}
-	global.LastUpdate = time.Now()
+	m.LastUpdate = time.Now()
 }
 
 // IncTerminateRequested increments the number of terminations requested by n (can be negative to correct).
-func IncTerminateRequested(n int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.TerminateRequested += n
-	if global.TerminateRequested < 0 {
-		global.TerminateRequested = 0
+func (m *Metrics) IncTerminateRequested(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TerminateRequested += n
+	if m.TerminateRequested < 0 {
+		m.TerminateRequested = 0
 	}
-	global.LastUpdate = time.Now()
+	m.LastUpdate = time.Now()
 }
 
 // IncTerminateSucceeded increments the number of successful terminations by 1.
-func IncTerminateSucceeded() {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.TerminateSucceeded++
-	global.LastUpdate = time.Now()
+func (m *Metrics) IncTerminateSucceeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TerminateSucceeded++
+	m.terminations["succeeded"]++
+	m.LastUpdate = time.Now()
 }
 
 // IncTerminateFailed increments the number of failed terminations by 1 and records err (optional).
-func IncTerminateFailed(err string) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.TerminateFailed++
+func (m *Metrics) IncTerminateFailed(err string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TerminateFailed++
+	m.terminations["failed"]++
 	if err != "" {
-		global.LastError = err
+		m.LastError = err
 	}
-	global.LastUpdate = time.Now()
+	m.LastUpdate = time.Now()
+}
+
+// IncWarmStarted increments the number of warm pool instances started by 1.
+func (m *Metrics) IncWarmStarted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.WarmStarted++
+	m.warmStarts++
+	m.LastUpdate = time.Now()
+}
+
+// SetWarmPool sets the warm pool gauges (configured size and ready instances).
+func (m *Metrics) SetWarmPool(size, ready int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.WarmPoolSize = size
+	m.WarmPoolReady = ready
+	m.LastUpdate = time.Now()
 }
 
 // SetRollingRestart sets current index (1-based) and total items for rolling restart.
-func SetRollingRestart(index, total int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.RollingRestartIndex = index
-	global.RollingRestartTotal = total
-	global.LastUpdate = time.Now()
+func (m *Metrics) SetRollingRestart(index, total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RollingRestartIndex = index
+	m.RollingRestartTotal = total
+	m.LastUpdate = time.Now()
 }
 
 // UpdateLB sets the load balancer snapshot fields.
-func UpdateLB(enabled bool, id string, backends int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	global.LbEnabled = enabled
-	global.LbId = id
+func (m *Metrics) UpdateLB(enabled bool, id string, backends int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LbEnabled = enabled
+	m.LbId = id
 	if backends < 0 {
 		backends = 0
 	}
-	global.LbBackends = backends
-	global.LastUpdate = time.Now()
+	m.LbBackends = backends
+	m.LastUpdate = time.Now()
+}
+
+// SetLBNeedsReplacement records the spec changes that need the LB recreated.
+func (m *Metrics) SetLBNeedsReplacement(reasons []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LbNeedsReplacement = append([]string(nil), reasons...)
+	m.LastUpdate = time.Now()
+}
+
+// AddLBWorkRequests counts the LB work requests used and saved by a batch of backend
+// changes.
+func (m *Metrics) AddLBWorkRequests(used, saved, fallbacks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LbWorkRequests += used
+	m.LbWorkRequestsSaved += saved
+	m.LbBatchFallbacks += fallbacks
+	m.LastUpdate = time.Now()
+}
+
+// LBBackends returns the LB backend count last published.
+func (m *Metrics) LBBackends() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.LbBackends
 }
 
 // SetLBBackends updates just the backend count (e.g., during reconcile).
-func SetLBBackends(n int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
+func (m *Metrics) SetLBBackends(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if n < 0 {
 		n = 0
 	}
-	global.LbBackends = n
-	global.LastUpdate = time.Now()
+	m.LbBackends = n
+	m.LastUpdate = time.Now()
 }
 
-// Snapshot returns a copy of current metrics suitable for JSON encoding.
 // AppendScaleQueue appends a desired target to the queue.
-func AppendScaleQueue(v int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
+func (m *Metrics) AppendScaleQueue(v int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if v < 0 {
 		return
 	}
-	global.ScaleQueue = append(global.ScaleQueue, v)
-	global.LastUpdate = time.Now()
+	m.ScaleQueue = append(m.ScaleQueue, v)
+	m.LastUpdate = time.Now()
 }
 
 // RemoveScaleQueueValue removes the first occurrence of v from the queue.
-func RemoveScaleQueueValue(v int) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	for i, x := range global.ScaleQueue {
+func (m *Metrics) RemoveScaleQueueValue(v int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, x := range m.ScaleQueue {
 		if x == v {
-			global.ScaleQueue = append(global.ScaleQueue[:i], global.ScaleQueue[i+1:]...)
+			m.ScaleQueue = append(m.ScaleQueue[:i], m.ScaleQueue[i+1:]...)
 			break
 		}
 	}
-	global.LastUpdate = time.Now()
+	m.LastUpdate = time.Now()
 }
 
 // PeekScaleQueue returns the head value without removing it.
-func PeekScaleQueue() (int, bool) {
-	global.mu.RLock()
-	defer global.mu.RUnlock()
-	if len(global.ScaleQueue) == 0 {
+func (m *Metrics) PeekScaleQueue() (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.ScaleQueue) == 0 {
 		return 0, false
 	}
-	return global.ScaleQueue[0], true
+	return m.ScaleQueue[0], true
 }
 
 // PopScaleQueue removes and returns the head value (FIFO). Returns false if empty.
-func PopScaleQueue() (int, bool) {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	if len(global.ScaleQueue) == 0 {
-		global.LastUpdate = time.Now()
+func (m *Metrics) PopScaleQueue() (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.ScaleQueue) == 0 {
+		m.LastUpdate = time.Now()
 		return 0, false
 	}
-	v := global.ScaleQueue[0]
+	v := m.ScaleQueue[0]
 	// re-slice with a fresh backing array to avoid retaining references
-	global.ScaleQueue = append([]int{}, global.ScaleQueue[1:]...)
-	global.LastUpdate = time.Now()
+	m.ScaleQueue = append([]int{}, m.ScaleQueue[1:]...)
+	m.LastUpdate = time.Now()
 	return v, true
 }
 
 // PopScaleQueueIfHead pops the head only if it matches expected. Returns true on pop.
-func PopScaleQueueIfHead(expected int) bool {
-	global.mu.Lock()
-	defer global.mu.Unlock()
-	if len(global.ScaleQueue) == 0 {
-		global.LastUpdate = time.Now()
+func (m *Metrics) PopScaleQueueIfHead(expected int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.ScaleQueue) == 0 {
+		m.LastUpdate = time.Now()
 		return false
 	}
-	if global.ScaleQueue[0] == expected {
-		global.ScaleQueue = append([]int{}, global.ScaleQueue[1:]...)
-		global.LastUpdate = time.Now()
+	if m.ScaleQueue[0] == expected {
+		m.ScaleQueue = append([]int{}, m.ScaleQueue[1:]...)
+		m.LastUpdate = time.Now()
 		return true
 	}
-	global.LastUpdate = time.Now()
+	m.LastUpdate = time.Now()
 	return false
 }
 
-func Snapshot() map[string]any {
-	global.mu.RLock()
-	defer global.mu.RUnlock()
+// Snapshot returns a copy of current metrics suitable for JSON encoding.
+func (m *Metrics) Snapshot() map[string]any {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	out := map[string]any{
-		"operation":           global.Operation,
-		"phase":               global.Phase,
-		"startedAt":           global.StartedAt.Format(time.RFC3339),
-		"lastUpdate":          global.LastUpdate.Format(time.RFC3339),
-		"launchRequested":     global.LaunchRequested,
-		"launchSucceeded":     global.LaunchSucceeded,
-		"launchFailed":        global.LaunchFailed,
-		"terminateRequested":  global.TerminateRequested,
-		"terminateSucceeded":  global.TerminateSucceeded,
-		"terminateFailed":     global.TerminateFailed,
-		"rollingRestartIndex": global.RollingRestartIndex,
-		"rollingRestartTotal": global.RollingRestartTotal,
-		"lbEnabled":           global.LbEnabled,
-		"lbId":                global.LbId,
-		"lbBackends":          global.LbBackends,
-		"startTotal":          global.StartTotal,
-		"targetTotal":         global.TargetTotal,
-		"lastError":           global.LastError,
-		"scaleQueue":          append([]int{}, global.ScaleQueue...),
+		"operation":           m.Operation,
+		"phase":               m.Phase,
+		"startedAt":           m.StartedAt.Format(time.RFC3339),
+		"lastUpdate":          m.LastUpdate.Format(time.RFC3339),
+		"launchRequested":     m.LaunchRequested,
+		"launchSucceeded":     m.LaunchSucceeded,
+		"launchFailed":        m.LaunchFailed,
+		"terminateRequested":  m.TerminateRequested,
+		"terminateSucceeded":  m.TerminateSucceeded,
+		"terminateFailed":     m.TerminateFailed,
+		"warmStarted":         m.WarmStarted,
+		"warmPoolSize":        m.WarmPoolSize,
+		"warmPoolReady":       m.WarmPoolReady,
+		"rollingRestartIndex": m.RollingRestartIndex,
+		"rollingRestartTotal": m.RollingRestartTotal,
+		"lbEnabled":           m.LbEnabled,
+		"lbId":                m.LbId,
+		"lbBackends":          m.LbBackends,
+		"lbNeedsReplacement":  append([]string{}, m.LbNeedsReplacement...),
+		"lbWorkRequests":      m.LbWorkRequests,
+		"lbWorkRequestsSaved": m.LbWorkRequestsSaved,
+		"lbBatchFallbacks":    m.LbBatchFallbacks,
+		"startTotal":          m.StartTotal,
+		"targetTotal":         m.TargetTotal,
+		"lastError":           m.LastError,
+		"scaleQueue":          append([]int{}, m.ScaleQueue...),
 	}
 	return out
 }
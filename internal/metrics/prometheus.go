@@ -0,0 +1,173 @@
+// internal/metrics/prometheus.go
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DurationBuckets are the upper bounds, in seconds, of the operation duration histogram.
+var DurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	Buckets []float64 // upper bounds, ascending; +Inf is implied
+	Counts  []uint64  // observations <= Buckets[i]
+	Count   uint64
+	Sum     float64
+}
+
+// NewHistogram returns an empty histogram over buckets.
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{Buckets: buckets, Counts: make([]uint64, len(buckets))}
+}
+
+// Observe adds v to the histogram.
+func (h *Histogram) Observe(v float64) {
+	for i, b := range h.Buckets {
+		if v <= b {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += v
+}
+
+// Exposition collects samples and writes them in the Prometheus text format. Samples of
+// one metric family are written together under a single HELP and TYPE header, in the
+// order the families were first added, so several fleets can add to the same families.
+type Exposition struct {
+	families []*family
+	byName   map[string]*family
+}
+
+type family struct {
+	name, typ, help string
+	lines           []string
+}
+
+func (e *Exposition) family(name, typ, help string) *family {
+	if e.byName == nil {
+		e.byName = map[string]*family{}
+	}
+	f, ok := e.byName[name]
+	if !ok {
+		f = &family{name: name, typ: typ, help: help}
+		e.byName[name] = f
+		e.families = append(e.families, f)
+	}
+	return f
+}
+
+// Gauge adds a gauge sample. labels are name, value pairs.
+func (e *Exposition) Gauge(name, help string, v float64, labels ...string) {
+	f := e.family(name, "gauge", help)
+	f.lines = append(f.lines, sample(name, labels, v))
+}
+
+// Counter adds a counter sample; name should end in _total.
+func (e *Exposition) Counter(name, help string, v float64, labels ...string) {
+	f := e.family(name, "counter", help)
+	f.lines = append(f.lines, sample(name, labels, v))
+}
+
+// Histogram adds the _bucket, _sum and _count samples of h.
+func (e *Exposition) Histogram(name, help string, h *Histogram, labels ...string) {
+	f := e.family(name, "histogram", help)
+	for i, b := range h.Buckets {
+		f.lines = append(f.lines, sample(name+"_bucket", append(labels[:len(labels):len(labels)], "le", formatFloat(b)), float64(h.Counts[i])))
+	}
+	f.lines = append(f.lines,
+		sample(name+"_bucket", append(labels[:len(labels):len(labels)], "le", "+Inf"), float64(h.Count)),
+		sample(name+"_sum", labels, h.Sum),
+		sample(name+"_count", labels, float64(h.Count)),
+	)
+}
+
+// WriteTo writes every family in the text format.
+func (e *Exposition) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, f := range e.families {
+		m, _ := bw.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n# TYPE " + f.name + " " + f.typ + "\n")
+		n += int64(m)
+		for _, l := range f.lines {
+			m, _ = bw.WriteString(l)
+			n += int64(m)
+		}
+	}
+	return n, bw.Flush()
+}
+
+func sample(name string, labels []string, v float64) string {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 1 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteString(" " + formatFloat(v) + "\n")
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+// Collect adds the operation series kept since m was created: launch and terminate
+// results, warm pool starts and operation durations. labels (name, value pairs, e.g.
+// fleet) are added to every sample.
+func (m *Metrics) Collect(e *Exposition, labels ...string) {
+	with := func(kv ...string) []string { return append(labels[:len(labels):len(labels)], kv...) }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, r := range []string{"succeeded", "failed"} {
+		e.Counter("fleetctl_launches_total", "Instance launches by result.", float64(m.launches[r]), with("result", r)...)
+	}
+	for _, r := range []string{"succeeded", "failed"} {
+		e.Counter("fleetctl_terminations_total", "Instance terminations by result.", float64(m.terminations[r]), with("result", r)...)
+	}
+	e.Counter("fleetctl_warm_pool_starts_total", "Warm pool instances started by scale-up.", float64(m.warmStarts), labels...)
+	keys := make([][2]string, 0, len(m.durations))
+	for k := range m.durations {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		e.Histogram("fleetctl_operation_duration_seconds", "Duration of scale and rolling restart operations by result.", m.durations[k], with("operation", k[0], "result", k[1])...)
+	}
+}
@@ -0,0 +1,69 @@
+// internal/metrics/prometheus_test.go
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpositionGroupsFamilies(t *testing.T) {
+	var e Exposition
+	e.Gauge("fleetctl_instances_desired", "Desired instances.", 3, "fleet", "shop", "group", "web")
+	e.Counter("fleetctl_control_loop_ticks_total", "Ticks.", 12, "fleet", "shop")
+	e.Gauge("fleetctl_instances_desired", "Desired instances.", 1, "fleet", `a"b\c`, "group", "web")
+	h := NewHistogram([]float64{1, 10})
+	h.Observe(0.5)
+	h.Observe(4)
+	e.Histogram("fleetctl_operation_duration_seconds", "Durations.", h, "operation", "scale-up")
+	var b strings.Builder
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP fleetctl_instances_desired Desired instances.
+# TYPE fleetctl_instances_desired gauge
+fleetctl_instances_desired{fleet="shop",group="web"} 3
+fleetctl_instances_desired{fleet="a\"b\\c",group="web"} 1
+# HELP fleetctl_control_loop_ticks_total Ticks.
+# TYPE fleetctl_control_loop_ticks_total counter
+fleetctl_control_loop_ticks_total{fleet="shop"} 12
+# HELP fleetctl_operation_duration_seconds Durations.
+# TYPE fleetctl_operation_duration_seconds histogram
+fleetctl_operation_duration_seconds_bucket{operation="scale-up",le="1"} 1
+fleetctl_operation_duration_seconds_bucket{operation="scale-up",le="10"} 2
+fleetctl_operation_duration_seconds_bucket{operation="scale-up",le="+Inf"} 2
+fleetctl_operation_duration_seconds_sum{operation="scale-up"} 4.5
+fleetctl_operation_duration_seconds_count{operation="scale-up"} 2
+`
+	if b.String() != want {
+		t.Fatalf("exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestCollectKeepsTotalsAcrossOperations(t *testing.T) {
+	m := New()
+	m.Reset("scale-up")
+	m.IncLaunchSucceeded()
+	m.IncLaunchFailed("capacity")
+	m.Done()
+	m.Reset("scale-down")
+	m.IncTerminateSucceeded()
+	m.Done()
+
+	var e Exposition
+	m.Collect(&e, "fleet", "shop")
+	var b strings.Builder
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`fleetctl_launches_total{fleet="shop",result="succeeded"} 1`,
+		`fleetctl_launches_total{fleet="shop",result="failed"} 1`,
+		`fleetctl_terminations_total{fleet="shop",result="succeeded"} 1`,
+		`fleetctl_operation_duration_seconds_count{fleet="shop",operation="scale-up",result="error"} 1`,
+		`fleetctl_operation_duration_seconds_count{fleet="shop",operation="scale-down",result="success"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, b.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// internal/metrics/registry.go
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry is the aggregated view of the Metrics of every fleet served by one process.
+type Registry struct {
+	mu     sync.RWMutex
+	fleets map[string]*Metrics
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{fleets: map[string]*Metrics{}}
+}
+
+// Register adds the metrics of fleet, replacing earlier ones.
+func (r *Registry) Register(fleet string, m *Metrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fleets[fleet] = m
+}
+
+// Get returns the metrics of fleet.
+func (r *Registry) Get(fleet string) (*Metrics, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.fleets[fleet]
+	return m, ok
+}
+
+// names returns the registered fleets, sorted.
+func (r *Registry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.fleets))
+	for name := range r.fleets {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Collect adds the series of every fleet, labelled fleet=<name>.
+func (r *Registry) Collect(e *Exposition) {
+	for _, name := range r.names() {
+		if m, ok := r.Get(name); ok {
+			m.Collect(e, "fleet", name)
+		}
+	}
+}
+
+// totalKeys are the Snapshot counters summed over fleets.
+var totalKeys = []string{
+	"launchRequested", "launchSucceeded", "launchFailed",
+	"terminateRequested", "terminateSucceeded", "terminateFailed",
+	"warmStarted", "lbBackends", "lbWorkRequests", "lbWorkRequestsSaved", "lbBatchFallbacks",
+}
+
+// Snapshot returns every fleet's Snapshot under "fleets", the operations in progress
+// (fleet -> operation) under "running" and the counters summed over fleets under "totals".
+func (r *Registry) Snapshot() map[string]any {
+	fleets := map[string]any{}
+	running := map[string]string{}
+	totals := map[string]int{}
+	for _, name := range r.names() {
+		m, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		snap := m.Snapshot()
+		fleets[name] = snap
+		if op, _ := snap["operation"].(string); op != "" {
+			running[name] = op
+		}
+		for _, k := range totalKeys {
+			v, _ := snap[k].(int)
+			totals[k] += v
+		}
+	}
+	return map[string]any{"fleets": fleets, "running": running, "totals": totals}
+}
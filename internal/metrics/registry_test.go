@@ -0,0 +1,53 @@
+// internal/metrics/registry_test.go
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFleetMetricsDoNotShareCounters(t *testing.T) {
+	shop, blog := New(), New()
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(2)
+		go func() { defer wg.Done(); shop.IncLaunchSucceeded() }()
+		go func() { defer wg.Done(); blog.IncTerminateSucceeded() }()
+	}
+	wg.Wait()
+	shop.Reset("scale-up") // clears the operation, not the totals
+
+	r := NewRegistry()
+	r.Register("shop", shop)
+	r.Register("blog", blog)
+	blog.Reset("scale-down")
+	blog.IncTerminateRequested(2)
+
+	snap := r.Snapshot()
+	totals := snap["totals"].(map[string]int)
+	if totals["terminateRequested"] != 2 || totals["launchSucceeded"] != 0 {
+		t.Fatalf("totals = %v", totals)
+	}
+	running := snap["running"].(map[string]string)
+	if len(running) != 2 || running["shop"] != "scale-up" || running["blog"] != "scale-down" {
+		t.Fatalf("running = %v", running)
+	}
+
+	var e Exposition
+	r.Collect(&e)
+	var b strings.Builder
+	if _, err := e.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`fleetctl_launches_total{fleet="blog",result="succeeded"} 0`,
+		`fleetctl_launches_total{fleet="shop",result="succeeded"} 50`,
+		`fleetctl_terminations_total{fleet="blog",result="succeeded"} 50`,
+		`fleetctl_terminations_total{fleet="shop",result="succeeded"} 0`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, b.String())
+		}
+	}
+}
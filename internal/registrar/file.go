@@ -0,0 +1,125 @@
+// internal/registrar/file.go
+package registrar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// File renders a text/template of the fleet's members (an HAProxy backend, an nginx
+// upstream block, ...) to a file and runs a reload command when the output changed.
+type File struct {
+	name    string
+	fleet   string
+	tmpl    *template.Template
+	path    string
+	reload  []string
+	timeout time.Duration
+}
+
+// FileData is what templates are rendered with.
+type FileData struct {
+	Fleet   string
+	Members []Member            // sorted by group, name and ID
+	Groups  map[string][]Member // members per instance group
+}
+
+// NewFile parses the template file tmplPath; path receives the output and reload (argv,
+// optional) runs after it changed.
+func NewFile(name, fleet, tmplPath, path string, reload []string, timeout time.Duration) (*File, error) {
+	if tmplPath == "" || path == "" {
+		return nil, fmt.Errorf("type file needs template and path")
+	}
+	tmpl, err := template.New(filepath.Base(tmplPath)).Option("missingkey=error").ParseFiles(tmplPath)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return &File{name: name, fleet: fleet, tmpl: tmpl, path: path, reload: reload, timeout: timeout}, nil
+}
+
+func (r *File) Name() string { return r.name }
+
+// Apply renders u.Members and, when the output differs from the file on disk, replaces
+// the file (write to a temporary file, then rename) and runs the reload command. When the
+// reload fails the previous file is put back, so the next update tries again.
+func (r *File) Apply(ctx context.Context, u Update) error {
+	out, err := r.render(u.Members)
+	if err != nil {
+		return err
+	}
+	prev, err := os.ReadFile(r.path)
+	if err == nil && bytes.Equal(prev, out) {
+		return nil
+	}
+	existed := err == nil
+	if err := writeFileAtomic(r.path, out); err != nil {
+		return err
+	}
+	log.Printf("registrar %s: wrote %s (%d members)", r.name, r.path, len(u.Members))
+	if err := r.runReload(ctx); err != nil {
+		if existed {
+			_ = writeFileAtomic(r.path, prev)
+		} else {
+			_ = os.Remove(r.path)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *File) render(members []Member) ([]byte, error) {
+	data := FileData{Fleet: r.fleet, Members: sorted(members), Groups: map[string][]Member{}}
+	for _, m := range data.Members {
+		data.Groups[m.Group] = append(data.Groups[m.Group], m)
+	}
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *File) runReload(ctx context.Context) error {
+	if len(r.reload) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, r.reload[0], r.reload[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("reload %q: %w: %s", strings.Join(r.reload, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
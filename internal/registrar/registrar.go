@@ -0,0 +1,131 @@
+// internal/registrar/registrar.go
+package registrar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+// DefaultTimeout bounds reload commands and webhook requests when spec.registrars[].timeout is unset.
+const DefaultTimeout = 30 * time.Second
+
+// Member is an instance announced to a registrar.
+type Member struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`  // display name
+	Group string `json:"group"` // instance group
+	IP    string `json:"ip"`    // primary private IP
+	Port  int    `json:"port"`  // backend port of the registrar (spec.registrars[].port); 0 when unset
+}
+
+// Update is one change to the instances receiving traffic.
+type Update struct {
+	Members []Member // every instance that receives traffic once the update is applied
+	Added   []Member
+	Removed []Member
+	Sync    bool // from a reconcile: Members is authoritative and Added/Removed are empty
+}
+
+// Registrar announces fleet instances to whatever routes traffic to them: the OCI load
+// balancer (in fleet), a templated upstream file (File) or an HTTP webhook (Webhook).
+type Registrar interface {
+	// Name identifies the registrar in logs and errors.
+	Name() string
+	// Apply registers u.Added and deregisters u.Removed, or with u.Sync makes the
+	// registrar match u.Members exactly.
+	Apply(ctx context.Context, u Update) error
+}
+
+// Open builds the registrars of spec.registrars for fleet. Registrars that are
+// misconfigured are left out and their errors joined.
+func Open(fleet string, specs []config.RegistrarSpec) ([]Registrar, error) {
+	var (
+		regs []Registrar
+		errs []error
+		seen = map[string]bool{}
+	)
+	for i, spec := range specs {
+		name := spec.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", strings.ToLower(spec.Type), i)
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("registrars[%d]: duplicate name %q", i, name))
+			continue
+		}
+		seen[name] = true
+		timeout := spec.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		var (
+			r   Registrar
+			err error
+		)
+		switch strings.ToLower(spec.Type) {
+		case "file":
+			r, err = NewFile(name, fleet, spec.Template, spec.Path, spec.ReloadCommand, timeout)
+		case "webhook":
+			r, err = NewWebhook(name, fleet, spec.URL, spec.Headers, spec.TokenEnv, timeout)
+		default:
+			err = fmt.Errorf("unknown type %q (use file or webhook)", spec.Type)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("registrars[%s]: %w", name, err))
+			continue
+		}
+		regs = append(regs, Scoped(r, spec.Groups, spec.Port))
+	}
+	return regs, errors.Join(errs...)
+}
+
+// Scoped narrows the updates r receives to instance groups (all when empty) and sets port
+// on their members. Updates that touch none of the groups are skipped.
+func Scoped(r Registrar, groups []string, port int) Registrar {
+	return scoped{Registrar: r, groups: groups, port: port}
+}
+
+type scoped struct {
+	Registrar
+	groups []string
+	port   int
+}
+
+func (s scoped) Apply(ctx context.Context, u Update) error {
+	u = Update{Members: s.scope(u.Members), Added: s.scope(u.Added), Removed: s.scope(u.Removed), Sync: u.Sync}
+	if !u.Sync && len(u.Added) == 0 && len(u.Removed) == 0 {
+		return nil // none of its groups changed
+	}
+	return s.Registrar.Apply(ctx, u)
+}
+
+func (s scoped) scope(ms []Member) []Member {
+	out := make([]Member, 0, len(ms))
+	for _, m := range ms {
+		if len(s.groups) > 0 && !slices.Contains(s.groups, m.Group) {
+			continue
+		}
+		m.Port = s.port
+		out = append(out, m)
+	}
+	return out
+}
+
+// Sort orders members by group, name and ID so rendered output and payloads are stable.
+func Sort(ms []Member) {
+	slices.SortFunc(ms, func(a, b Member) int {
+		if c := strings.Compare(a.Group, b.Group); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,122 @@
+// internal/registrar/registrar_test.go
+package registrar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fleetctl/internal/config"
+)
+
+var members = []Member{
+	{ID: "c", Name: "web-2", Group: "web", IP: "10.0.0.2"},
+	{ID: "a", Name: "api-1", Group: "api", IP: "10.0.1.1"},
+	{ID: "b", Name: "web-1", Group: "web", IP: "10.0.0.1"},
+}
+
+func TestFileRendersAndReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "upstream.tmpl")
+	out := filepath.Join(dir, "upstream.conf")
+	reloads := filepath.Join(dir, "reloads")
+	src := "# {{.Fleet}}\nupstream web {\n{{range index .Groups \"web\"}}  server {{.IP}}:{{.Port}}; # {{.Name}}\n{{end}}}\n"
+	if err := os.WriteFile(tmpl, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	regs, err := Open("shop", []config.RegistrarSpec{{
+		Type: "file", Groups: []string{"web"}, Port: 8080, Template: tmpl, Path: out,
+		ReloadCommand: []string{"sh", "-c", "echo reload >> " + reloads},
+	}})
+	if err != nil || len(regs) != 1 || regs[0].Name() != "file-0" {
+		t.Fatalf("Open = %v, %v", regs, err)
+	}
+	ctx := context.Background()
+	if err := regs[0].Apply(ctx, Update{Members: members, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	want := "# shop\nupstream web {\n  server 10.0.0.1:8080; # web-1\n  server 10.0.0.2:8080; # web-2\n}\n"
+	if string(got) != want {
+		t.Fatalf("rendered:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Unchanged output is not rewritten or reloaded; a change in another group is ignored
+	if err := regs[0].Apply(ctx, Update{Members: members, Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	api := Member{ID: "d", Name: "api-2", Group: "api", IP: "10.0.1.2"}
+	if err := regs[0].Apply(ctx, Update{Members: append(members, api), Added: []Member{api}}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := os.ReadFile(reloads); strings.Count(string(n), "reload") != 1 {
+		t.Fatalf("reloads = %q, want one", n)
+	}
+
+	// A failed reload puts the previous file back
+	regs, _ = Open("shop", []config.RegistrarSpec{{Type: "file", Template: tmpl, Path: out, ReloadCommand: []string{"false"}}})
+	if err := regs[0].Apply(ctx, Update{Members: members[:1], Sync: true}); err == nil {
+		t.Fatal("Apply succeeded with a failing reload command")
+	}
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Fatalf("after failed reload:\n%s\nwant the previous file", got)
+	}
+}
+
+func TestWebhookPostsUpdates(t *testing.T) {
+	var got []WebhookPayload
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		got = append(got, p)
+		auth = r.Header.Get("Authorization")
+		if p.Event == "sync" {
+			http.Error(w, "catalog unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("CONSUL_TOKEN", "s3cret")
+
+	regs, err := Open("shop", []config.RegistrarSpec{{Name: "consul", Type: "webhook", URL: srv.URL, TokenEnv: "CONSUL_TOKEN", Port: 9000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := regs[0].Apply(ctx, Update{Members: members, Removed: []Member{{ID: "z", Group: "web", IP: "10.0.0.9"}}}); err != nil {
+		t.Fatal(err)
+	}
+	p := got[0]
+	if p.Fleet != "shop" || p.Event != "update" || len(p.Members) != 3 || p.Members[0].Name != "api-1" || p.Members[0].Port != 9000 ||
+		len(p.Added) != 0 || p.Added == nil || p.Removed[0].ID != "z" || auth != "Bearer s3cret" {
+		t.Fatalf("payload = %+v, auth %q", p, auth)
+	}
+	if err := regs[0].Apply(ctx, Update{Members: members, Sync: true}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("sync against a failing webhook: err = %v", err)
+	}
+}
+
+func TestOpenRejectsMisconfiguredRegistrars(t *testing.T) {
+	regs, err := Open("shop", []config.RegistrarSpec{
+		{Name: "hook", Type: "webhook", URL: "http://127.0.0.1:1/"},
+		{Name: "hook", Type: "webhook", URL: "http://127.0.0.1:2/"},
+		{Type: "file", Path: "/tmp/x"},
+		{Type: "webhook", URL: "ftp://x"},
+		{Type: "consul"},
+	})
+	if len(regs) != 1 || err == nil {
+		t.Fatalf("Open = %d registrars, %v", len(regs), err)
+	}
+	for _, want := range []string{`duplicate name "hook"`, "registrars[file-2]: type file needs template and path", "registrars[webhook-3]: type webhook needs an http(s) url", `unknown type "consul"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// internal/registrar/webhook.go
+package registrar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Webhook POSTs every update as JSON to a URL, e.g. a service that writes Consul
+// catalog entries or a proxy's admin API.
+type Webhook struct {
+	name     string
+	fleet    string
+	url      string
+	headers  map[string]string
+	tokenEnv string
+	http     *http.Client
+}
+
+// WebhookPayload is the body of webhook requests. Event is "update" (added and removed
+// instances) or "sync" (members is the complete list; sent by every reconcile).
+type WebhookPayload struct {
+	Fleet   string   `json:"fleet"`
+	Event   string   `json:"event"`
+	Members []Member `json:"members"`
+	Added   []Member `json:"added"`
+	Removed []Member `json:"removed"`
+}
+
+// NewWebhook returns a webhook registrar posting to url. When tokenEnv is set, the
+// variable's value is sent as a bearer token.
+func NewWebhook(name, fleet, url string, headers map[string]string, tokenEnv string, timeout time.Duration) (*Webhook, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("type webhook needs an http(s) url")
+	}
+	return &Webhook{name: name, fleet: fleet, url: url, headers: headers, tokenEnv: tokenEnv, http: &http.Client{Timeout: timeout}}, nil
+}
+
+func (r *Webhook) Name() string { return r.name }
+
+// Apply posts u; any status other than 2xx is an error.
+func (r *Webhook) Apply(ctx context.Context, u Update) error {
+	p := WebhookPayload{Fleet: r.fleet, Event: "update", Members: sorted(u.Members), Added: sorted(u.Added), Removed: sorted(u.Removed)}
+	if u.Sync {
+		p.Event = "sync"
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	if r.tokenEnv != "" {
+		if tok := os.Getenv(r.tokenEnv); tok != "" {
+			req.Header.Set("Authorization", "Bearer "+tok)
+		}
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", r.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post %s: %s: %s", r.url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sorted returns a sorted copy of ms, never nil so payloads carry [] rather than null.
+func sorted(ms []Member) []Member {
+	out := append(make([]Member, 0, len(ms)), ms...)
+	Sort(out)
+	return out
+}
@@ -0,0 +1,134 @@
+// internal/state/backend.go
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+// Backend names accepted by spec.state.backend.
+const (
+	BackendFile = "file"
+	BackendBolt = "bolt"
+	BackendS3   = "s3"
+)
+
+// ErrConflict is returned by Backend.Save when the stored document changed since it was loaded.
+var ErrConflict = errors.New("state was modified concurrently")
+
+// Backend stores the serialized state document. Writes are conditional on the version
+// returned by the last Load, so several processes or hosts can share one backend.
+type Backend interface {
+	// Load returns the stored document and its version; data is nil and version "" when
+	// nothing has been stored yet.
+	Load(ctx context.Context) (data []byte, version string, err error)
+	// Save stores data if the stored version still equals version ("" means it must not
+	// exist yet) and returns the new version. A mismatch yields an error wrapping ErrConflict.
+	Save(ctx context.Context, data []byte, version string) (string, error)
+	// SaveSnapshot stores a copy of data under name next to the state (e.g. a backup taken
+	// before a migration) and returns where it was written.
+	SaveSnapshot(ctx context.Context, name string, data []byte) (string, error)
+	// ListSnapshots returns the stored snapshots, oldest first.
+	ListSnapshots(ctx context.Context) ([]Snapshot, error)
+	// LoadSnapshot returns the contents of the snapshot called name.
+	LoadSnapshot(ctx context.Context, name string) ([]byte, error)
+	// DeleteSnapshot removes the snapshot called name.
+	DeleteSnapshot(ctx context.Context, name string) error
+	// String identifies the backend and location, e.g. "file:/var/lib/fleetctl/state.json".
+	String() string
+}
+
+// Prober is implemented by backends that can tell cheaply, without reading the document,
+// whether it changed. The Store serves reads from memory while the token is unchanged.
+type Prober interface {
+	// Probe returns a token that differs whenever the stored document has been written.
+	Probe(ctx context.Context) (string, error)
+}
+
+// Snapshot describes a stored copy of the state document.
+type Snapshot struct {
+	Name     string    `json:"name"`
+	Location string    `json:"location"`
+	Size     int64     `json:"size"`
+	Time     time.Time `json:"time"`
+}
+
+// snapshotTimeLayout is the UTC timestamp suffix of snapshot names.
+const snapshotTimeLayout = "20060102T150405Z"
+
+// snapshotTime parses the timestamp suffix of a snapshot name, falling back to fallback.
+func snapshotTime(name string, fallback time.Time) time.Time {
+	if i := strings.LastIndex(name, "-"); i >= 0 {
+		if t, err := time.Parse(snapshotTimeLayout, name[i+1:]); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
+
+// sortSnapshots orders snapshots oldest first.
+func sortSnapshots(snaps []Snapshot) {
+	sort.SliceStable(snaps, func(i, j int) bool {
+		if !snaps[i].Time.Equal(snaps[j].Time) {
+			return snaps[i].Time.Before(snaps[j].Time)
+		}
+		return snaps[i].Name < snaps[j].Name
+	})
+}
+
+// ErrSnapshotNotFound is returned by LoadSnapshot and DeleteSnapshot for unknown names.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// OpenBackend builds the backend selected by spec. defaultPath is the state file path used
+// by the file and bolt backends when spec.path is empty.
+func OpenBackend(spec config.StateSpec, defaultPath, fleetName string) (Backend, error) {
+	path := strings.TrimSpace(spec.Path)
+	switch strings.ToLower(strings.TrimSpace(spec.Backend)) {
+	case "", BackendFile:
+		if path == "" {
+			path = defaultPath
+		}
+		return NewFileBackend(path), nil
+	case BackendBolt:
+		if path == "" {
+			path = strings.TrimSuffix(defaultPath, ".json") + ".db"
+		}
+		return NewBoltBackend(path), nil
+	case BackendS3:
+		if spec.S3 == nil {
+			return nil, fmt.Errorf("state.backend s3 requires state.s3")
+		}
+		return newS3BackendFromSpec(*spec.S3, fleetName)
+	default:
+		return nil, fmt.Errorf("state.backend %q: expected file, bolt or s3", spec.Backend)
+	}
+}
+
+func newS3BackendFromSpec(spec config.S3StateSpec, fleetName string) (*S3Backend, error) {
+	if strings.TrimSpace(spec.Endpoint) == "" || strings.TrimSpace(spec.Bucket) == "" {
+		return nil, fmt.Errorf("state.s3: endpoint and bucket are required")
+	}
+	key := strings.TrimSpace(spec.Key)
+	if key == "" {
+		key = fmt.Sprintf("fleetctl/%s.state.json", fleetName)
+	}
+	akEnv, skEnv := spec.AccessKeyIDEnv, spec.SecretAccessKeyEnv
+	if akEnv == "" {
+		akEnv = "AWS_ACCESS_KEY_ID"
+	}
+	if skEnv == "" {
+		skEnv = "AWS_SECRET_ACCESS_KEY"
+	}
+	ak, sk := os.Getenv(akEnv), os.Getenv(skEnv)
+	if ak == "" || sk == "" {
+		return nil, fmt.Errorf("state.s3: credentials not found in %s / %s", akEnv, skEnv)
+	}
+	return NewS3Backend(spec.Endpoint, spec.Region, spec.Bucket, key, ak, sk), nil
+}
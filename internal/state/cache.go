@@ -0,0 +1,146 @@
+// internal/state/cache.go
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// cached returns the in-memory document when it is still current: always while a batch
+// holds unsaved writes, otherwise when the backend's Probe token is unchanged.
+func (s *Store) cached(ctx context.Context) *root {
+	if s.cache == nil {
+		return nil
+	}
+	if s.batch != nil && s.batch.dirty() {
+		return s.cache
+	}
+	if s.probe == "" {
+		return nil
+	}
+	if tok := s.probeToken(ctx); tok != s.probe {
+		return nil
+	}
+	return s.cache
+}
+
+// probeToken returns the backend's Probe token, or "" when it cannot tell (no cache use).
+func (s *Store) probeToken(ctx context.Context) string {
+	p, ok := s.backend.(Prober)
+	if !ok {
+		return ""
+	}
+	tok, err := p.Probe(ctx)
+	if err != nil {
+		return ""
+	}
+	return tok
+}
+
+// invalidate drops the in-memory document so the next read goes to the backend.
+func (s *Store) invalidate() {
+	s.cache, s.probe = nil, ""
+}
+
+// batch collects the writes made while Store.Batch runs. They are applied to the cached
+// document right away and saved together at the end; on a conflict they are re-applied
+// to the newer document, like a single update.
+type batch struct {
+	fns []func(r *root) error
+}
+
+func (b *batch) dirty() bool { return len(b.fns) > 0 }
+
+// apply runs fn on the current document and keeps it for the final save. Callers hold s.mu.
+func (b *batch) apply(s *Store, fn func(r *root) error) error {
+	r, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(r); errors.Is(err, errNoChange) {
+		return nil
+	} else if err != nil {
+		s.invalidate() // the next load replays the batch without fn
+		return err
+	}
+	b.fns = append(b.fns, fn)
+	return nil
+}
+
+// replay re-applies the batched writes to a freshly loaded document.
+func (b *batch) replay(r *root) error {
+	for _, fn := range b.fns {
+		if err := fn(r); err != nil && !errors.Is(err, errNoChange) {
+			return fmt.Errorf("re-applying batched state change: %w", err)
+		}
+	}
+	return nil
+}
+
+// Batch runs fn and saves every state change made meanwhile in one write. Reads inside fn
+// see the pending changes. Changes made by other goroutines while the batch is open join
+// it, so fn should only group quick bookkeeping (no cloud calls). Nested batches join the
+// outer one.
+func (s *Store) Batch(fn func() error) error {
+	s.mu.Lock()
+	if s.batch != nil {
+		s.mu.Unlock()
+		return fn()
+	}
+	s.batch = &batch{}
+	s.mu.Unlock()
+
+	ferr := fn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.batch
+	if !b.dirty() {
+		s.batch = nil
+		return ferr
+	}
+	var err error
+	for attempt := 0; attempt <= maxConflictRetries; attempt++ {
+		var r *root
+		if r, err = s.load(); err != nil { // cached, or reloaded with the batch replayed
+			break
+		}
+		if err = s.save(r); !errors.Is(err, ErrConflict) {
+			break
+		}
+	}
+	s.batch = nil
+	if err != nil {
+		s.invalidate()
+		return errors.Join(ferr, err)
+	}
+	return ferr
+}
+
+// statToken tracks a file's identity across probes. The token changes when the file is
+// replaced (new inode, as with tmp+rename) or modified in place (size or mtime).
+type statToken struct {
+	mu   sync.Mutex
+	last os.FileInfo
+	gen  int
+}
+
+func (t *statToken) probe(path string) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "absent", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil || !os.SameFile(t.last, info) {
+		t.gen++
+	}
+	t.last = info
+	return fmt.Sprintf("%d:%d:%d", t.gen, info.Size(), info.ModTime().UnixNano()), nil
+}
@@ -0,0 +1,94 @@
+// internal/state/cache_test.go
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// countingBackend counts the document reads and writes that reach the file backend.
+type countingBackend struct {
+	*FileBackend
+	loads, saves atomic.Int32
+}
+
+func (c *countingBackend) Load(ctx context.Context) ([]byte, string, error) {
+	c.loads.Add(1)
+	return c.FileBackend.Load(ctx)
+}
+
+func (c *countingBackend) Save(ctx context.Context, data []byte, version string) (string, error) {
+	c.saves.Add(1)
+	return c.FileBackend.Save(ctx, data, version)
+}
+
+func TestStoreCachesAndDetectsExternalWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cb := &countingBackend{FileBackend: NewFileBackend(path)}
+	st := NewWithBackend(cb)
+	if err := st.AddActiveRecord("dev", "web", "ocid-a", "dev-web-a"); err != nil {
+		t.Fatal(err)
+	}
+	st.CountActive("dev") // reloads once after our own write
+	loads := cb.loads.Load()
+	for i := 0; i < 5; i++ {
+		if n, err := st.CountActive("dev"); err != nil || n != 1 {
+			t.Fatalf("CountActive = %d, %v", n, err)
+		}
+	}
+	if got := cb.loads.Load(); got != loads {
+		t.Fatalf("unchanged file was read %d more times", got-loads)
+	}
+
+	// Another process (here: another Store) writes the file.
+	if err := New(path).AddActiveRecord("dev", "web", "ocid-b", "dev-web-b"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := st.CountActive("dev"); err != nil || n != 2 {
+		t.Fatalf("CountActive after external write = %d, %v; want 2", n, err)
+	}
+
+	// A hand edit in place (same inode) is noticed as well.
+	data, _ := os.ReadFile(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(f, `{"schemaVersion": %d, "fleets": {}}`, SchemaVersion)
+	f.Close()
+	if n, _ := st.CountActive("dev"); n != 0 {
+		t.Fatalf("CountActive after in-place edit = %d, want 0 (file was %d bytes)", n, len(data))
+	}
+}
+
+func TestBatchWritesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cb := &countingBackend{FileBackend: NewFileBackend(path)}
+	st := NewWithBackend(cb)
+	err := st.Batch(func() error {
+		for _, id := range []string{"a", "b", "c"} {
+			if err := st.AddActiveRecord("dev", "web", id, "dev-web-"+id); err != nil {
+				return err
+			}
+		}
+		if n, _ := st.CountActive("dev"); n != 3 {
+			t.Errorf("reads inside the batch see %d records, want 3", n)
+		}
+		// A concurrent writer lands before the batch is saved.
+		return New(path).AddActiveRecord("dev", "web", "x", "dev-web-x")
+	})
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if got := cb.saves.Load(); got < 1 || got > 2 {
+		t.Fatalf("batch saved %d times, want one write (plus one retry after the conflict)", got)
+	}
+	recs, err := New(path).ActiveRecords("dev")
+	if err != nil || ids(recs) != "a,b,c,x" {
+		t.Fatalf("stored records = %s, %v; want the batch and the concurrent write", ids(recs), err)
+	}
+}
@@ -0,0 +1,73 @@
+// internal/state/desired.go
+package state
+
+import (
+	"time"
+)
+
+// DesiredOverride is an operator-set fleet size that takes precedence over the config
+// baseline (sum of spec.instances[].count) until it expires.
+type DesiredOverride struct {
+	Desired   int       `json:"desired"`
+	Source    string    `json:"source,omitempty"` // "cli" or "api"
+	SetAt     time.Time `json:"setAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the override no longer applies at now.
+func (o DesiredOverride) Expired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !now.Before(o.ExpiresAt)
+}
+
+// SetDesiredOverride stores o as the fleet's desired override, replacing any previous one.
+func (s *Store) SetDesiredOverride(fleetName string, o DesiredOverride) error {
+	if o.SetAt.IsZero() {
+		o.SetAt = time.Now()
+	}
+
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		fs.Desired = &o
+		return nil
+	})
+}
+
+// DesiredOverride returns the fleet's desired override, expired or not; ok is false when none is set.
+func (s *Store) DesiredOverride(fleetName string) (DesiredOverride, bool, error) {
+	var d *DesiredOverride
+	if err := s.viewFleet(fleetName, func(fs FleetState) error { d = fs.Desired; return nil }); err != nil {
+		return DesiredOverride{}, false, err
+	}
+	if d == nil {
+		return DesiredOverride{}, false, nil
+	}
+	return *d, true, nil
+}
+
+// ClearDesiredOverride removes the fleet's desired override; the config baseline applies again.
+func (s *Store) ClearDesiredOverride(fleetName string) error {
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if fs.Desired == nil {
+			return errNoChange
+		}
+		fs.Desired = nil
+		return nil
+	})
+}
+
+// ClearDesiredOverrideIf removes the fleet's desired override only while it is still o
+// (same size, source, SetAt and ExpiresAt), so an override set by another process after o
+// was read is kept. It reports whether o was cleared.
+func (s *Store) ClearDesiredOverrideIf(fleetName string, o DesiredOverride) (bool, error) {
+	cleared := false
+	err := s.updateFleet(fleetName, func(fs *FleetState) error {
+		cleared = false
+		d := fs.Desired
+		if d == nil || d.Desired != o.Desired || d.Source != o.Source || !d.SetAt.Equal(o.SetAt) || !d.ExpiresAt.Equal(o.ExpiresAt) {
+			return errNoChange
+		}
+		fs.Desired = nil
+		cleared = true
+		return nil
+	})
+	return cleared, err
+}
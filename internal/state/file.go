@@ -0,0 +1,183 @@
+// internal/state/file.go
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofrs/flock"
+)
+
+// FileBackend keeps the state document in a local JSON file. The version is a hash of the
+// file contents; check-and-write runs under a flock on <path>.lock so processes on one
+// host do not overwrite each other's changes.
+type FileBackend struct {
+	path string
+	stat statToken
+}
+
+// NewFileBackend returns a backend for the JSON file at path.
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+func (b *FileBackend) String() string { return "file:" + b.path }
+
+// Path returns the state file path.
+func (b *FileBackend) Path() string { return b.path }
+
+func contentVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// read returns the file contents and version; a missing file is version "".
+func (b *FileBackend) read() ([]byte, string, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("reading state file %q: %w", b.path, err)
+	}
+	return data, contentVersion(data), nil
+}
+
+// Load reads the state file.
+func (b *FileBackend) Load(ctx context.Context) ([]byte, string, error) {
+	return b.read()
+}
+
+// Save atomically replaces the state file if it still has version.
+func (b *FileBackend) Save(ctx context.Context, data []byte, version string) (string, error) {
+	if dir := filepath.Dir(b.path); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+	}
+	fl := flock.New(b.path + ".lock")
+	if err := fl.Lock(); err != nil {
+		return "", fmt.Errorf("lock state file %q: %w", b.path, err)
+	}
+	defer func() { _ = fl.Unlock() }()
+
+	current, cur, err := b.read()
+	if err != nil {
+		return "", err
+	}
+	if cur != version {
+		return "", fmt.Errorf("%s: %w", b.path, ErrConflict)
+	}
+	if current != nil && bytes.Equal(current, data) {
+		return cur, nil
+	}
+	if err := writeFileSync(b.path, data); err != nil {
+		return "", fmt.Errorf("writing state file: %w", err)
+	}
+	return contentVersion(data), nil
+}
+
+// Probe returns a token built from the state file's identity, size and mtime; it changes
+// on every Save (a new file is renamed into place) and on edits made by other tools.
+func (b *FileBackend) Probe(ctx context.Context) (string, error) {
+	return b.stat.probe(b.path)
+}
+
+// writeFileSync replaces path with data durably: the data goes to <path>.tmp, which is
+// fsynced before being renamed over path; the directory is then synced (best effort) so
+// the rename survives a crash.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// snapshotDir holds snapshots of the state file: <path>.snapshots/.
+func (b *FileBackend) snapshotDir() string { return b.path + ".snapshots" }
+
+// SaveSnapshot writes data to <path>.snapshots/<name>.json.
+func (b *FileBackend) SaveSnapshot(ctx context.Context, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(b.snapshotDir(), 0o755); err != nil {
+		return "", fmt.Errorf("create snapshot dir: %w", err)
+	}
+	p := filepath.Join(b.snapshotDir(), name+".json")
+	if err := writeFileSync(p, data); err != nil {
+		return "", fmt.Errorf("write snapshot %q: %w", p, err)
+	}
+	return p, nil
+}
+
+// ListSnapshots lists <path>.snapshots/*.json.
+func (b *FileBackend) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	entries, err := os.ReadDir(b.snapshotDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	var out []Snapshot
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, Snapshot{
+			Name:     name,
+			Location: filepath.Join(b.snapshotDir(), e.Name()),
+			Size:     info.Size(),
+			Time:     snapshotTime(name, info.ModTime()),
+		})
+	}
+	sortSnapshots(out)
+	return out, nil
+}
+
+// LoadSnapshot reads <path>.snapshots/<name>.json.
+func (b *FileBackend) LoadSnapshot(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(b.snapshotDir(), name+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", name, ErrSnapshotNotFound)
+	}
+	return data, err
+}
+
+// DeleteSnapshot removes <path>.snapshots/<name>.json.
+func (b *FileBackend) DeleteSnapshot(ctx context.Context, name string) error {
+	err := os.Remove(filepath.Join(b.snapshotDir(), name+".json"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", name, ErrSnapshotNotFound)
+	}
+	return err
+}
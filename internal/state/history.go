@@ -0,0 +1,114 @@
+// internal/state/history.go
+package state
+
+import (
+	"time"
+)
+
+// maxHistory bounds the per-fleet history kept in the state file.
+const maxHistory = 500
+
+// HistoryEntry records an operation that changed the fleet.
+type HistoryEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`          // e.g. "scale-up", "scale-down", "rolling-restart"
+	Source    string    `json:"source,omitempty"`   // "cli", "api" or "control-loop"
+	Disrupted int       `json:"disrupted"`          // instances terminated or replaced (counts toward the disruption budget)
+	Detail    string    `json:"detail,omitempty"`   // human-readable summary
+	Override  string    `json:"override,omitempty"` // reason given with --override-window, if the window was bypassed
+	Error     string    `json:"error,omitempty"`    // set when the operation failed part-way
+}
+
+// QueuedOp is a disruptive operation deferred until the next maintenance window.
+type QueuedOp struct {
+	Operation   string    `json:"operation"`         // "scale" or "rolling-restart"
+	Desired     int       `json:"desired,omitempty"` // target for "scale"
+	Source      string    `json:"source,omitempty"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+// AppendHistory appends e to the fleet history, keeping the newest maxHistory entries.
+func (s *Store) AppendHistory(fleetName string, e HistoryEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		fs.History = append(fs.History, e)
+		if len(fs.History) > maxHistory {
+			fs.History = append([]HistoryEntry(nil), fs.History[len(fs.History)-maxHistory:]...)
+		}
+		return nil
+	})
+}
+
+// History returns up to n of the newest history entries, oldest first (n <= 0 returns all).
+func (s *Store) History(fleetName string, n int) ([]HistoryEntry, error) {
+	var out []HistoryEntry
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		h := fs.History
+		if n > 0 && len(h) > n {
+			h = h[len(h)-n:]
+		}
+		out = append([]HistoryEntry(nil), h...)
+		return nil
+	})
+	return out, err
+}
+
+// DisruptionsSince sums the instances disrupted by operations recorded at or after t.
+func (s *Store) DisruptionsSince(fleetName string, t time.Time) (int, error) {
+	n := 0
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		for _, e := range fs.History {
+			if !e.Time.Before(t) {
+				n += e.Disrupted
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// QueueOp queues op for the next maintenance window. A queued scale replaces any earlier
+// queued scale (only the latest target matters); a rolling restart is queued at most once.
+func (s *Store) QueueOp(fleetName string, op QueuedOp) error {
+	if op.RequestedAt.IsZero() {
+		op.RequestedAt = time.Now()
+	}
+
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		kept := make([]QueuedOp, 0, len(fs.Queued)+1)
+		for _, q := range fs.Queued {
+			if q.Operation != op.Operation {
+				kept = append(kept, q)
+			}
+		}
+		fs.Queued = append(kept, op)
+		return nil
+	})
+}
+
+// Queued returns the operations waiting for a maintenance window.
+func (s *Store) Queued(fleetName string) ([]QueuedOp, error) {
+	var out []QueuedOp
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		out = append([]QueuedOp(nil), fs.Queued...)
+		return nil
+	})
+	return out, err
+}
+
+// TakeQueued removes and returns all queued operations.
+func (s *Store) TakeQueued(fleetName string) ([]QueuedOp, error) {
+	var out []QueuedOp
+	err := s.updateFleet(fleetName, func(fs *FleetState) error {
+		out = fs.Queued
+		if len(out) == 0 {
+			return errNoChange
+		}
+		fs.Queued = nil
+		return nil
+	})
+	return out, err
+}
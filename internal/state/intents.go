@@ -0,0 +1,65 @@
+// internal/state/intents.go
+package state
+
+import "time"
+
+// Launch intents are written ahead of each LaunchInstance request as Launching records
+// keyed by the request's retry token. A successful launch replaces the intent with the
+// active record (AddInstanceRecord); a request that created nothing removes it
+// (AbandonLaunch). Intents left by a crash are resolved against OCI on the next start.
+
+// launchIntentIndex returns the index of the Launching record with token, or -1.
+func launchIntentIndex(recs []InstanceRecord, token string) int {
+	if token == "" {
+		return -1
+	}
+	for i, r := range recs {
+		if r.Status == StatusLaunching && r.LaunchToken == token {
+			return i
+		}
+	}
+	return -1
+}
+
+// BeginLaunch records a launch intent before the instance is requested. rec needs a
+// LaunchToken; its ID is usually still empty.
+func (s *Store) BeginLaunch(fleetName string, rec InstanceRecord) error {
+	now := time.Now()
+	rec.Status = StatusLaunching
+	rec.CreatedAt, rec.UpdatedAt = now, now
+
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if launchIntentIndex(fs.Instances, rec.LaunchToken) >= 0 {
+			return errNoChange
+		}
+		fs.Instances = append(fs.Instances, rec)
+		return nil
+	})
+}
+
+// AbandonLaunch removes the launch intent with token (the request created no instance,
+// or recovery found none).
+func (s *Store) AbandonLaunch(fleetName, token string) error {
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		i := launchIntentIndex(fs.Instances, token)
+		if i < 0 {
+			return errNoChange
+		}
+		fs.Instances = append(fs.Instances[:i:i], fs.Instances[i+1:]...)
+		return nil
+	})
+}
+
+// PendingLaunches returns the launch intents not yet resolved, oldest first.
+func (s *Store) PendingLaunches(fleetName string) ([]InstanceRecord, error) {
+	var out []InstanceRecord
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		for _, r := range fs.Instances {
+			if r.Status == StatusLaunching {
+				out = append(out, r)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
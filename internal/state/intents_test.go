@@ -0,0 +1,56 @@
+// internal/state/intents_test.go
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+func TestLaunchIntents(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+	for _, tok := range []string{"t1", "t2"} {
+		if err := st.BeginLaunch("dev", InstanceRecord{Group: "web", Name: "dev-web-" + tok, LaunchToken: tok}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := st.CountActive("dev"); n != 0 {
+		t.Fatalf("intents counted as active: %d", n)
+	}
+	pending, err := st.PendingLaunches("dev")
+	if err != nil || len(pending) != 2 || pending[0].Status != StatusLaunching {
+		t.Fatalf("PendingLaunches = %+v, %v", pending, err)
+	}
+
+	// The launched instance replaces its intent; the other request created nothing.
+	if err := st.AddInstanceRecord("dev", InstanceRecord{ID: "ocid-1", Group: "web", LaunchToken: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AbandonLaunch("dev", "t2"); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ = st.PendingLaunches("dev")
+	recs, _ := st.ActiveRecords("dev")
+	if len(pending) != 0 || ids(recs) != "ocid-1" {
+		t.Fatalf("after settling: pending=%+v active=%s", pending, ids(recs))
+	}
+	if sum, _ := st.Summary("dev"); sum == "" {
+		t.Fatal("empty summary")
+	}
+}
+
+func TestCompactKeepsLaunchIntents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	zero := 0
+	old := now.Add(-30 * 24 * time.Hour)
+	recs := []InstanceRecord{
+		{ID: "a", Status: StatusTerminated, UpdatedAt: old},
+		{LaunchToken: "t", Status: StatusLaunching, UpdatedAt: old},
+	}
+	got := compactRecords(recs, config.StateRetention{TerminatedMaxCount: &zero}, now)
+	if len(got) != 1 || got[0].LaunchToken != "t" {
+		t.Fatalf("compactRecords = %+v; want only the intent", got)
+	}
+}
@@ -0,0 +1,243 @@
+// internal/state/migrate.go
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// SchemaVersion is the state document version written by this build. Documents without
+// a schemaVersion field are version 1 (the original format).
+const SchemaVersion = 4
+
+// MigrationStep describes one applied (or, with --check, pending) schema migration.
+type MigrationStep struct {
+	From        int      `json:"from"`
+	To          int      `json:"to"`
+	Description string   `json:"description"`
+	Changes     []string `json:"changes,omitempty"`
+}
+
+// migration upgrades a raw state document from version to-1 to version to. Migrations
+// work on the generic JSON form so they keep working as the Go types evolve.
+type migration struct {
+	to          int
+	description string
+	apply       func(doc map[string]any) []string
+}
+
+// migrations must be ordered by to and cover every version up to SchemaVersion.
+var migrations = []migration{
+	{to: 2, description: "add schemaVersion; fill missing fleetName; mark records without capacity as on-demand", apply: migrateV2},
+	{to: 3, description: "instance records carry privateIp, publicIp, imageId and configRevision", apply: migrateV3},
+	{to: 4, description: "launch intents: Launching records keyed by launchToken", apply: migrateV4},
+}
+
+// NewerSchemaError is returned when a document was written by a newer fleetctl.
+type NewerSchemaError struct {
+	Version int
+}
+
+func (e *NewerSchemaError) Error() string {
+	return fmt.Sprintf("state schema version %d is newer than this fleetctl supports (%d); upgrade fleetctl", e.Version, SchemaVersion)
+}
+
+// docVersion returns the schemaVersion of doc (1 when absent).
+func docVersion(doc map[string]any) int {
+	if v, ok := doc["schemaVersion"].(float64); ok && v >= 1 {
+		return int(v)
+	}
+	return 1
+}
+
+// migrateDocument upgrades data to SchemaVersion. It returns the original version, the
+// steps applied and the migrated document; steps is empty when data is already current.
+func migrateDocument(data []byte) (from int, steps []MigrationStep, out []byte, err error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, nil, nil, fmt.Errorf("parsing state: %w", err)
+	}
+	from = docVersion(doc)
+	if from > SchemaVersion {
+		return from, nil, nil, &NewerSchemaError{Version: from}
+	}
+	if from == SchemaVersion {
+		return from, nil, data, nil
+	}
+	for _, m := range migrations {
+		if m.to <= from {
+			continue
+		}
+		changes := m.apply(doc)
+		doc["schemaVersion"] = m.to
+		steps = append(steps, MigrationStep{From: m.to - 1, To: m.to, Description: m.description, Changes: changes})
+	}
+	out, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return from, nil, nil, fmt.Errorf("encoding migrated state: %w", err)
+	}
+	return from, steps, out, nil
+}
+
+// Migration is the outcome of bringing a stored document to SchemaVersion.
+type Migration struct {
+	From   int             `json:"from"`
+	To     int             `json:"to"`
+	Steps  []MigrationStep `json:"steps"`
+	Backup string          `json:"backup,omitempty"` // where the original was saved; empty for --check or no-op
+}
+
+// migrate upgrades data in the backend when it is older than SchemaVersion: the original
+// is saved as a snapshot first, then the migrated document is written conditionally.
+func (s *Store) migrate(ctx context.Context, data []byte, version string) ([]byte, string, Migration, error) {
+	from, steps, out, err := migrateDocument(data)
+	m := Migration{From: from, To: SchemaVersion, Steps: steps}
+	if err != nil || len(steps) == 0 {
+		return data, version, m, err
+	}
+	backup, err := s.backend.SaveSnapshot(ctx, snapshotName(fmt.Sprintf("%s%d", snapshotMigrate, from), time.Now()), data)
+	if err != nil {
+		return nil, "", m, fmt.Errorf("backup before migrating state (%s): %w", s.backend, err)
+	}
+	m.Backup = backup
+	newVersion, err := s.backend.Save(ctx, out, version)
+	if err != nil {
+		return nil, "", m, fmt.Errorf("saving migrated state (%s): %w", s.backend, err)
+	}
+	log.Printf("state: migrated %s from schema v%d to v%d (backup: %s)", s.backend, from, SchemaVersion, backup)
+	return out, newVersion, m, nil
+}
+
+// loadCurrent reads the stored document and brings it to SchemaVersion, starting over
+// when another process wrote while the migration was being saved.
+func (s *Store) loadCurrent(ctx context.Context) ([]byte, string, error) {
+	data, version, _, err := s.loadMigrated(ctx)
+	return data, version, err
+}
+
+func (s *Store) loadMigrated(ctx context.Context) ([]byte, string, Migration, error) {
+	for attempt := 0; ; attempt++ {
+		data, version, err := s.backend.Load(ctx)
+		if err != nil {
+			return nil, "", Migration{}, fmt.Errorf("reading state (%s): %w", s.backend, err)
+		}
+		if len(data) == 0 {
+			return nil, version, Migration{From: SchemaVersion, To: SchemaVersion}, nil
+		}
+		data, version, m, err := s.migrate(ctx, data, version)
+		if errors.Is(err, ErrConflict) && attempt < maxConflictRetries {
+			continue
+		}
+		return data, version, m, err
+	}
+}
+
+// MigrationPlan reports the stored schema version and the migrations the next load would
+// apply, without writing anything (fleetctl state migrate --check).
+func (s *Store) MigrationPlan() (Migration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, _, err := s.backend.Load(context.Background())
+	if err != nil {
+		return Migration{}, fmt.Errorf("reading state (%s): %w", s.backend, err)
+	}
+	if len(data) == 0 {
+		return Migration{From: SchemaVersion, To: SchemaVersion}, nil
+	}
+	from, steps, _, err := migrateDocument(data)
+	return Migration{From: from, To: SchemaVersion, Steps: steps}, err
+}
+
+// Migrate applies pending migrations now, backing up the original first.
+func (s *Store) Migrate() (Migration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, version, m, err := s.loadMigrated(context.Background())
+	if err == nil {
+		s.version = version
+	}
+	s.invalidate()
+	return m, err
+}
+
+// snapshotName names a snapshot taken for reason at now: <reason>-<UTC timestamp>.
+func snapshotName(reason string, now time.Time) string {
+	return reason + "-" + now.UTC().Format(snapshotTimeLayout)
+}
+
+// fleetsOf returns the fleets object of doc with its keys in order.
+func fleetsOf(doc map[string]any) (map[string]any, []string) {
+	fleets, _ := doc["fleets"].(map[string]any)
+	names := make([]string, 0, len(fleets))
+	for name := range fleets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fleets, names
+}
+
+func migrateV2(doc map[string]any) []string {
+	var changes []string
+	fleets, names := fleetsOf(doc)
+	for _, name := range names {
+		fs, ok := fleets[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		if fn, _ := fs["fleetName"].(string); fn == "" {
+			fs["fleetName"] = name
+			changes = append(changes, fmt.Sprintf("fleet %s: set fleetName", name))
+		}
+		insts, _ := fs["instances"].([]any)
+		n := 0
+		for _, it := range insts {
+			rec, ok := it.(map[string]any)
+			if !ok {
+				continue
+			}
+			if c, _ := rec["capacity"].(string); c == "" {
+				rec["capacity"] = "on-demand"
+				n++
+			}
+		}
+		if n > 0 {
+			changes = append(changes, fmt.Sprintf("fleet %s: %d instance record(s) marked capacity on-demand", name, n))
+		}
+	}
+	return changes
+}
+
+// migrateV3 adds no data: the new record fields are optional and unknown for existing
+// records until the next launch or sync-state fills them in. The version bump keeps older
+// fleetctl builds, which would drop the fields on write, from rewriting the document.
+func migrateV3(doc map[string]any) []string {
+	var changes []string
+	fleets, names := fleetsOf(doc)
+	for _, name := range names {
+		fs, _ := fleets[name].(map[string]any)
+		insts, _ := fs["instances"].([]any)
+		n := 0
+		for _, it := range insts {
+			if rec, ok := it.(map[string]any); ok && rec["status"] == StatusActive && rec["privateIp"] == nil {
+				n++
+			}
+		}
+		if n > 0 {
+			changes = append(changes, fmt.Sprintf("fleet %s: %d active record(s) without addresses; filled on next sync-state", name, n))
+		}
+	}
+	return changes
+}
+
+// migrateV4 adds no data. Older builds neither know Launching records nor keep
+// launchToken, so the bump stops them from rewriting a document holding intents.
+func migrateV4(doc map[string]any) []string {
+	return nil
+}
@@ -0,0 +1,79 @@
+// internal/state/migrate_test.go
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const v1Doc = `{
+  "fleets": {
+    "dev": {
+      "instances": [
+        {"id": "ocid-a", "group": "web", "name": "dev-web-a", "status": "Active"},
+        {"id": "ocid-b", "group": "web", "name": "dev-web-b", "status": "Active", "capacity": "preemptible"}
+      ]
+    }
+  }
+}`
+
+func TestLoadMigratesWithBackupFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(v1Doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st := New(path)
+
+	plan, err := st.MigrationPlan()
+	if err != nil || plan.From != 1 || len(plan.Steps) != SchemaVersion-1 || len(plan.Steps[0].Changes) != 2 {
+		t.Fatalf("MigrationPlan = %+v, %v", plan, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != v1Doc {
+		t.Fatalf("MigrationPlan must not write")
+	}
+
+	recs, err := st.ActiveRecordsFIFO("dev", 10)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Capacity != "on-demand" || recs[1].Capacity != "preemptible" {
+		t.Fatalf("migrated records = %+v", recs)
+	}
+
+	backups, _ := filepath.Glob(path + ".snapshots/migrate-v1-*.json")
+	if len(backups) != 1 {
+		t.Fatalf("expected one migrate backup, got %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != v1Doc {
+		t.Fatalf("backup must hold the original document")
+	}
+	var doc struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Fleets        map[string]struct {
+			FleetName string `json:"fleetName"`
+		} `json:"fleets"`
+	}
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &doc); err != nil || doc.SchemaVersion != SchemaVersion || doc.Fleets["dev"].FleetName != "dev" {
+		t.Fatalf("migrated file = %s", data)
+	}
+
+	if m, err := st.Migrate(); err != nil || len(m.Steps) != 0 {
+		t.Fatalf("second Migrate = %+v, %v; want no-op", m, err)
+	}
+}
+
+func TestLoadRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"schemaVersion": 99, "fleets": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := New(path).CountActive("dev")
+	var ne *NewerSchemaError
+	if !errors.As(err, &ne) || ne.Version != 99 {
+		t.Fatalf("expected NewerSchemaError, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+// internal/state/s3.go
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Backend keeps the state document as one object in S3-compatible storage. The object
+// ETag is the version: writes use If-Match (or If-None-Match: * for the first write), so
+// concurrent writers on different hosts get 412 Precondition Failed instead of losing data.
+// Requests are signed with AWS Signature Version 4 and use path-style addressing.
+type S3Backend struct {
+	endpoint  string
+	region    string
+	bucket    string
+	key       string
+	accessKey string
+	secretKey string
+	http      *http.Client
+	now       func() time.Time
+}
+
+// NewS3Backend returns a backend for bucket/key at endpoint (e.g. http://localhost:9000).
+func NewS3Backend(endpoint, region, bucket, key, accessKey, secretKey string) *S3Backend {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Backend{
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		region:    region,
+		bucket:    bucket,
+		key:       strings.TrimLeft(key, "/"),
+		accessKey: accessKey,
+		secretKey: secretKey,
+		http:      &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}
+}
+
+func (b *S3Backend) String() string {
+	return fmt.Sprintf("s3://%s/%s (%s)", b.bucket, b.key, b.endpoint)
+}
+
+func (b *S3Backend) objectURL(key string) string {
+	return b.endpoint + "/" + s3Escape(b.bucket, false) + "/" + s3Escape(key, false)
+}
+
+// snapshotKey places snapshots next to the state object: <key>.snapshots/<name>.json.
+func (b *S3Backend) snapshotKey(name string) string {
+	return b.key + ".snapshots/" + name + ".json"
+}
+
+func (b *S3Backend) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, []byte, error) {
+	return b.doURL(ctx, method, b.objectURL(key), body, header)
+}
+
+func (b *S3Backend) doURL(ctx context.Context, method, rawURL string, body []byte, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	signV4(req, body, b.accessKey, b.secretKey, b.region, "s3", b.now().UTC())
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: %w", method, b, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: read body: %w", method, b, err)
+	}
+	return resp, data, nil
+}
+
+// Load fetches the state object; a missing object is version "".
+func (b *S3Backend) Load(ctx context.Context) ([]byte, string, error) {
+	resp, data, err := b.do(ctx, http.MethodGet, b.key, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return data, resp.Header.Get("ETag"), nil
+	case http.StatusNotFound:
+		return nil, "", nil
+	default:
+		return nil, "", fmt.Errorf("GET %s: %s: %s", b, resp.Status, s3ErrorCode(data))
+	}
+}
+
+// Probe returns the state object's ETag from a HEAD request, so an unchanged document is
+// not downloaded again.
+func (b *S3Backend) Probe(ctx context.Context) (string, error) {
+	resp, _, err := b.do(ctx, http.MethodHead, b.key, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusNotFound:
+		return "absent", nil
+	default:
+		return "", fmt.Errorf("HEAD %s: %s", b, resp.Status)
+	}
+}
+
+// Save puts the state object conditionally on its ETag.
+func (b *S3Backend) Save(ctx context.Context, data []byte, version string) (string, error) {
+	h := http.Header{}
+	if version == "" {
+		h.Set("If-None-Match", "*")
+	} else {
+		h.Set("If-Match", version)
+	}
+	resp, body, err := b.do(ctx, http.MethodPut, b.key, data, h)
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		// 409 ConditionalRequestConflict: another conditional write is in flight
+		return "", fmt.Errorf("PUT %s: %s: %w", b, resp.Status, ErrConflict)
+	default:
+		return "", fmt.Errorf("PUT %s: %s: %s", b, resp.Status, s3ErrorCode(body))
+	}
+}
+
+// SaveSnapshot puts data as <key>.snapshots/<name>.json.
+func (b *S3Backend) SaveSnapshot(ctx context.Context, name string, data []byte) (string, error) {
+	key := b.snapshotKey(name)
+	resp, body, err := b.do(ctx, http.MethodPut, key, data, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("PUT snapshot %s: %s: %s", key, resp.Status, s3ErrorCode(body))
+	}
+	return fmt.Sprintf("s3://%s/%s", b.bucket, key), nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response used for snapshots.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListSnapshots lists objects under <key>.snapshots/ with ListObjectsV2.
+func (b *S3Backend) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	prefix := b.key + ".snapshots/"
+	var out []Snapshot
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u := b.endpoint + "/" + s3Escape(b.bucket, false) + "?" + q.Encode()
+		resp, body, err := b.doURL(ctx, http.MethodGet, u, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("list snapshots %s: %s: %s", b, resp.Status, s3ErrorCode(body))
+		}
+		var res listBucketResult
+		if err := xml.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("list snapshots %s: %w", b, err)
+		}
+		for _, c := range res.Contents {
+			name, ok := strings.CutSuffix(strings.TrimPrefix(c.Key, prefix), ".json")
+			if !ok || name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			out = append(out, Snapshot{
+				Name:     name,
+				Location: fmt.Sprintf("s3://%s/%s", b.bucket, c.Key),
+				Size:     c.Size,
+				Time:     snapshotTime(name, c.LastModified),
+			})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		token = res.NextContinuationToken
+	}
+	sortSnapshots(out)
+	return out, nil
+}
+
+// LoadSnapshot fetches <key>.snapshots/<name>.json.
+func (b *S3Backend) LoadSnapshot(ctx context.Context, name string) ([]byte, error) {
+	key := b.snapshotKey(name)
+	resp, data, err := b.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return data, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", name, ErrSnapshotNotFound)
+	default:
+		return nil, fmt.Errorf("GET snapshot %s: %s: %s", key, resp.Status, s3ErrorCode(data))
+	}
+}
+
+// DeleteSnapshot deletes <key>.snapshots/<name>.json.
+func (b *S3Backend) DeleteSnapshot(ctx context.Context, name string) error {
+	key := b.snapshotKey(name)
+	resp, body, err := b.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("DELETE snapshot %s: %s: %s", key, resp.Status, s3ErrorCode(body))
+	}
+	return nil
+}
+
+// s3ErrorCode extracts <Code> from an S3 XML error body for error messages.
+func s3ErrorCode(body []byte) string {
+	s := string(body)
+	if i := strings.Index(s, "<Code>"); i >= 0 {
+		if j := strings.Index(s[i:], "</Code>"); j >= 0 {
+			return s[i+len("<Code>") : i+j]
+		}
+	}
+	if len(s) > 200 {
+		s = s[:200]
+	}
+	return strings.TrimSpace(s)
+}
+
+// s3Escape URI-encodes s as SigV4 requires: everything but unreserved characters,
+// keeping "/" unless encodeSlash is set.
+func s3Escape(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// signV4 adds AWS Signature Version 4 headers to req. Every header already set on the
+// request is signed, together with host, x-amz-date and x-amz-content-sha256.
+func signV4(req *http.Request, payload []byte, accessKey, secretKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonURI := req.URL.EscapedPath()
+	if u, err := url.PathUnescape(canonURI); err == nil {
+		canonURI = s3Escape(u, false)
+	}
+	if canonURI == "" {
+		canonURI = "/"
+	}
+	canonQuery := canonicalQuery(req.URL.Query())
+
+	canonReq := strings.Join([]string{req.Method, canonURI, canonQuery, canonHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonReq))
+
+	k := hmacSHA256([]byte("AWS4"+secretKey), day)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, sig))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
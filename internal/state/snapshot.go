@@ -0,0 +1,233 @@
+// internal/state/snapshot.go
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+// Snapshot name prefixes. Automatic snapshots ("pre-...") are pruned to the retention
+// limit; manual backups and the originals saved before a schema migration ("migrate-v<n>")
+// are kept until deleted by hand.
+const (
+	snapshotAutoPrefix = "pre-"
+	snapshotBackup     = "backup"
+	snapshotMigrate    = "migrate-v"
+)
+
+// SetRetention sets how long the fleet's terminated records are kept. Automatic snapshots
+// cover the whole store and are kept up to the largest limit of its fleets.
+func (s *Store) SetRetention(fleetName string, r config.StateRetention) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retention == nil {
+		s.retention = map[string]config.StateRetention{}
+	}
+	s.retention[fleetName] = r
+}
+
+// snapshotsKept is the automatic snapshot limit: the largest of the fleets' settings, the
+// default when none is set. Callers hold s.mu.
+func (s *Store) snapshotsKept() int {
+	if len(s.retention) == 0 {
+		return config.StateRetention{}.SnapshotsKept()
+	}
+	kept := 0
+	for _, r := range s.retention {
+		kept = max(kept, r.SnapshotsKept())
+	}
+	return kept
+}
+
+// compactRecords drops terminated and preempted records older than the retention age and
+// then the oldest of them beyond the retention count. Active records and launch intents
+// are always kept.
+func compactRecords(recs []InstanceRecord, r config.StateRetention, now time.Time) []InstanceRecord {
+	maxAge, maxCount := r.MaxAge(), r.MaxCount()
+	drop := map[int]bool{}
+	var ended []int
+	for i, rec := range recs {
+		switch {
+		case rec.Status == StatusActive, rec.Status == StatusLaunching:
+		case maxAge > 0 && now.Sub(rec.UpdatedAt) > maxAge:
+			drop[i] = true
+		default:
+			ended = append(ended, i)
+		}
+	}
+	if maxCount > 0 && len(ended) > maxCount {
+		sort.SliceStable(ended, func(a, b int) bool { return recs[ended[a]].UpdatedAt.Before(recs[ended[b]].UpdatedAt) })
+		for _, i := range ended[:len(ended)-maxCount] {
+			drop[i] = true
+		}
+	}
+	if len(drop) == 0 {
+		return recs
+	}
+	out := make([]InstanceRecord, 0, len(recs)-len(drop))
+	for i, rec := range recs {
+		if !drop[i] {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// snapshotLocked saves the stored document as <reason>-<time> and prunes automatic
+// snapshots beyond the retention limit. It returns "" when nothing is stored yet.
+// Callers hold s.mu.
+func (s *Store) snapshotLocked(ctx context.Context, reason string) (string, error) {
+	data, _, err := s.backend.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("reading state (%s): %w", s.backend, err)
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	loc, err := s.backend.SaveSnapshot(ctx, snapshotName(reason, s.now()), data)
+	if err != nil {
+		return "", fmt.Errorf("snapshot state (%s): %w", s.backend, err)
+	}
+	if strings.HasPrefix(reason, snapshotAutoPrefix) {
+		s.pruneSnapshotsLocked(ctx)
+	}
+	return loc, nil
+}
+
+// pruneSnapshotsLocked deletes the oldest automatic snapshots beyond the retention limit.
+func (s *Store) pruneSnapshotsLocked(ctx context.Context) {
+	snaps, err := s.backend.ListSnapshots(ctx)
+	if err != nil {
+		log.Printf("state: list snapshots for pruning: %v", err)
+		return
+	}
+	var auto []Snapshot
+	for _, sn := range snaps {
+		// pre-migrate-v<n>: migration backups named before they had their own prefix
+		if strings.HasPrefix(sn.Name, snapshotAutoPrefix) && !strings.HasPrefix(sn.Name, snapshotAutoPrefix+snapshotMigrate) {
+			auto = append(auto, sn)
+		}
+	}
+	for i := 0; i < len(auto)-s.snapshotsKept(); i++ {
+		if err := s.backend.DeleteSnapshot(ctx, auto[i].Name); err != nil {
+			log.Printf("state: prune snapshot %s: %v", auto[i].Name, err)
+		}
+	}
+}
+
+// SnapshotBefore takes an automatic snapshot ("pre-<op>") ahead of a destructive write,
+// such as an explicit sync-state rebuild, and returns where it was written ("" when
+// nothing is stored yet).
+func (s *Store) SnapshotBefore(op string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked(context.Background(), snapshotAutoPrefix+op)
+}
+
+var backupLabel = regexp.MustCompile(`^[A-Za-z0-9._]+$`)
+
+// Backup saves the current document as a manual snapshot ("backup[-label]-<time>") and
+// returns where it was written.
+func (s *Store) Backup(label string) (string, error) {
+	reason := snapshotBackup
+	if label != "" {
+		if !backupLabel.MatchString(label) {
+			return "", fmt.Errorf("backup label %q: use letters, digits, '.' and '_'", label)
+		}
+		reason += "-" + label
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loc, err := s.snapshotLocked(context.Background(), reason)
+	if err == nil && loc == "" {
+		err = fmt.Errorf("nothing to back up: %s holds no state yet", s.backend)
+	}
+	return loc, err
+}
+
+// ListSnapshots returns the automatic snapshots and backups, oldest first.
+func (s *Store) ListSnapshots() ([]Snapshot, error) {
+	return s.backend.ListSnapshots(context.Background())
+}
+
+// Export returns the current document at SchemaVersion.
+func (s *Store) Export() ([]byte, error) {
+	var out []byte
+	err := s.view(func(r *root) error {
+		r.SchemaVersion = SchemaVersion
+		data, err := json.MarshalIndent(r, "", "  ")
+		out = data
+		return err
+	})
+	return out, err
+}
+
+// Restore replaces the current document with the snapshot called name. The current
+// document is snapshotted first; the returned location is that pre-restore snapshot.
+func (s *Store) Restore(name string) (string, error) {
+	data, err := s.backend.LoadSnapshot(context.Background(), name)
+	if err != nil {
+		return "", fmt.Errorf("load snapshot %q (%s): %w", name, s.backend, err)
+	}
+	return s.replace(data, "restore")
+}
+
+// Import replaces the current document with data (e.g. from Export). The current document
+// is snapshotted first; the returned location is that pre-import snapshot.
+func (s *Store) Import(data []byte) (string, error) {
+	return s.replace(data, "import")
+}
+
+// replace validates data, migrating it when older, snapshots the stored document and
+// writes data in its place.
+func (s *Store) replace(data []byte, op string) (string, error) {
+	_, _, out, err := migrateDocument(data)
+	if err != nil {
+		return "", err
+	}
+	var r root
+	if err := json.Unmarshal(out, &r); err != nil {
+		return "", fmt.Errorf("parsing state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx := context.Background()
+	backup, err := s.snapshotLocked(ctx, snapshotAutoPrefix+op)
+	if err != nil {
+		return "", err
+	}
+	for attempt := 0; ; attempt++ {
+		_, version, err := s.backend.Load(ctx)
+		if err != nil {
+			return backup, fmt.Errorf("reading state (%s): %w", s.backend, err)
+		}
+		version, err = s.backend.Save(ctx, out, version)
+		if errors.Is(err, ErrConflict) && attempt < maxConflictRetries {
+			continue
+		}
+		if err != nil {
+			return backup, fmt.Errorf("writing state (%s): %w", s.backend, err)
+		}
+		s.version = version
+		s.invalidate()
+		log.Printf("state: %s replaced %s (previous document: %s)", op, s.backend, orNone(backup))
+		return backup, nil
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+// internal/state/snapshot_test.go
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fleetctl/internal/config"
+)
+
+func TestCompactRecords(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	recs := []InstanceRecord{
+		{ID: "old", Status: StatusTerminated, UpdatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "t1", Status: StatusTerminated, UpdatedAt: now.Add(-3 * time.Hour)},
+		{ID: "active-old", Status: StatusActive, UpdatedAt: now.Add(-30 * 24 * time.Hour)},
+		{ID: "p1", Status: StatusPreempted, UpdatedAt: now.Add(-2 * time.Hour)},
+		{ID: "t2", Status: StatusTerminated, UpdatedAt: now.Add(-1 * time.Hour)},
+	}
+	zero, two := 0, 2
+	noAge := time.Duration(0)
+	tests := []struct {
+		name string
+		r    config.StateRetention
+		want string
+	}{
+		{"defaults drop by age", config.StateRetention{}, "t1,active-old,p1,t2"},
+		{"count keeps newest", config.StateRetention{TerminatedMaxCount: &two}, "active-old,p1,t2"},
+		{"no limits", config.StateRetention{TerminatedMaxAge: &noAge, TerminatedMaxCount: &zero}, "old,t1,active-old,p1,t2"},
+	}
+	for _, tt := range tests {
+		if got := ids(compactRecords(append([]InstanceRecord(nil), recs...), tt.r, now)); got != tt.want {
+			t.Fatalf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotsBackupRestore(t *testing.T) {
+	srv := newFakeS3(t)
+	dir := t.TempDir()
+	backends := []Backend{
+		NewFileBackend(filepath.Join(dir, "state.json")),
+		NewBoltBackend(filepath.Join(dir, "state.db")),
+		NewS3Backend(srv.URL, "", "fleets", "fleetctl/dev.state.json", "AK", "SK"),
+	}
+	for _, b := range backends {
+		st := NewWithBackend(b)
+		st.SetRetention("dev", config.StateRetention{Snapshots: 2})
+		clock := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+		st.now = func() time.Time { clock = clock.Add(time.Minute); return clock }
+		if _, err := st.Backup(""); err == nil {
+			t.Fatalf("%s: backup of empty state should fail", b)
+		}
+		if err := st.AddActiveRecord("dev", "web", "i1", "web-1"); err != nil {
+			t.Fatal(err)
+		}
+		backup, err := st.Backup("before_sync")
+		if err != nil || backup == "" {
+			t.Fatalf("%s: backup = %q, %v", b, backup, err)
+		}
+		data, _, err := b.Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.SaveSnapshot(context.Background(), snapshotName(snapshotMigrate+"1", st.now()), data); err != nil {
+			t.Fatal(err)
+		}
+		// Three requested syncs snapshot first, and only the newest two automatic snapshots
+		// stay; the migration backup is never pruned. Plain rebuilds take no snapshot.
+		for i := 0; i < 3; i++ {
+			if _, err := st.SnapshotBefore("sync-state"); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.ResetFleetActive("dev", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := st.ResetFleetActive("dev", nil); err != nil {
+			t.Fatal(err)
+		}
+		snaps, err := st.ListSnapshots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, sn := range snaps {
+			names = append(names, strings.SplitN(sn.Name, "-2", 2)[0])
+		}
+		if got := strings.Join(names, ","); got != "backup-before_sync,migrate-v1,pre-sync-state,pre-sync-state" {
+			t.Fatalf("%s: snapshots = %s", b, got)
+		}
+
+		if n, _ := st.CountActive("dev"); n != 0 {
+			t.Fatalf("%s: active after reset = %d", b, n)
+		}
+		pre, err := st.Restore(snaps[0].Name)
+		if err != nil || !strings.Contains(pre, "pre-restore") {
+			t.Fatalf("%s: restore = %q, %v", b, pre, err)
+		}
+		if n, _ := st.CountActive("dev"); n != 1 {
+			t.Fatalf("%s: active after restore = %d, want 1", b, n)
+		}
+
+		exported, err := st.Export()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Import([]byte(`{"schemaVersion": 99}`)); err == nil {
+			t.Fatalf("%s: import of a newer schema must fail", b)
+		}
+		other := NewWithBackend(NewFileBackend(filepath.Join(t.TempDir(), "copy.json")))
+		if _, err := other.Import(exported); err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := other.CountActive("dev"); n != 1 {
+			t.Fatalf("%s: imported copy active = %d, want 1", b, n)
+		}
+	}
+}
+
+func TestRetentionPerFleet(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+	one, three := 1, 3
+	st.SetRetention("a", config.StateRetention{TerminatedMaxCount: &one, Snapshots: 2})
+	st.SetRetention("b", config.StateRetention{TerminatedMaxCount: &three, Snapshots: 5})
+	for _, fleet := range []string{"a", "b"} {
+		for _, id := range []string{"i1", "i2", "i3"} {
+			if err := st.AddActiveRecord(fleet, "web", id, id); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.MarkTerminatedByIDs(fleet, []string{id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for fleet, want := range map[string]int{"a": 1, "b": 3} {
+		var n int
+		_ = st.viewFleet(fleet, func(fs FleetState) error { n = len(fs.Instances); return nil })
+		if n != want {
+			t.Fatalf("fleet %s keeps %d ended records, want %d", fleet, n, want)
+		}
+	}
+	if kept := st.snapshotsKept(); kept != 5 {
+		t.Fatalf("snapshotsKept = %d, want the largest fleet setting 5", kept)
+	}
+}
@@ -2,18 +2,24 @@
 package state
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
 	"sync"
 	"time"
+
+	"fleetctl/internal/config"
 )
 
 const (
 	StatusActive     = "Active"
 	StatusTerminated = "Terminated"
+	StatusPreempted  = "Preempted" // preemptible instance reclaimed by OCI
+	StatusLaunching  = "Launching" // launch intent: LaunchInstance requested, outcome not yet recorded
 )
 
 // InstanceRecord represents a single tracked instance under our control.
@@ -22,102 +28,150 @@ type InstanceRecord struct {
 	Group     string    `json:"group"`
 	Name      string    `json:"name"`
 	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
+	Capacity  string    `json:"capacity,omitempty"` // "on-demand" or "preemptible"
+	CreatedAt time.Time `json:"createdAt"`          // OCI timeCreated when known
 	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Placement chosen at launch (after any capacity fallback)
+	AvailabilityDomain string `json:"availabilityDomain,omitempty"`
+	FaultDomain        string `json:"faultDomain,omitempty"`
+	Shape              string `json:"shape,omitempty"`
+
+	// Instance details recorded at launch and refreshed on sync
+	PrivateIP      string `json:"privateIp,omitempty"`
+	PublicIP       string `json:"publicIp,omitempty"`
+	ImageID        string `json:"imageId,omitempty"`
+	ConfigRevision string `json:"configRevision,omitempty"` // config.Spec.Revision at launch
+
+	// opc-retry-token of the launch request (client.LaunchTokenTagKey); Launching records
+	// are keyed by it until the instance is recorded
+	LaunchToken string `json:"launchToken,omitempty"`
 }
 
 // LBState captures load balancer snapshot for a fleet.
 type LBState struct {
 	Enabled       bool      `json:"enabled"`
 	ID            string    `json:"id"`
-	BackendSet    string    `json:"backendSet"`
+	BackendSet    string    `json:"backendSet"` // comma-separated when the spec declares several
 	Listener      string    `json:"listener"`
 	Backends      []string  `json:"backends,omitempty"`
 	BackendsCount int       `json:"backendsCount"`
 	UpdatedAt     time.Time `json:"updatedAt"`
+
+	// Spec changes the running LB cannot take in place (e.g. isPrivate); empty when none
+	NeedsReplacement []string `json:"needsReplacement,omitempty"`
 }
 
 // FleetState captures tracked instances and LB snapshot for a named fleet.
 type FleetState struct {
 	FleetName string           `json:"fleetName"`
+	UID       string           `json:"uid,omitempty"` // generated once; tags the fleet's cloud resources (see FleetUID)
 	Instances []InstanceRecord `json:"instances"`
 	LB        *LBState         `json:"lb,omitempty"`
+	History   []HistoryEntry   `json:"history,omitempty"`
+	Queued    []QueuedOp       `json:"queued,omitempty"`
+	Desired   *DesiredOverride `json:"desired,omitempty"`
 	UpdatedAt time.Time        `json:"updatedAt"`
 }
 
 // root is the top-level JSON format to allow multiple fleets in one file.
 type root struct {
-	Fleets map[string]FleetState `json:"fleets"`
+	SchemaVersion int                   `json:"schemaVersion"` // see SchemaVersion and migrate.go
+	Fleets        map[string]FleetState `json:"fleets"`
 }
 
-// Store persists tracking state to a JSON file.
+// Store persists tracking state through a Backend (a JSON file by default). The parsed
+// document is kept in memory and reused while the backend's Probe token is unchanged, so
+// reads do not re-read and re-parse the document (see cache.go).
 type Store struct {
-	path string
-	mu   sync.Mutex
+	backend Backend
+	mu      sync.Mutex
+	version string // backend version of the document last loaded; guards conditional writes
+
+	cache *root  // parsed document at version; nil when not loaded or invalidated
+	probe string // Probe token observed when cache was loaded; "" forces a reload
+	batch *batch // open Batch, if any
+
+	retention map[string]config.StateRetention // per fleet; fleets without one get the defaults
+	now       func() time.Time                 // clock for snapshot names
 }
 
-// New creates a new state Store at the provided path.
+// New creates a new state Store backed by the JSON file at path.
 func New(path string) *Store {
-	return &Store{path: path}
+	return NewWithBackend(NewFileBackend(path))
 }
 
-// ensureDir ensures the directory for the state file exists.
-func (s *Store) ensureDir() error {
-	dir := filepath.Dir(s.path)
-	if dir == "." || dir == "" {
-		return nil
-	}
-	return os.MkdirAll(dir, 0o755)
+// NewWithBackend creates a Store on top of b.
+func NewWithBackend(b Backend) *Store {
+	return &Store{backend: b, now: time.Now}
+}
+
+// Backend returns the backend the store persists to.
+func (s *Store) Backend() Backend {
+	return s.backend
 }
 
-// load reads the state file, returning an initialized root if missing.
+// errNoChange lets an update function skip the write when there is nothing to change.
+// A function returning it must not have modified the document.
+var errNoChange = errors.New("no change")
+
+// maxConflictRetries bounds how often an update is re-applied after another writer won.
+const maxConflictRetries = 5
+
+// load returns the state document, initialized if none is stored yet. The cached copy is
+// returned while it is current; otherwise the document is read, parsed and cached, with
+// the writes of an open batch re-applied on top.
 func (s *Store) load() (*root, error) {
-	if err := s.ensureDir(); err != nil {
-		return nil, err
+	ctx := context.Background()
+	if r := s.cached(ctx); r != nil {
+		return r, nil
 	}
-
-	data, err := os.ReadFile(s.path)
+	token := s.probeToken(ctx) // before reading: a write in between only causes a spare reload
+	data, version, err := s.loadCurrent(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &root{Fleets: map[string]FleetState{}}, nil
-		}
-		return nil, fmt.Errorf("reading state file %q: %w", s.path, err)
+		return nil, err
 	}
-
-	var r root
-	if err := json.Unmarshal(data, &r); err != nil {
-		return nil, fmt.Errorf("parsing state file %q: %w", s.path, err)
+	s.version = version
+	r := root{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("parsing state (%s): %w", s.backend, err)
+		}
 	}
 	if r.Fleets == nil {
 		r.Fleets = map[string]FleetState{}
 	}
+	if s.batch != nil {
+		if err := s.batch.replay(&r); err != nil {
+			return nil, err
+		}
+	}
+	s.cache, s.probe = &r, token
 	return &r, nil
 }
 
-// save writes the state back to disk atomically.
+// save writes the state back if nobody else has written since it was loaded;
+// otherwise it returns an error wrapping ErrConflict.
 func (s *Store) save(r *root) error {
-	if err := s.ensureDir(); err != nil {
-		return err
-	}
-
-	tmp := s.path + ".tmp"
+	r.SchemaVersion = SchemaVersion
 	data, err := json.MarshalIndent(r, "", "  ")
 	if err != nil {
 		return fmt.Errorf("encoding state: %w", err)
 	}
-	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		return fmt.Errorf("writing temp state file: %w", err)
-	}
-	return os.Rename(tmp, s.path)
+	version, err := s.backend.Save(context.Background(), data, s.version)
+	if err != nil {
+		s.invalidate()
+		return fmt.Errorf("writing state (%s): %w", s.backend, err)
+	}
+	s.version = version
+	// Keep the document but re-check the backend on the next read: the token after our
+	// own write cannot be told apart from one after a concurrent writer's.
+	s.cache, s.probe = r, ""
+	return nil
 }
 
-// AddActiveInstances appends n active instances for fleetName and group.
-func (s *Store) AddActiveInstances(fleetName, group string, n int) error {
-	if n <= 0 {
-		return nil
-	}
-	now := time.Now()
-
+// view runs fn on the current state document; fn must not modify it.
+func (s *Store) view(fn func(r *root) error) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -125,96 +179,140 @@ func (s *Store) AddActiveInstances(fleetName, group string, n int) error {
 	if err != nil {
 		return err
 	}
-	fs := r.Fleets[fleetName]
-	fs.FleetName = fleetName
-
-	for i := 0; i < n; i++ {
-		id := fmt.Sprintf("local-%d-%d", now.UnixNano(), i)
-		name := fmt.Sprintf("%s-%d-%d", group, now.UnixNano(), i)
-		rec := InstanceRecord{
-			ID:        id,
-			Group:     group,
-			Name:      name,
-			Status:    StatusActive,
-			CreatedAt: now,
-			UpdatedAt: now,
+	return fn(r)
+}
+
+// update loads the state, applies fn and saves it. When another process wrote in
+// between, the update is re-applied to the newer state. Inside a Batch the change is
+// applied in memory and saved when the batch ends.
+func (s *Store) update(fn func(r *root) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.batch != nil {
+		return s.batch.apply(s, fn)
+	}
+	var err error
+	for attempt := 0; attempt <= maxConflictRetries; attempt++ {
+		var r *root
+		if r, err = s.load(); err != nil {
+			return err
+		}
+		if err = fn(r); errors.Is(err, errNoChange) {
+			return nil
+		} else if err != nil {
+			s.invalidate() // fn may have modified the cached document before failing
+			return err
+		}
+		if err = s.save(r); !errors.Is(err, ErrConflict) {
+			return err
 		}
-		fs.Instances = append(fs.Instances, rec)
 	}
+	return err
+}
 
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+// updateFleet is update scoped to one fleet; fs is stored back with UpdatedAt refreshed
+// and ended instance records compacted per the retention settings.
+func (s *Store) updateFleet(fleetName string, fn func(fs *FleetState) error) error {
+	return s.update(func(r *root) error {
+		fs := r.Fleets[fleetName]
+		fs.FleetName = fleetName
+		if err := fn(&fs); err != nil {
+			return err
+		}
+		fs.UpdatedAt = time.Now()
+		fs.Instances = compactRecords(fs.Instances, s.retention[fleetName], fs.UpdatedAt)
+		r.Fleets[fleetName] = fs
+		return nil
+	})
 }
 
-// RemoveActiveInstances marks up to n active instances as terminated (LIFO).
-func (s *Store) RemoveActiveInstances(fleetName string, n int) (int, error) {
+// viewFleet runs fn on the current state of one fleet.
+func (s *Store) viewFleet(fleetName string, fn func(fs FleetState) error) error {
+	return s.view(func(r *root) error { return fn(r.Fleets[fleetName]) })
+}
+
+// AddActiveInstances appends n active instances for fleetName and group.
+func (s *Store) AddActiveInstances(fleetName, group string, n int) error {
 	if n <= 0 {
-		return 0, nil
+		return nil
 	}
 	now := time.Now()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return 0, err
-	}
-	fs := r.Fleets[fleetName]
-
-	// Find indexes of active instances
-	activeIdx := make([]int, 0, len(fs.Instances))
-	for idx, inst := range fs.Instances {
-		if inst.Status == StatusActive {
-			activeIdx = append(activeIdx, idx)
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		for i := 0; i < n; i++ {
+			id := fmt.Sprintf("local-%d-%d", now.UnixNano(), i)
+			name := fmt.Sprintf("%s-%d-%d", group, now.UnixNano(), i)
+			rec := InstanceRecord{
+				ID:        id,
+				Group:     group,
+				Name:      name,
+				Status:    StatusActive,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			fs.Instances = append(fs.Instances, rec)
 		}
-	}
-	// Remove from the end for deterministic behavior
-	sort.Ints(activeIdx)
+		return nil
+	})
+}
 
-	removed := 0
-	for i := len(activeIdx) - 1; i >= 0 && removed < n; i-- {
-		idx := activeIdx[i]
-		fs.Instances[idx].Status = StatusTerminated
-		fs.Instances[idx].UpdatedAt = now
-		removed++
+// RemoveActiveInstances marks up to n active instances as terminated (LIFO).
+func (s *Store) RemoveActiveInstances(fleetName string, n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
 	}
+	now := time.Now()
 
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return removed, s.save(r)
+	removed := 0
+	err := s.updateFleet(fleetName, func(fs *FleetState) error {
+		// Find indexes of active instances
+		activeIdx := make([]int, 0, len(fs.Instances))
+		for idx, inst := range fs.Instances {
+			if inst.Status == StatusActive {
+				activeIdx = append(activeIdx, idx)
+			}
+		}
+		// Remove from the end for deterministic behavior
+		sort.Ints(activeIdx)
+
+		removed = 0
+		for i := len(activeIdx) - 1; i >= 0 && removed < n; i-- {
+			idx := activeIdx[i]
+			fs.Instances[idx].Status = StatusTerminated
+			fs.Instances[idx].UpdatedAt = now
+			removed++
+		}
+		return nil
+	})
+	return removed, err
 }
 
 // CountActive returns the number of active instances tracked for the fleet.
 func (s *Store) CountActive(fleetName string) (int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return 0, err
-	}
-	fs := r.Fleets[fleetName]
 	active := 0
-	for _, inst := range fs.Instances {
-		if inst.Status == StatusActive {
-			active++
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		for _, inst := range fs.Instances {
+			if inst.Status == StatusActive {
+				active++
+			}
 		}
-	}
-	return active, nil
+		return nil
+	})
+	return active, err
 }
 
 // Summary returns a human-readable summary for the fleet.
 func (s *Store) Summary(fleetName string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
+	var fs FleetState
+	err := s.viewFleet(fleetName, func(v FleetState) error {
+		fs = v
+		fs.Instances = append([]InstanceRecord(nil), v.Instances...) // the cached document changes under later writes
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
-	fs := r.Fleets[fleetName]
 	byGroup := map[string]int{}
 	active := 0
 	total := len(fs.Instances)
@@ -248,31 +346,63 @@ func (s *Store) Summary(fleetName string) (string, error) {
 
 // AddActiveRecord appends a specific active instance record (e.g., after OCI launch).
 func (s *Store) AddActiveRecord(fleetName, group, id, name string) error {
-	now := time.Now()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.AddInstanceRecord(fleetName, InstanceRecord{ID: id, Group: group, Name: name})
+}
 
-	r, err := s.load()
-	if err != nil {
-		return err
+// AddInstanceRecord appends rec as an active record, filling status and timestamps. A
+// launch intent with the same LaunchToken is replaced by the record.
+func (s *Store) AddInstanceRecord(fleetName string, rec InstanceRecord) error {
+	now := time.Now()
+	rec.Status = StatusActive
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = now
 	}
-	fs := r.Fleets[fleetName]
-	fs.FleetName = fleetName
+	rec.UpdatedAt = now
 
-	rec := InstanceRecord{
-		ID:        id,
-		Group:     group,
-		Name:      name,
-		Status:    StatusActive,
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-	fs.Instances = append(fs.Instances, rec)
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if i := launchIntentIndex(fs.Instances, rec.LaunchToken); i >= 0 {
+			fs.Instances[i] = rec
+			return nil
+		}
+		fs.Instances = append(fs.Instances, rec)
+		return nil
+	})
+}
+
+// ActiveRecords returns the fleet's active records, oldest first (by creation time, then
+// the order they were added).
+func (s *Store) ActiveRecords(fleetName string) ([]InstanceRecord, error) {
+	var out []InstanceRecord
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		for _, inst := range fs.Instances {
+			if inst.Status == StatusActive {
+				out = append(out, inst)
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, err
+}
 
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+// SetInstanceAddresses records the IPs of an active instance (e.g. after looking them up
+// for an instance launched before addresses were tracked).
+func (s *Store) SetInstanceAddresses(fleetName, id, privateIP, publicIP string) error {
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		for i := range fs.Instances {
+			rec := &fs.Instances[i]
+			if rec.ID != id || rec.Status != StatusActive {
+				continue
+			}
+			if rec.PrivateIP == privateIP && rec.PublicIP == publicIP {
+				return errNoChange
+			}
+			rec.PrivateIP, rec.PublicIP = privateIP, publicIP
+			rec.UpdatedAt = time.Now()
+			return nil
+		}
+		return errNoChange
+	})
 }
 
 // ActiveRecordsLIFO returns up to n active records in LIFO order without mutating state.
@@ -280,28 +410,13 @@ func (s *Store) ActiveRecordsLIFO(fleetName string, n int) ([]InstanceRecord, er
 	if n <= 0 {
 		return nil, nil
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
+	active, err := s.ActiveRecords(fleetName)
 	if err != nil {
 		return nil, err
 	}
-	fs := r.Fleets[fleetName]
-
-	activeIdx := make([]int, 0, len(fs.Instances))
-	for idx, inst := range fs.Instances {
-		if inst.Status == StatusActive {
-			activeIdx = append(activeIdx, idx)
-		}
-	}
-	sort.Ints(activeIdx)
-
 	out := make([]InstanceRecord, 0, n)
-	for i := len(activeIdx) - 1; i >= 0 && len(out) < n; i-- {
-		idx := activeIdx[i]
-		out = append(out, fs.Instances[idx])
+	for i := len(active) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, active[i])
 	}
 	return out, nil
 }
@@ -311,34 +426,28 @@ func (s *Store) ActiveRecordsFIFO(fleetName string, n int) ([]InstanceRecord, er
 	if n <= 0 {
 		return nil, nil
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
+	active, err := s.ActiveRecords(fleetName)
 	if err != nil {
 		return nil, err
 	}
-	fs := r.Fleets[fleetName]
-
-	activeIdx := make([]int, 0, len(fs.Instances))
-	for idx, inst := range fs.Instances {
-		if inst.Status == StatusActive {
-			activeIdx = append(activeIdx, idx)
-		}
-	}
-	sort.Ints(activeIdx)
-
-	out := make([]InstanceRecord, 0, n)
-	for i := 0; i < len(activeIdx) && len(out) < n; i++ {
-		idx := activeIdx[i]
-		out = append(out, fs.Instances[idx])
+	if len(active) > n {
+		active = active[:n]
 	}
-	return out, nil
+	return active, nil
 }
 
 // MarkTerminatedByIDs marks any instances with matching IDs as terminated.
 func (s *Store) MarkTerminatedByIDs(fleetName string, ids []string) error {
+	return s.markActiveByIDs(fleetName, ids, StatusTerminated)
+}
+
+// MarkPreemptedByIDs marks active instances with matching IDs as reclaimed by OCI.
+func (s *Store) MarkPreemptedByIDs(fleetName string, ids []string) error {
+	return s.markActiveByIDs(fleetName, ids, StatusPreempted)
+}
+
+// markActiveByIDs moves active instances with matching IDs to status.
+func (s *Store) markActiveByIDs(fleetName string, ids []string, status string) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -348,37 +457,23 @@ func (s *Store) MarkTerminatedByIDs(fleetName string, ids []string) error {
 		idset[id] = struct{}{}
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return err
-	}
-	fs := r.Fleets[fleetName]
-
-	for i := range fs.Instances {
-		if _, ok := idset[fs.Instances[i].ID]; ok && fs.Instances[i].Status == StatusActive {
-			fs.Instances[i].Status = StatusTerminated
-			fs.Instances[i].UpdatedAt = now
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		for i := range fs.Instances {
+			if _, ok := idset[fs.Instances[i].ID]; ok && fs.Instances[i].Status == StatusActive {
+				fs.Instances[i].Status = status
+				fs.Instances[i].UpdatedAt = now
+			}
 		}
-	}
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+		return nil
+	})
 }
 
 // ResetFleetActive replaces the fleet's tracked instances with the provided active records.
+// History, queued work, the desired override and the recorded LB survive a rebuild; the
+// LB OCID is what discovery looks up first, and only ClearLB drops it. Callers rebuilding on
+// request (sync-state) take a SnapshotBefore first so a bad rebuild can be rolled back.
 func (s *Store) ResetFleetActive(fleetName string, records []InstanceRecord) error {
 	now := time.Now()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return err
-	}
 	for i := range records {
 		records[i].Status = StatusActive
 		if records[i].CreatedAt.IsZero() {
@@ -386,131 +481,123 @@ func (s *Store) ResetFleetActive(fleetName string, records []InstanceRecord) err
 		}
 		records[i].UpdatedAt = now
 	}
-	r.Fleets[fleetName] = FleetState{
-		FleetName: fleetName,
-		Instances: records,
-		UpdatedAt: now,
-	}
-	return s.save(r)
+
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		fs.Instances = records
+		return nil
+	})
 }
 
 // LB persistence helpers
 
 // SetLBInfo upserts the LB identity and enabled flag for the fleet.
 func (s *Store) SetLBInfo(fleetName string, enabled bool, id, backendSet, listener string) error {
-	now := time.Now()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return err
-	}
-	fs := r.Fleets[fleetName]
-	fs.FleetName = fleetName
-
-	if fs.LB == nil {
-		fs.LB = &LBState{}
-	}
-	fs.LB.Enabled = enabled
-	fs.LB.ID = id
-	fs.LB.BackendSet = backendSet
-	fs.LB.Listener = listener
-	fs.LB.UpdatedAt = now
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if fs.LB == nil {
+			fs.LB = &LBState{}
+		}
+		fs.LB.Enabled = enabled
+		fs.LB.ID = id
+		fs.LB.BackendSet = backendSet
+		fs.LB.Listener = listener
+		fs.LB.UpdatedAt = time.Now()
+		return nil
+	})
+}
 
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+// SetLBNeedsReplacement records the spec changes that need the LB recreated (nil clears them).
+func (s *Store) SetLBNeedsReplacement(fleetName string, reasons []string) error {
+	dst := append([]string(nil), reasons...)
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if fs.LB == nil {
+			fs.LB = &LBState{}
+		}
+		fs.LB.NeedsReplacement = dst
+		return nil
+	})
 }
 
 // SetLBBackends sets the current backend IPs and count for the fleet LB.
 func (s *Store) SetLBBackends(fleetName string, ips []string) error {
-	now := time.Now()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return err
-	}
-	fs := r.Fleets[fleetName]
-	fs.FleetName = fleetName
-
-	if fs.LB == nil {
-		fs.LB = &LBState{}
-	}
 	dst := make([]string, len(ips))
 	copy(dst, ips)
-	fs.LB.Backends = dst
-	fs.LB.BackendsCount = len(dst)
-	fs.LB.UpdatedAt = now
 
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if fs.LB == nil {
+			fs.LB = &LBState{}
+		}
+		fs.LB.Backends = dst
+		fs.LB.BackendsCount = len(dst)
+		fs.LB.UpdatedAt = time.Now()
+		return nil
+	})
 }
 
 // SetLBBackendsCount updates only the backend count (when IPs are not readily available).
 func (s *Store) SetLBBackendsCount(fleetName string, n int) error {
-	now := time.Now()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
-	if err != nil {
-		return err
-	}
-	fs := r.Fleets[fleetName]
-	fs.FleetName = fleetName
-
-	if fs.LB == nil {
-		fs.LB = &LBState{}
-	}
 	if n < 0 {
 		n = 0
 	}
-	fs.LB.BackendsCount = n
-	fs.LB.UpdatedAt = now
 
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		if fs.LB == nil {
+			fs.LB = &LBState{}
+		}
+		fs.LB.BackendsCount = n
+		fs.LB.UpdatedAt = time.Now()
+		return nil
+	})
 }
 
 // ClearLB removes any LB snapshot from state (e.g., when LB disabled).
 func (s *Store) ClearLB(fleetName string) error {
-	now := time.Now()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.updateFleet(fleetName, func(fs *FleetState) error {
+		fs.LB = nil
+		return nil
+	})
+}
 
-	r, err := s.load()
-	if err != nil {
-		return err
-	}
-	fs := r.Fleets[fleetName]
-	fs.FleetName = fleetName
-	fs.LB = nil
-	fs.UpdatedAt = now
-	r.Fleets[fleetName] = fs
-	return s.save(r)
+// FleetUID returns the fleet's UID, generating and storing one on first use. It tells the
+// fleet's load balancer apart from another fleet's with the same name.
+func (s *Store) FleetUID(fleetName string) (string, error) {
+	var uid string
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		uid = fs.UID
+		return nil
+	})
+	if err != nil || uid != "" {
+		return uid, err
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate fleet uid: %w", err)
+	}
+	err = s.updateFleet(fleetName, func(fs *FleetState) error {
+		// Another process may have generated one first; keep it.
+		if fs.UID == "" {
+			fs.UID = hex.EncodeToString(b)
+		}
+		uid = fs.UID
+		return nil
+	})
+	return uid, err
 }
 
 // GetLBInfo returns the LB snapshot for the fleet, if present.
 func (s *Store) GetLBInfo(fleetName string) (LBState, bool, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	r, err := s.load()
+	var lb *LBState
+	err := s.viewFleet(fleetName, func(fs FleetState) error {
+		if fs.LB != nil {
+			v := *fs.LB // copied under the lock: writers update the cached LB in place
+			lb = &v
+		}
+		return nil
+	})
 	if err != nil {
 		return LBState{}, false, err
 	}
-	fs := r.Fleets[fleetName]
-	if fs.LB == nil {
+	if lb == nil {
 		return LBState{}, false, nil
 	}
-	return *fs.LB, true, nil
+	return *lb, true, nil
 }
@@ -0,0 +1,121 @@
+// internal/state/store_test.go
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestActiveRecordsOldestFirst(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Sync records arrive in OCI list order (newest first); launches without a known time sort by record time.
+	err := st.ResetFleetActive("dev", []InstanceRecord{
+		{ID: "c", Group: "web", CreatedAt: base.Add(2 * time.Hour)},
+		{ID: "a", Group: "web", CreatedAt: base},
+		{ID: "b", Group: "web", CreatedAt: base.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddInstanceRecord("dev", InstanceRecord{ID: "d", Group: "web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fifo, err := st.ActiveRecordsFIFO("dev", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(fifo); got != "a,b,c,d" {
+		t.Fatalf("FIFO = %s, want a,b,c,d", got)
+	}
+	lifo, _ := st.ActiveRecordsLIFO("dev", 2)
+	if got := ids(lifo); got != "d,c" {
+		t.Fatalf("LIFO = %s, want d,c", got)
+	}
+
+	if err := st.SetInstanceAddresses("dev", "b", "10.0.0.7", "203.0.113.9"); err != nil {
+		t.Fatal(err)
+	}
+	recs, _ := st.ActiveRecords("dev")
+	if recs[1].ID != "b" || recs[1].PrivateIP != "10.0.0.7" || recs[1].PublicIP != "203.0.113.9" {
+		t.Fatalf("addresses not recorded: %+v", recs[1])
+	}
+}
+
+func TestResetFleetActiveKeepsLB(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := st.SetLBInfo("dev", true, "ocid1.loadbalancer.oc1..a", "dev-backendset", "dev-listener"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ResetFleetActive("dev", []InstanceRecord{{ID: "a", Group: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+	lb, ok, err := st.GetLBInfo("dev")
+	if err != nil || !ok || lb.ID != "ocid1.loadbalancer.oc1..a" {
+		t.Fatalf("LB after rebuild = %+v, %t, %v", lb, ok, err)
+	}
+	if err := st.ClearLB("dev"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := st.GetLBInfo("dev"); ok {
+		t.Fatalf("LB still recorded after ClearLB")
+	}
+}
+
+func TestClearDesiredOverrideIf(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "state.json"))
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := DesiredOverride{Desired: 5, Source: "api", SetAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := st.SetDesiredOverride("dev", expired); err != nil {
+		t.Fatal(err)
+	}
+	read, _, _ := st.DesiredOverride("dev")
+
+	// Another process replaces the override after it was read: it must survive.
+	fresh := DesiredOverride{Desired: 8, Source: "cli", SetAt: now.Add(2 * time.Hour)}
+	if err := st.SetDesiredOverride("dev", fresh); err != nil {
+		t.Fatal(err)
+	}
+	if cleared, err := st.ClearDesiredOverrideIf("dev", read); err != nil || cleared {
+		t.Fatalf("ClearDesiredOverrideIf(stale) = %t, %v; want false", cleared, err)
+	}
+	if o, ok, _ := st.DesiredOverride("dev"); !ok || o.Desired != 8 {
+		t.Fatalf("fresh override lost: %+v, %t", o, ok)
+	}
+
+	read, _, _ = st.DesiredOverride("dev")
+	if cleared, err := st.ClearDesiredOverrideIf("dev", read); err != nil || !cleared {
+		t.Fatalf("ClearDesiredOverrideIf(current) = %t, %v; want true", cleared, err)
+	}
+	if _, ok, _ := st.DesiredOverride("dev"); ok {
+		t.Fatalf("override still set")
+	}
+}
+
+func ids(recs []InstanceRecord) string {
+	out := ""
+	for i, r := range recs {
+		if i > 0 {
+			out += ","
+		}
+		out += r.ID
+	}
+	return out
+}
+
+func TestFleetUIDIsGeneratedOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	uid, err := New(path).FleetUID("dev")
+	if err != nil || len(uid) != 16 {
+		t.Fatalf("FleetUID = %q, %v", uid, err)
+	}
+	// Kept in state: another store on the same file sees the same UID
+	if again, err := New(path).FleetUID("dev"); err != nil || again != uid {
+		t.Fatalf("FleetUID again = %q, %v, want %q", again, err, uid)
+	}
+	if other, _ := New(path).FleetUID("prod"); other == uid {
+		t.Fatalf("fleets dev and prod share UID %s", uid)
+	}
+}